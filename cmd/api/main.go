@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -17,10 +20,12 @@ import (
 	"koteyye_music_be/internal/config"
 	"koteyye_music_be/internal/handler"
 	"koteyye_music_be/internal/middleware"
+	"koteyye_music_be/internal/realtime"
 	"koteyye_music_be/internal/repository"
 	"koteyye_music_be/internal/service"
 	"koteyye_music_be/pkg/database"
 	"koteyye_music_be/pkg/logger"
+	"koteyye_music_be/pkg/mailer"
 	"koteyye_music_be/pkg/migrations"
 	"koteyye_music_be/pkg/minio"
 )
@@ -49,18 +54,46 @@ func main() {
 
 	logger.Log.Info("Starting Music Service API", "port", cfg.ServerPort)
 
+	handler.SetErrorFormat(cfg.ErrorFormat)
+
+	// Streaming and transcoding write large temp files; the directory may be a dedicated volume
+	if err := os.MkdirAll(cfg.TempDir, 0o700); err != nil {
+		logger.Log.Error("Failed to create temp directory", "dir", cfg.TempDir, "error", err)
+		os.Exit(1)
+	}
+
 	// Initialize database connection
-	db, err := database.NewDB(context.Background(), cfg.DBDSN)
+	db, err := database.NewDB(context.Background(), cfg.DBDSN, int32(cfg.DBMaxConns), int32(cfg.DBMinConns), cfg.DBMaxConnLifetime)
 	if err != nil {
 		logger.Log.Error("Failed to connect to database", "error", err)
 		os.Exit(1)
 	}
 	defer db.Close()
-	logger.Log.Info("Database connected successfully")
+	if err := db.Ping(context.Background(), cfg.DBPingTimeout); err != nil {
+		logger.Log.Error("Database is unreachable, check DB_DSN", "error", err)
+		db.Close()
+		os.Exit(1)
+	}
+	poolConfig := db.Pool.Config()
+	logger.Log.Info("Database connected successfully",
+		"max_conns", poolConfig.MaxConns,
+		"min_conns", poolConfig.MinConns,
+		"max_conn_lifetime", poolConfig.MaxConnLifetime.String())
+
+	migrator := migrations.NewMigrator(db, logger.Log)
+
+	// "migrate" manages the schema and exits instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), migrator, os.Args[2:]); err != nil {
+			logger.Log.Error("Migration command failed", "error", err)
+			db.Close()
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Run database migrations
 	logger.Log.Info("Running database migrations...")
-	migrator := migrations.NewMigrator(db, logger.Log)
 	if err := migrator.Up(context.Background()); err != nil {
 		logger.Log.Error("Failed to run migrations", "error", err)
 		os.Exit(1)
@@ -71,29 +104,62 @@ func main() {
 		cfg.MinIOEndpoint,
 		cfg.MinIOAccessKey,
 		cfg.MinIOSecretKey,
-		cfg.MinIOBucket,
+		minio.Buckets{
+			Audio:  cfg.MinIOAudioBucket,
+			Image:  cfg.MinIOImageBucket,
+			Avatar: cfg.MinIOAvatarBucket,
+		},
 		cfg.MinIOUseSSL,
+		cfg.MinIOPublicEndpoint,
+		cfg.MinIOPublicUseSSL,
 		logger.Log,
 	)
 	if err != nil {
 		logger.Log.Error("Failed to initialize MinIO client", "error", err)
 		os.Exit(1)
 	}
-	logger.Log.Info("MinIO connected successfully", "bucket", cfg.MinIOBucket)
+	logger.Log.Info("MinIO connected successfully", "buckets", minioClient.Buckets().Unique())
+
+	if cfg.MinIOPublicImages {
+		policyCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := minioClient.SetPublicImagesPolicy(policyCtx)
+		cancel()
+		if err != nil {
+			logger.Log.Error("Failed to set public images bucket policy", "error", err)
+			os.Exit(1)
+		}
+	}
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	trackRepo := repository.NewTrackRepository(db)
 	albumRepo := repository.NewAlbumRepository(db.Pool)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	idempotencyKeyRepo := repository.NewIdempotencyKeyRepository(db)
+	activityRepo := repository.NewActivityRepository(db)
 
 	// Initialize MinIO service
 	minioService := minio.NewService(minioClient, cfg.MinIOEndpoint, cfg.MinIOUseSSL, logger.Log)
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, cfg.JWTSecret, logger.Log)
-	userService := service.NewUserService(userRepo, minioClient, logger.Log)
-	trackService := service.NewTrackService(trackRepo, albumRepo, minioClient, minioService, logger.Log)
-	albumService := service.NewAlbumService(albumRepo, trackRepo, minioService)
+	emailVerificationRepo := repository.NewEmailVerificationRepository(db)
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, emailVerificationRepo, mailer.NewLogMailer(logger.Log), cfg.JWTSecret, cfg.JWTExpiry, cfg.JWTIssuer, cfg.JWTAudience, cfg.RefreshTokenTTL, cfg.EmailVerificationTTL, cfg.RequireEmailVerification, service.PasswordPolicy{
+		MinLength:     cfg.PasswordMinLength,
+		RequireLetter: cfg.PasswordRequireLetter,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+	}, cfg.PublicBaseURL, logger.Log)
+	thumbnailService := service.NewThumbnailService(minioService, cfg.CoverThumbnailSizes, logger.Log)
+	userService := service.NewUserService(userRepo, minioClient, thumbnailService, cfg.MaxAvatarSize, cfg.AvatarCrop, cfg.MinVolume, cfg.MaxVolume, logger.Log)
+	liveHub := realtime.NewHub(cfg.LiveMaxSubscribers)
+	playTracker := service.NewPlayTracker(trackRepo, cfg.PlayDebounceInterval, cfg.PlayFlushInterval, logger.Log)
+	trackService := service.NewTrackService(trackRepo, albumRepo, minioClient, minioService, thumbnailService, liveHub, playTracker, cfg.AudioMP3Bitrate, cfg.AudioRenditions, cfg.StreamURLExpiry, cfg.TempDir, logger.Log)
+	activityService := service.NewActivityService(activityRepo, logger.Log)
+	searchService := service.NewSearchService(trackRepo, albumRepo, logger.Log)
+	genreService := service.NewGenreService(albumRepo, trackRepo, logger.Log)
+	importService := service.NewImportService(albumRepo, minioService, cfg.MaxCoverSize, cfg.MaxAudioSize, logger.Log)
+	albumService := service.NewAlbumService(albumRepo, trackRepo, idempotencyKeyRepo, minioService, thumbnailService, trackService, cfg.MinIOCoverFormat, cfg.MinIOCoverQuality, cfg.PublicBaseURL, cfg.IdempotencyKeyTTL, logger.Log)
 	oauthService := service.NewOAuthService(
 		userRepo,
 		authService,
@@ -104,29 +170,55 @@ func main() {
 		cfg.YandexClientSecret,
 		cfg.YandexRedirectURL,
 		cfg.FrontendURL,
+		cfg.JWTSecret,
 		logger.Log,
 	)
 
 	// Initialize handlers
+	uploadLimits := handler.UploadLimits{
+		MaxCoverSize:   cfg.MaxCoverSize,
+		MaxAudioSize:   cfg.MaxAudioSize,
+		MaxAvatarSize:  cfg.MaxAvatarSize,
+		MaxArchiveSize: cfg.MaxArchiveSize,
+	}
 	authHandler := handler.NewAuthHandler(authService, logger.Log)
-	userHandler := handler.NewUserHandler(userService, logger.Log)
-	trackHandler := handler.NewTrackHandler(trackService, logger.Log)
+	userHandler := handler.NewUserHandler(userService, uploadLimits, logger.Log)
+	trackHandler := handler.NewTrackHandler(trackService, thumbnailService, uploadLimits, logger.Log)
 	oauthHandler := handler.NewOAuthHandler(oauthService, logger.Log)
-	adminHandler := handler.NewAdminHandler(trackService, albumService, logger.Log)
-	albumHandler := handler.NewAlbumHandler(albumService, logger.Log)
+	adminHandler := handler.NewAdminHandler(trackService, albumService, activityService, importService, userService, uploadLimits, logger.Log)
+	albumHandler := handler.NewAlbumHandler(albumService, thumbnailService, cfg.PreloadHints, logger.Log)
+	searchHandler := handler.NewSearchHandler(searchService, logger.Log)
+	genreHandler := handler.NewGenreHandler(genreService, logger.Log)
+	readinessHandler := handler.NewHealthHandler(db, minioClient, logger.Log)
+	liveHandler := handler.NewLiveHandler(trackService, cfg.AllowedOrigins, logger.Log)
 
 	// Setup router
-	router := setupRouter(authHandler, userHandler, trackHandler, oauthHandler, adminHandler, albumHandler, authService, userRepo)
+	router := setupRouter(cfg, authHandler, userHandler, trackHandler, oauthHandler, adminHandler, albumHandler, searchHandler, genreHandler, readinessHandler, liveHandler, authService, userRepo)
 
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         ":" + cfg.ServerPort,
 		Handler:      router,
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		// Leave room for the timeout middleware to write its 504
+		WriteTimeout: cfg.RequestTimeout + 5*time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Start background guest cleanup
+	cleanupCtx, stopCleanup := context.WithCancel(context.Background())
+	defer stopCleanup()
+	go runGuestCleanup(cleanupCtx, userRepo, cfg.GuestCleanupInterval, cfg.GuestMaxAge)
+
+	// Start flushing buffered plays; the last flush runs after the server stops
+	playsCtx, stopPlays := context.WithCancel(context.Background())
+	defer stopPlays()
+	playsDone := make(chan struct{})
+	go func() {
+		playTracker.Run(playsCtx)
+		close(playsDone)
+	}()
+
 	// Start server in a goroutine
 	go func() {
 		logger.Log.Info("Server started", "addr", server.Addr)
@@ -142,6 +234,22 @@ func main() {
 	<-quit
 
 	logger.Log.Info("Shutting down server...")
+	stopCleanup()
+
+	// Transcoding uploads can outlast server.Shutdown, so drain them first
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.ShutdownUploadTimeout)
+	var drained sync.WaitGroup
+	for name, svc := range map[string]interface{ Close(context.Context) error }{"albums": albumService, "tracks": trackService} {
+		drained.Add(1)
+		go func() {
+			defer drained.Done()
+			if err := svc.Close(drainCtx); err != nil {
+				logger.Log.Error("Uploads didn't finish before shutdown", "service", name, "error", err)
+			}
+		}()
+	}
+	drained.Wait()
+	cancelDrain()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -151,29 +259,59 @@ func main() {
 		os.Exit(1)
 	}
 
+	stopPlays()
+	<-playsDone
+
 	logger.Log.Info("Server shutdown complete")
 }
 
-func setupRouter(authHandler *handler.AuthHandler, userHandler *handler.UserHandler, trackHandler *handler.TrackHandler, oauthHandler *handler.OAuthHandler, adminHandler *handler.AdminHandler, albumHandler *handler.AlbumHandler, authService *service.AuthService, userRepo *repository.UserRepository) *chi.Mux {
+// runGuestCleanup periodically deletes guest users inactive for longer than maxAge
+func runGuestCleanup(ctx context.Context, userRepo *repository.UserRepository, interval, maxAge time.Duration) {
+	if interval <= 0 {
+		logger.Log.Info("Guest cleanup disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := userRepo.DeleteStaleGuests(ctx, maxAge)
+			if err != nil {
+				logger.Log.Error("Failed to delete stale guests", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				logger.Log.Info("Deleted stale guest users", "count", deleted)
+			}
+		}
+	}
+}
+
+func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, userHandler *handler.UserHandler, trackHandler *handler.TrackHandler, oauthHandler *handler.OAuthHandler, adminHandler *handler.AdminHandler, albumHandler *handler.AlbumHandler, searchHandler *handler.SearchHandler, genreHandler *handler.GenreHandler, readinessHandler *handler.HealthHandler, liveHandler *handler.LiveHandler, authService *service.AuthService, userRepo *repository.UserRepository) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Global middleware
-	r.Use(middleware.RequestID)
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.CORS)
-	
-	// Debug middleware to log all requests
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			logger.Log.Info("Incoming request", 
-				"method", r.Method, 
-				"path", r.URL.Path, 
-				"host", r.Host,
-				"user_agent", r.UserAgent())
-			next.ServeHTTP(w, r)
-		})
-	})
+	r.Use(middleware.RealIP(cfg.TrustedProxies))
+	r.Use(middleware.RequestID(cfg.TrustRequestID))
+	r.Use(middleware.Logger(logger.Log))
+	r.Use(middleware.Recoverer(logger.Log))
+	r.Use(middleware.CORS(cfg.AllowedOrigins, cfg.AllowedMethods, cfg.AllowedHeaders))
+	r.Use(middleware.Timeout(cfg.RequestTimeout, handler.RequestTimedOut))
+	r.Use(middleware.MaxBodyBytes(cfg.MaxBodySize))
+	uploadTimeout := middleware.Timeout(cfg.UploadTimeout, handler.RequestTimedOut)
+	// Streams and WebSockets last as long as the client listens
+	noTimeout := middleware.RouteTimeout(0)
+	longTimeout := middleware.RouteTimeout(cfg.LongRequestTimeout)
+	// Upload routes raise the body limit to their file limits
+	avatarBody := middleware.MaxBodyBytes(cfg.MaxAvatarSize + handler.MultipartOverhead)
+	coverBody := middleware.MaxBodyBytes(cfg.MaxCoverSize + handler.MultipartOverhead)
+	trackBody := middleware.MaxBodyBytes(cfg.MaxAudioSize + cfg.MaxCoverSize + handler.MultipartOverhead)
+	archiveBody := middleware.MaxBodyBytes(cfg.MaxArchiveSize + handler.MultipartOverhead)
 
 	// Health check
 	healthHandler := func(w http.ResponseWriter, r *http.Request) {
@@ -183,6 +321,9 @@ func setupRouter(authHandler *handler.AuthHandler, userHandler *handler.UserHand
 	r.Get("/health", healthHandler)
 	r.Head("/health", healthHandler)
 
+	// Readiness check (database and MinIO)
+	r.Get("/health/ready", readinessHandler.Ready)
+
 	// Swagger documentation
 	r.Get("/swagger/*", httpSwagger.WrapHandler)
 
@@ -191,6 +332,10 @@ func setupRouter(authHandler *handler.AuthHandler, userHandler *handler.UserHand
 		r.Post("/register", authHandler.Register)
 		r.Post("/login", authHandler.Login)
 		r.Post("/guest", authHandler.GuestLogin)
+		r.Post("/refresh", authHandler.Refresh)
+		r.Post("/logout", authHandler.Logout)
+		r.Get("/verify", authHandler.VerifyEmail)
+		r.Post("/verify/resend", authHandler.ResendVerification)
 
 		// OAuth routes
 		r.Get("/google/login", oauthHandler.OAuthLogin)
@@ -204,10 +349,16 @@ func setupRouter(authHandler *handler.AuthHandler, userHandler *handler.UserHand
 		// Public routes with optional authentication (lazy auth)
 		r.With(middleware.OptionalAuthMiddleware(authService)).Get("/", trackHandler.ListTracks)
 		r.With(middleware.OptionalAuthMiddleware(authService)).Get("/{id}", trackHandler.GetTrack)
-		r.With(middleware.OptionalAuthMiddleware(authService)).Get("/{id}/stream", trackHandler.StreamTrack)
-		r.With(middleware.OptionalAuthMiddleware(authService)).Head("/{id}/stream", trackHandler.StreamTrack) // Support HEAD for stream
+		r.With(noTimeout, middleware.OptionalAuthMiddleware(authService)).Get("/{id}/stream", trackHandler.StreamTrack)
+		r.With(noTimeout, middleware.OptionalAuthMiddleware(authService)).Head("/{id}/stream", trackHandler.StreamTrack) // Support HEAD for stream
+		r.With(middleware.OptionalAuthMiddleware(authService)).Get("/{id}/stream-url", trackHandler.GetStreamURL)
+		r.With(middleware.OptionalAuthMiddleware(authService)).Get("/{id}/next", trackHandler.GetNextTrack)
+		r.With(middleware.OptionalAuthMiddleware(authService)).Post("/{id}/play", trackHandler.IncrementPlays) // Debounced per user or client IP
+		r.Get("/{id}/lyrics", trackHandler.GetLyrics) // Public lyrics
 		r.Get("/{id}/cover", trackHandler.GetTrackCover) // Public cover access
 		r.Head("/{id}/cover", trackHandler.GetTrackCover) // Support HEAD for cover
+		// Public WebSocket with play and like counts
+		r.With(noTimeout).Get("/{id}/live", liveHandler.TrackStats)
 
 		// Protected routes (require authentication including guests)
 		r.Group(func(r chi.Router) {
@@ -215,16 +366,45 @@ func setupRouter(authHandler *handler.AuthHandler, userHandler *handler.UserHand
 			r.Use(middleware.RequireAuth(userRepo))
 
 			r.Get("/my", trackHandler.GetUserTracks)
-			r.Post("/{id}/play", trackHandler.IncrementPlays)
-			r.Post("/{id}/like", trackHandler.ToggleLike)
+			r.Post("/likes/check", trackHandler.CheckLikes)
+			r.Delete("/{id}", trackHandler.DeleteTrack) // Owner only
+
+			// Liking, unless guests are read-only (GUEST_CAN_LIKE)
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireNonGuest(cfg.GuestCanLike))
+
+				r.Post("/{id}/like", trackHandler.AddLike)
+				r.Delete("/{id}/like", trackHandler.RemoveLike)
+				r.Post("/{id}/like/toggle", trackHandler.ToggleLike) // Deprecated: use POST/DELETE /{id}/like
+				r.Put("/{id}/like", trackHandler.SetLike)
+			})
+			r.Get("/{id}/likes", trackHandler.GetTrackLikers)
 		})
 	})
 
+	// Search (public, like status for authenticated users)
+	r.With(middleware.OptionalAuthMiddleware(authService)).Get("/api/search", searchHandler.Search)
+
+	// Server time for clock-skew handling (token expiry only with a valid token)
+	r.Get("/api/time", authHandler.ServerTime)
+
+	// Artist routes (public)
+	r.Get("/api/artists", trackHandler.ListArtists)
+	r.With(middleware.OptionalAuthMiddleware(authService)).Get("/api/radio", trackHandler.Radio)
+	r.Get("/api/artists/{name}/albums", albumHandler.GetArtistAlbums)
+
+	// Genre routes (public)
+	r.Get("/api/genres", genreHandler.ListGenres)
+
 	// Album routes (public)
 	r.Route("/api/albums", func(r chi.Router) {
 		r.Get("/", albumHandler.GetAlbums)
-		r.Get("/{id}", albumHandler.GetAlbumByID)
-		r.Get("/{id}/info", albumHandler.GetAlbumInfo)
+		r.Get("/featured", albumHandler.GetFeaturedAlbums)
+		r.With(middleware.OptionalAuthMiddleware(authService)).Get("/{id}", albumHandler.GetAlbumByID)
+		r.With(middleware.OptionalAuthMiddleware(authService)).Get("/{id}/info", albumHandler.GetAlbumInfo)
+		r.Get("/{id}/similar", albumHandler.GetSimilarAlbums)
+		r.Get("/{id}/export.m3u", albumHandler.ExportAlbumM3U)
+		r.With(middleware.OptionalAuthMiddleware(authService)).Get("/{id}/tracks", trackHandler.ListAlbumTracks)
 		r.Get("/{id}/cover", albumHandler.GetAlbumCover) // Public album cover access
 		r.Head("/{id}/cover", albumHandler.GetAlbumCover) // Support HEAD for cover
 	})
@@ -237,14 +417,21 @@ func setupRouter(authHandler *handler.AuthHandler, userHandler *handler.UserHand
 		r.Route("/api/users", func(r chi.Router) {
 			r.Get("/me", userHandler.GetMe)
 			r.Put("/me", userHandler.UpdateMe)
-			r.Post("/me/avatar", userHandler.UploadAvatar)
+			r.Patch("/me", userHandler.UpdateMe)
+			r.Delete("/me", userHandler.DeleteMe)
+			r.Get("/me/permissions", userHandler.GetPermissions)
+			r.Get("/me/stats", userHandler.GetStats)
+			r.With(uploadTimeout, avatarBody).Post("/me/avatar", userHandler.UploadAvatar)
 			r.Delete("/me/avatar", userHandler.RemoveAvatar)
-			r.Post("/player-state", userHandler.UpdatePlayerState) // Move player-state under /api/users/
+			r.Get("/me/recent-albums", albumHandler.GetRecentAlbums)
+			r.Get("/me/link/{provider}", oauthHandler.LinkAccount)
+			r.With(middleware.RequireNonGuest(cfg.GuestCanSavePlayerState)).Post("/player-state", userHandler.UpdatePlayerState) // Move player-state under /api/users/
 		})
 	})
 
 	// Public avatar serving (no auth required)
 	r.Get("/api/avatars/*", userHandler.GetAvatar)
+	r.Head("/api/avatars/*", userHandler.GetAvatar) // Support HEAD for avatars
 
 	// Admin routes (require admin role)
 	r.Group(func(r chi.Router) {
@@ -254,25 +441,70 @@ func setupRouter(authHandler *handler.AuthHandler, userHandler *handler.UserHand
 		r.Route("/api/admin", func(r chi.Router) {
 			// Album management (admin only)
 			r.Route("/albums", func(r chi.Router) {
-				r.Post("/", adminHandler.CreateAlbum)
+				r.Get("/", adminHandler.ListAlbums) // Includes drafts
+				r.With(uploadTimeout, coverBody).Post("/", adminHandler.CreateAlbum)
 				r.Delete("/{id}", adminHandler.DeleteAlbum)
-				r.Post("/{id}/tracks", adminHandler.AddTrackToAlbum)
+				r.With(uploadTimeout, coverBody).Put("/{id}/cover", adminHandler.UpdateAlbumCover)
+				r.With(longTimeout).Get("/{id}/export", adminHandler.ExportAlbum)
+				r.With(uploadTimeout, trackBody).Post("/{id}/tracks", adminHandler.AddTrackToAlbum)
+				r.With(longTimeout, archiveBody).Post("/{id}/tracks/bulk", adminHandler.BulkUploadTracks)
+				r.Put("/{id}/tracks/order", adminHandler.ReorderAlbumTracks)
+				r.Put("/{id}/featured", adminHandler.SetAlbumFeatured)
+				r.Post("/{id}/publish", adminHandler.PublishAlbum)
 			})
 
 			// Track management (admin only)
 			r.Route("/tracks", func(r chi.Router) {
-				r.Post("/upload", trackHandler.UploadTrack)
+				r.With(uploadTimeout, trackBody).Post("/upload", trackHandler.UploadTrack)
 				r.Delete("/{id}", adminHandler.DeleteTrack)
+				r.Put("/{id}/lyrics", adminHandler.SetTrackLyrics)
+				r.Put("/{id}/status", adminHandler.SetTrackStatus)
+				r.Put("/{id}/album", adminHandler.MoveTrack)
 			})
+
+			// User management (admin only)
+			r.Get("/users", adminHandler.ListUsers)
+			r.Put("/users/{id}/role", adminHandler.UpdateUserRole)
+
+			// Bulk catalog import (admin only)
+			r.With(longTimeout, middleware.MaxBodyBytes(handler.MaxImportManifestSize)).Post("/import", adminHandler.ImportCatalog)
+
+			// Maintenance jobs (admin only)
+			r.With(longTimeout).Post("/maintenance/backfill-durations", adminHandler.BackfillDurations)
+			r.With(longTimeout).Post("/maintenance/verify-storage", adminHandler.VerifyStorage)
+
+			// Activity feed (admin only)
+			r.Get("/activity", adminHandler.ListActivity)
+
+			// Analytics (admin only)
+			r.Get("/analytics/top-tracks", adminHandler.TopTracks)
 		})
 	})
 
-	// Add 404 handler
-	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
-		logger.Log.Warn("Route not found", "method", r.Method, "path", r.URL.Path, "host", r.Host)
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Not Found"))
-	})
+	// Unknown routes and wrong methods get the same JSON errors as handlers
+	r.NotFound(handler.NotFound)
+	r.MethodNotAllowed(handler.MethodNotAllowed(r))
 
 	return r
 }
+
+// runMigrate handles "migrate up" and "migrate down [-steps N]"
+func runMigrate(ctx context.Context, migrator *migrations.Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | migrate down [-steps N]")
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := fs.Int("steps", 1, "number of migrations to roll back")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return migrator.Down(ctx, *steps)
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up or down", args[0])
+	}
+}
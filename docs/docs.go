@@ -1760,8 +1760,8 @@ const docTemplate = `{
                     "example": "newuser@example.com"
                 },
                 "password": {
+                    "description": "Правила задаются PASSWORD_* (по умолчанию от 8 символов, буква и цифра)",
                     "type": "string",
-                    "minLength": 6,
                     "example": "password123"
                 }
             }
@@ -1,27 +1,49 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 
 	"koteyye_music_be/internal/middleware"
 	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/repository"
 	"koteyye_music_be/internal/service"
+	"koteyye_music_be/pkg/filetype"
 )
 
 type AdminHandler struct {
-	trackService *service.TrackService
-	albumService *service.AlbumService
-	logger       *slog.Logger
+	trackService    *service.TrackService
+	albumService    *service.AlbumService
+	activityService *service.ActivityService
+	importService   *service.ImportService
+	userService     *service.UserService
+	uploadLimits    UploadLimits
+	logger          *slog.Logger
 }
 
-func NewAdminHandler(trackService *service.TrackService, albumService *service.AlbumService, log *slog.Logger) *AdminHandler {
+// MaxImportManifestSize limits the JSON body of an import request; it replaces the
+// general body limit on that route
+const MaxImportManifestSize = 5 << 20
+
+func NewAdminHandler(trackService *service.TrackService, albumService *service.AlbumService, activityService *service.ActivityService, importService *service.ImportService, userService *service.UserService, uploadLimits UploadLimits, log *slog.Logger) *AdminHandler {
 	return &AdminHandler{
-		trackService: trackService,
-		albumService: albumService,
-		logger:       log,
+		trackService:    trackService,
+		albumService:    albumService,
+		activityService: activityService,
+		importService:   importService,
+		userService:     userService,
+		uploadLimits:    uploadLimits,
+		logger:          log,
 	}
 }
 
@@ -36,19 +58,20 @@ func NewAdminHandler(trackService *service.TrackService, albumService *service.A
 // @Param genre formData string true "Music genre (pop, rock, hip-hop, rap, indie, electronic, house, techno, jazz, blues, classical, metal, punk, r-n-b, soul, folk, reggae, country, latin, k-pop, soundtrack, lo-fi, chanson)"
 // @Param release_date formData string true "Release date (YYYY-MM-DD)"
 // @Param cover formData file true "Album cover image (JPG, PNG)"
+// @Param force formData bool false "Create the album even if one with the same title, artist and release date exists"
 // @Success 201 {object} models.AlbumResponse
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]string "Album already exists"
 // @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 413 {object} map[string]string "Cover image too large"
 // @Router /api/admin/albums [post]
 func (h *AdminHandler) CreateAlbum(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// Parse multipart form
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		h.logger.Error("Failed to parse multipart form", "error", err)
-		sendErrorResponse(w, http.StatusBadRequest, "Invalid form data")
+	// Parse multipart form, rejecting oversized covers with 413
+	if !parseUploadForm(w, r, h.uploadLimits.MaxCoverSize, h.uploadLimits.coverTooLargeMessage()) {
 		return
 	}
 
@@ -63,6 +86,16 @@ func (h *AdminHandler) CreateAlbum(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	force := false
+	if value := r.FormValue("force"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: "force must be true or false"})
+			return
+		}
+		force = parsed
+	}
+
 	// Get cover file
 	coverFile, coverHeader, err := r.FormFile("cover")
 	if err != nil {
@@ -72,20 +105,33 @@ func (h *AdminHandler) CreateAlbum(w http.ResponseWriter, r *http.Request) {
 	}
 	defer coverFile.Close()
 
+	if !checkFileSize(w, coverHeader, h.uploadLimits.MaxCoverSize, h.uploadLimits.coverTooLargeMessage()) {
+		return
+	}
+
 	// Create album request
 	albumReq := &models.AlbumCreate{
 		Title:       title,
 		Artist:      artist,
 		Genre:       genre,
 		ReleaseDate: releaseDate,
+		Force:       force,
 	}
 
 	// Create album
 	album, err := h.albumService.CreateAlbum(ctx, albumReq, coverFile, coverHeader)
 	if err != nil {
 		h.logger.Error("Failed to create album", "error", err)
-		if strings.Contains(err.Error(), "invalid genre") {
-			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		if errors.Is(err, service.ErrAlbumExists) {
+			sendServiceError(w, http.StatusConflict, err.Error(), err)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidGenre) || strings.Contains(err.Error(), "invalid cover image") || errors.Is(err, filetype.ErrMismatch) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		if errors.Is(err, service.ErrShuttingDown) {
+			sendServiceError(w, http.StatusServiceUnavailable, "Server is shutting down, retry later", err)
 			return
 		}
 		sendErrorResponse(w, http.StatusInternalServerError, "Failed to create album")
@@ -99,38 +145,195 @@ func (h *AdminHandler) CreateAlbum(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(album)
 }
 
+// UpdateAlbumCover replaces the cover image of an album (admin only)
+// @Summary Update Album Cover
+// @Security BearerAuth
+// @Tags admin
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Album ID"
+// @Param cover formData file true "Album cover image (JPG, PNG)"
+// @Success 200 {object} models.AlbumResponse
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Album not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 413 {object} map[string]string "Cover image too large"
+// @Router /api/admin/albums/{id}/cover [put]
+func (h *AdminHandler) UpdateAlbumCover(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Get album ID from URL parameter
+	albumID := chi.URLParam(r, "id")
+	if albumID == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "Album ID is required")
+		return
+	}
+
+	// Parse multipart form, rejecting oversized covers with 413
+	if !parseUploadForm(w, r, h.uploadLimits.MaxCoverSize, h.uploadLimits.coverTooLargeMessage()) {
+		return
+	}
+
+	// Get cover file
+	coverFile, coverHeader, err := r.FormFile("cover")
+	if err != nil {
+		h.logger.Error("Failed to get cover file", "error", err)
+		sendErrorResponse(w, http.StatusBadRequest, "Cover image is required")
+		return
+	}
+	defer coverFile.Close()
+
+	if !checkFileSize(w, coverHeader, h.uploadLimits.MaxCoverSize, h.uploadLimits.coverTooLargeMessage()) {
+		return
+	}
+
+	album, err := h.albumService.UpdateAlbumCover(ctx, albumID, coverFile, coverHeader)
+	if err != nil {
+		h.logger.Error("Failed to update album cover", "album_id", albumID, "error", err)
+		if errors.Is(err, repository.ErrNotFound) {
+			sendServiceError(w, http.StatusNotFound, "Album not found", err)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid cover image") || errors.Is(err, filetype.ErrMismatch) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to update album cover")
+		return
+	}
+
+	h.logger.Info("Album cover updated successfully", "album_id", albumID)
+
+	sendJSONResponse(w, http.StatusOK, album)
+}
+
+// ExportAlbum streams the album's audio files as a zip archive (admin only)
+// @Summary Export Album as Zip
+// @Security BearerAuth
+// @Tags admin
+// @Produce application/zip
+// @Param id path string true "Album ID"
+// @Success 200 {file} binary "Zip archive with album tracks"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Album not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/albums/{id}/export [get]
+func (h *AdminHandler) ExportAlbum(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Get album ID from URL parameter
+	albumID := chi.URLParam(r, "id")
+	if albumID == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "Album ID is required")
+		return
+	}
+
+	// Load the tracks before committing to a zip response, so a missing
+	// album is still answered with a JSON error
+	albumDetail, err := h.albumService.GetAlbumWithTracks(ctx, albumID, 0, true)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendServiceError(w, http.StatusNotFound, "Album not found", err)
+			return
+		}
+		h.logger.Error("Failed to get album for export", "album_id", albumID, "error", err)
+		sendServiceError(w, http.StatusInternalServerError, "Failed to get album", err)
+		return
+	}
+
+	album := albumDetail.Album
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", contentDisposition("attachment", album.Artist+" - "+album.Title+".zip"))
+
+	// Headers are sent with the first byte, errors after that can only be logged
+	if err := h.albumService.ExportAlbum(ctx, albumDetail, w); err != nil {
+		h.logger.Error("Failed to export album", "album_id", albumID, "error", err)
+		return
+	}
+
+	h.logger.Info("Album exported successfully", "album_id", albumID)
+}
+
 // AddTrackToAlbum adds a track to an existing album (admin only)
 // @Summary Add Track to Album
 // @Security BearerAuth
 // @Tags admin
 // @Accept multipart/form-data
 // @Produce json
+// @Description A retry with the same Idempotency-Key header returns the track created by the first request instead of uploading it again.
 // @Param id path string true "Album ID"
+// @Param Idempotency-Key header string false "Client-chosen key (up to 255 characters) that makes retries safe"
 // @Param title formData string true "Track title"
 // @Param artist formData string false "Track artist (optional, uses album artist if empty)"
 // @Param audio formData file true "Audio file (MP3, WAV, M4A, FLAC)"
+// @Param cover formData file false "Track cover image (JPG, PNG); the album cover is used when omitted"
 // @Success 201 {object} models.TrackResponse
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 404 {object} map[string]string "Album not found"
+// @Failure 409 {object} map[string]string "Album already contains this audio file, or the idempotency key is in use"
 // @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 413 {object} map[string]string "Audio file or cover image too large"
 // @Router /api/admin/albums/{id}/tracks [post]
 func (h *AdminHandler) AddTrackToAlbum(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Get album ID from URL
-	albumID := strings.TrimPrefix(r.URL.Path, "/api/admin/albums/")
-	albumID = strings.TrimSuffix(albumID, "/tracks")
+	albumID := chi.URLParam(r, "id")
 	if albumID == "" {
 		sendErrorResponse(w, http.StatusBadRequest, "Album ID is required")
 		return
 	}
 
-	// Parse multipart form
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		h.logger.Error("Failed to parse multipart form", "error", err)
-		sendErrorResponse(w, http.StatusBadRequest, "Invalid form data")
+	// Get user ID from context (set by auth middleware)
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	// A repeated Idempotency-Key is answered before the upload body is read
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	completed := false
+	if idempotencyKey != "" {
+		track, err := h.albumService.BeginIdempotentUpload(ctx, userID, idempotencyKey, albumID)
+		if err != nil {
+			h.logger.Error("Failed to check idempotency key", "album_id", albumID, "error", err)
+			switch {
+			case errors.Is(err, service.ErrInvalidIdempotencyKey):
+				sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+			case errors.Is(err, repository.ErrNotFound):
+				sendServiceError(w, http.StatusNotFound, "Album not found", err)
+			case errors.Is(err, service.ErrIdempotencyKeyInProgress), errors.Is(err, service.ErrIdempotencyKeyReused):
+				sendServiceError(w, http.StatusConflict, err.Error(), err)
+			default:
+				sendErrorResponse(w, http.StatusInternalServerError, "Failed to add track to album")
+			}
+			return
+		}
+		if track != nil {
+			h.logger.Info("Replayed track upload", "album_id", albumID, "track_id", track.ID)
+			sendJSONResponse(w, http.StatusCreated, track)
+			return
+		}
+
+		// Free the key when the upload fails so the client can retry with it
+		defer func() {
+			if completed {
+				return
+			}
+			if err := h.albumService.ReleaseIdempotentUpload(context.WithoutCancel(ctx), userID, idempotencyKey); err != nil {
+				h.logger.Error("Failed to release idempotency key", "user_id", userID, "error", err)
+			}
+		}()
+	}
+
+	// Parse multipart form, rejecting oversized audio with 413
+	if !parseUploadForm(w, r, h.uploadLimits.MaxAudioSize+h.uploadLimits.MaxCoverSize, h.uploadLimits.audioTooLargeMessage()) {
 		return
 	}
 
@@ -152,13 +355,23 @@ func (h *AdminHandler) AddTrackToAlbum(w http.ResponseWriter, r *http.Request) {
 	}
 	defer audioFile.Close()
 
-	// Get user ID from context (set by auth middleware)
-	userID, ok := middleware.GetUserID(ctx)
-	if !ok {
-		sendErrorResponse(w, http.StatusUnauthorized, "User not found")
+	if !checkFileSize(w, audioHeader, h.uploadLimits.MaxAudioSize, h.uploadLimits.audioTooLargeMessage()) {
 		return
 	}
 
+	// Get optional track cover
+	coverFile, coverHeader, err := r.FormFile("cover")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid cover image")
+		return
+	}
+	if coverFile != nil {
+		defer coverFile.Close()
+		if !checkFileSize(w, coverHeader, h.uploadLimits.MaxCoverSize, h.uploadLimits.coverTooLargeMessage()) {
+			return
+		}
+	}
+
 	// Create track request
 	var artistPtr *string
 	if artist != "" {
@@ -172,15 +385,23 @@ func (h *AdminHandler) AddTrackToAlbum(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Add track to album
-	track, err := h.albumService.AddTrackToAlbum(ctx, albumID, userID, trackReq, audioFile, audioHeader)
+	track, err := h.albumService.AddTrackToAlbum(ctx, albumID, userID, trackReq, audioFile, audioHeader, coverFile, coverHeader)
 	if err != nil {
 		h.logger.Error("Failed to add track to album", "album_id", albumID, "error", err)
-		if strings.Contains(err.Error(), "album not found") {
-			sendErrorResponse(w, http.StatusNotFound, "Album not found")
+		if errors.Is(err, repository.ErrNotFound) {
+			sendServiceError(w, http.StatusNotFound, "Album not found", err)
+			return
+		}
+		if errors.Is(err, service.ErrDuplicateTrack) {
+			sendServiceError(w, http.StatusConflict, err.Error(), err)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid audio format") || strings.Contains(err.Error(), "invalid cover image") || errors.Is(err, filetype.ErrMismatch) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
 			return
 		}
-		if strings.Contains(err.Error(), "invalid audio format") {
-			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		if errors.Is(err, service.ErrShuttingDown) {
+			sendServiceError(w, http.StatusServiceUnavailable, "Server is shutting down, retry later", err)
 			return
 		}
 		sendErrorResponse(w, http.StatusInternalServerError, "Failed to add track to album")
@@ -189,11 +410,474 @@ func (h *AdminHandler) AddTrackToAlbum(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.Info("Track added to album successfully", "album_id", albumID, "track_id", track.ID)
 
+	if idempotencyKey != "" {
+		if err := h.albumService.CompleteIdempotentUpload(context.WithoutCancel(ctx), userID, idempotencyKey, track.ID); err != nil {
+			h.logger.Error("Failed to store idempotency key", "user_id", userID, "track_id", track.ID, "error", err)
+		} else {
+			completed = true
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(track)
 }
 
+// BulkUploadTracks adds every audio file of a zip archive to an album (admin only)
+// @Summary Bulk Upload Tracks
+// @Description Entries are processed one at a time. A leading number in the file name ("01 - Title.mp3") sets the track number and the rest of the name the title; unnumbered files are appended. Non-audio files are ignored, and a failing file doesn't stop the others.
+// @Security BearerAuth
+// @Tags admin
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Album ID"
+// @Param archive formData file true "Zip archive with MP3, WAV, M4A or FLAC files"
+// @Param artist formData string false "Artist for all tracks (optional, uses album artist if empty)"
+// @Success 200 {object} models.BulkTrackUploadResponse "Succeeded and failed files"
+// @Failure 400 {object} map[string]string "Bad request - not a zip archive or no audio files"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Album not found"
+// @Failure 413 {object} map[string]string "Archive too large"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/albums/{id}/tracks/bulk [post]
+func (h *AdminHandler) BulkUploadTracks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	albumID := chi.URLParam(r, "id")
+	if albumID == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "Album ID is required")
+		return
+	}
+
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if !parseUploadForm(w, r, h.uploadLimits.MaxArchiveSize, h.uploadLimits.archiveTooLargeMessage()) {
+		return
+	}
+
+	archive, archiveHeader, err := r.FormFile("archive")
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "Archive file is required")
+		return
+	}
+	defer archive.Close()
+
+	if !checkFileSize(w, archiveHeader, h.uploadLimits.MaxArchiveSize, h.uploadLimits.archiveTooLargeMessage()) {
+		return
+	}
+
+	var artist *string
+	if value := strings.TrimSpace(r.FormValue("artist")); value != "" {
+		artist = &value
+	}
+
+	result, err := h.albumService.BulkAddTracks(ctx, albumID, userID, artist, archive, archiveHeader.Size, h.uploadLimits.MaxAudioSize)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendServiceError(w, http.StatusNotFound, "Album not found", err)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidArchive) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		h.logger.Error("Failed to bulk upload tracks", "album_id", albumID, "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to upload tracks")
+		return
+	}
+
+	h.logger.Info("Bulk track upload by admin", "album_id", albumID, "succeeded", len(result.Succeeded), "failed", len(result.Failed))
+	sendJSONResponse(w, http.StatusOK, result)
+}
+
+// ImportCatalog bulk-imports albums and tracks from a JSON manifest (admin only)
+// @Summary Import Catalog
+// @Description Creates albums with their tracks from a manifest. Files are referenced by keys already uploaded under albums/{album_id}/ or by http(s) URLs to fetch. Each album is stored in its own transaction; albums whose ID already exists are skipped, so the same manifest can be re-run after a partial failure. Per-album and per-track results are returned.
+// @Security BearerAuth
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param input body models.ImportManifest true "Import manifest"
+// @Success 200 {object} models.ImportResponse "Per-item import results"
+// @Failure 400 {object} map[string]string "Bad request - invalid manifest"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 413 {object} map[string]string "Manifest too large"
+// @Router /api/admin/import [post]
+func (h *AdminHandler) ImportCatalog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	var manifest models.ImportManifest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxImportManifestSize)).Decode(&manifest); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			sendErrorResponse(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Manifest exceeds %d bytes", MaxImportManifestSize))
+			return
+		}
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid manifest format")
+		return
+	}
+
+	if len(manifest.Albums) == 0 {
+		sendErrorResponse(w, http.StatusBadRequest, "Manifest contains no albums")
+		return
+	}
+	if len(manifest.Albums) > models.MaxImportAlbums {
+		sendErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Manifest may contain at most %d albums", models.MaxImportAlbums))
+		return
+	}
+
+	response := h.importService.Import(ctx, userID, &manifest)
+	sendJSONResponse(w, http.StatusOK, response)
+}
+
+// ReorderAlbumTracks sets the order of an album's tracks (admin only)
+// @Summary Reorder Album Tracks
+// @Description Numbers the album's tracks in the given order. The list must contain every track of the album exactly once.
+// @Security BearerAuth
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Album ID"
+// @Param input body models.TrackOrderRequest true "All album track IDs in the new order"
+// @Success 200 {object} models.AlbumDetail "Album with reordered tracks"
+// @Failure 400 {object} map[string]string "Bad request - list doesn't match the album's tracks"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Album not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/albums/{id}/tracks/order [put]
+func (h *AdminHandler) ReorderAlbumTracks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	albumID := chi.URLParam(r, "id")
+	if albumID == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "Album ID is required")
+		return
+	}
+
+	var req models.TrackOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	if len(req.TrackIDs) == 0 {
+		sendErrorResponse(w, http.StatusBadRequest, "track_ids is required")
+		return
+	}
+
+	album, err := h.albumService.ReorderTracks(ctx, albumID, req.TrackIDs)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendServiceError(w, http.StatusNotFound, "Album not found", err)
+			return
+		}
+		if errors.Is(err, service.ErrTrackOrderMismatch) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		h.logger.Error("Failed to reorder album tracks", "album_id", albumID, "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to reorder tracks")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, album)
+}
+
+// SetAlbumFeatured adds an album to or removes it from the featured section (admin only)
+// @Summary Set Album Featured
+// @Description Marks or unmarks an album as featured. order sets its position in GET /api/albums/featured; without it a newly featured album goes last.
+// @Security BearerAuth
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Album ID"
+// @Param input body models.AlbumFeaturedRequest true "Featured flag and optional position"
+// @Success 200 {object} models.AlbumResponse "Updated album"
+// @Failure 400 {object} map[string]string "Bad request - invalid order"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Album not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/albums/{id}/featured [put]
+func (h *AdminHandler) SetAlbumFeatured(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	albumID := chi.URLParam(r, "id")
+	if albumID == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "Album ID is required")
+		return
+	}
+
+	var req models.AlbumFeaturedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	album, err := h.albumService.SetAlbumFeatured(ctx, albumID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidFeaturedOrder) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			sendServiceError(w, http.StatusNotFound, "Album not found", err)
+			return
+		}
+		h.logger.Error("Failed to set album featured flag", "album_id", albumID, "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to update album")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, album)
+}
+
+// ListAlbums returns albums including drafts (admin only)
+// @Summary List Albums (Admin)
+// @Description Newest first. Unlike GET /api/albums draft albums are listed too, and track counts include draft tracks.
+// @Security BearerAuth
+// @Tags admin
+// @Produce json
+// @Param status query string false "Only albums in this state (draft, published)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page (max 100)" default(20)
+// @Success 200 {object} models.AlbumListResponse "Albums with pagination"
+// @Failure 400 {object} map[string]string "Bad request - invalid status"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/albums [get]
+func (h *AdminHandler) ListAlbums(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	filter := models.AlbumFilter{IncludeDrafts: true, Status: strings.TrimSpace(query.Get("status"))}
+	if filter.Status != "" && filter.Status != models.StatusDraft && filter.Status != models.StatusPublished {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: fmt.Sprintf("Invalid status. Allowed: %s, %s", models.StatusDraft, models.StatusPublished)})
+		return
+	}
+
+	albums, total, err := h.albumService.GetAllAlbums(ctx, limit, (page-1)*limit, filter)
+	if err != nil {
+		h.logger.Error("Failed to list albums", "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to list albums")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.AlbumListResponse{
+		Albums: albums,
+		Pagination: models.Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		},
+	})
+}
+
+// PublishAlbum makes a draft album public (admin only)
+// @Summary Publish Album
+// @Description Publishes the album and all of its draft tracks. Single tracks can be drafted again with PUT /api/admin/tracks/{id}/status.
+// @Security BearerAuth
+// @Tags admin
+// @Produce json
+// @Param id path string true "Album ID"
+// @Success 200 {object} models.AlbumDetail "Published album with its tracks"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Album not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/albums/{id}/publish [post]
+func (h *AdminHandler) PublishAlbum(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	album, err := h.albumService.PublishAlbum(r.Context(), albumID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendServiceError(w, http.StatusNotFound, "Album not found", err)
+			return
+		}
+		h.logger.Error("Failed to publish album", "album_id", albumID, "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to publish album")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, album)
+}
+
+// SetTrackStatus drafts or publishes a single track (admin only)
+// @Summary Set Track Status
+// @Description A published track is only public while its album is published too.
+// @Security BearerAuth
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param input body models.TrackStatusRequest true "New status"
+// @Success 200 {object} models.TrackResponse "Updated track"
+// @Failure 400 {object} map[string]string "Bad request - invalid status"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Track not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/tracks/{id}/status [put]
+func (h *AdminHandler) SetTrackStatus(w http.ResponseWriter, r *http.Request) {
+	trackID := chi.URLParam(r, "id")
+
+	var req models.TrackStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidJSON, Message: "Invalid request format"})
+		return
+	}
+
+	track, err := h.trackService.SetTrackStatus(r.Context(), trackID, req.Status)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidStatus):
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+		case errors.Is(err, repository.ErrNotFound):
+			sendServiceError(w, http.StatusNotFound, "Track not found", err)
+		default:
+			h.logger.Error("Failed to set track status", "track_id", trackID, "error", err)
+			sendErrorResponse(w, http.StatusInternalServerError, "Failed to update track")
+		}
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, track)
+}
+
+// MoveTrack moves a track to another album (admin only)
+// @Summary Move Track To Album
+// @Description Moves the track and its stored files to another album and appends it to the end of that album. If the files can't be moved, the track stays in its album.
+// @Security BearerAuth
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param input body models.MoveTrackRequest true "Destination album"
+// @Success 200 {object} models.TrackResponse "Moved track"
+// @Failure 400 {object} map[string]string "Bad request - missing album_id"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Track or album not found"
+// @Failure 409 {object} map[string]string "Destination album already contains this audio"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/tracks/{id}/album [put]
+func (h *AdminHandler) MoveTrack(w http.ResponseWriter, r *http.Request) {
+	trackID := chi.URLParam(r, "id")
+
+	var req models.MoveTrackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidJSON, Message: "Invalid request format"})
+		return
+	}
+	if req.AlbumID == "" {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "album_id is required"})
+		return
+	}
+
+	track, err := h.trackService.MoveTrackToAlbum(r.Context(), trackID, req.AlbumID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAlbumNotFound):
+			sendServiceError(w, http.StatusNotFound, "Album not found", err)
+		case errors.Is(err, repository.ErrNotFound):
+			sendServiceError(w, http.StatusNotFound, "Track not found", err)
+		case errors.Is(err, service.ErrDuplicateTrack):
+			sendServiceError(w, http.StatusConflict, "Album already contains this track", err)
+		case errors.Is(err, service.ErrShuttingDown):
+			sendServiceError(w, http.StatusServiceUnavailable, "Server is shutting down, retry later", err)
+		default:
+			h.logger.Error("Failed to move track", "track_id", trackID, "album_id", req.AlbumID, "error", err)
+			sendErrorResponse(w, http.StatusInternalServerError, "Failed to move track")
+		}
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, track)
+}
+
+// BackfillDurations fills in the duration, bitrate and format of tracks stored without them (admin only)
+// @Summary Backfill Track Audio Info
+// @Description Downloads the audio of every track whose duration is 0 or whose bitrate or format is unknown, measures it with ffprobe and stores the result. Tracks that fail are logged and counted; they don't stop the batch.
+// @Security BearerAuth
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.BackfillDurationsResponse "Number of checked, fixed and failed tracks"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/maintenance/backfill-durations [post]
+func (h *AdminHandler) BackfillDurations(w http.ResponseWriter, r *http.Request) {
+	result, err := h.trackService.BackfillDurations(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to backfill track durations", "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to backfill track durations")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, result)
+}
+
+// VerifyStorage reports drift between the database and MinIO (admin only)
+// @Summary Verify Storage Integrity
+// @Description Checks that the audio, renditions and covers of every track and album exist in MinIO and lists objects under albums/ that no track or album refers to. Nothing is deleted.
+// @Security BearerAuth
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.StorageReport "Missing and orphaned objects"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/maintenance/verify-storage [post]
+func (h *AdminHandler) VerifyStorage(w http.ResponseWriter, r *http.Request) {
+	report, err := h.trackService.VerifyStorage(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to verify storage", "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to verify storage")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, report)
+}
+
 // DeleteAlbum deletes an album and all its tracks (admin only)
 // @Summary Delete Album
 // @Security BearerAuth
@@ -210,7 +894,7 @@ func (h *AdminHandler) DeleteAlbum(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Get album ID from URL
-	albumID := strings.TrimPrefix(r.URL.Path, "/api/admin/albums/")
+	albumID := chi.URLParam(r, "id")
 	if albumID == "" {
 		sendErrorResponse(w, http.StatusBadRequest, "Album ID is required")
 		return
@@ -221,8 +905,8 @@ func (h *AdminHandler) DeleteAlbum(w http.ResponseWriter, r *http.Request) {
 	// Delete album
 	if err := h.albumService.DeleteAlbum(ctx, albumID); err != nil {
 		h.logger.Error("Failed to delete album", "album_id", albumID, "error", err)
-		if strings.Contains(err.Error(), "album not found") {
-			sendErrorResponse(w, http.StatusNotFound, "Album not found")
+		if errors.Is(err, repository.ErrNotFound) {
+			sendServiceError(w, http.StatusNotFound, "Album not found", err)
 			return
 		}
 		sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete album")
@@ -233,6 +917,51 @@ func (h *AdminHandler) DeleteAlbum(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// SetTrackLyrics creates or replaces the lyrics of a track (admin only)
+// @Summary Set Track Lyrics
+// @Description Stores plain-text or LRC lyrics. Content with [mm:ss.xx] line timestamps is marked as synced.
+// @Security BearerAuth
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param input body models.LyricsRequest true "Lyrics content and optional language tag"
+// @Success 200 {object} models.Lyrics "Saved lyrics"
+// @Failure 400 {object} map[string]string "Bad request - empty or oversized content, invalid language"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Track not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/tracks/{id}/lyrics [put]
+func (h *AdminHandler) SetTrackLyrics(w http.ResponseWriter, r *http.Request) {
+	trackID := chi.URLParam(r, "id")
+
+	var req models.LyricsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidJSON, Message: "Invalid request format"})
+		return
+	}
+
+	lyrics, err := h.trackService.SetLyrics(r.Context(), trackID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidLyrics):
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+		case errors.Is(err, repository.ErrNotFound):
+			sendServiceError(w, http.StatusNotFound, "Track not found", err)
+		default:
+			h.logger.Error("Failed to set track lyrics", "track_id", trackID, "error", err)
+			sendErrorResponse(w, http.StatusInternalServerError, "Failed to save lyrics")
+		}
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, lyrics)
+}
+
 // DeleteTrack deletes a track by ID (admin only)
 // @Summary Delete Track (Admin)
 // @Security BearerAuth
@@ -249,7 +978,7 @@ func (h *AdminHandler) DeleteTrack(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Get track ID from URL parameter
-	trackID := r.URL.Path[len("/api/admin/tracks/"):]
+	trackID := chi.URLParam(r, "id")
 	if trackID == "" {
 		h.logger.Warn("Delete track request without ID")
 		sendErrorResponse(w, http.StatusBadRequest, "Track ID is required")
@@ -261,8 +990,8 @@ func (h *AdminHandler) DeleteTrack(w http.ResponseWriter, r *http.Request) {
 	// Delete track (service handles DB and MinIO deletion with consistency)
 	if err := h.trackService.DeleteTrack(ctx, trackID); err != nil {
 		h.logger.Error("Failed to delete track", "track_id", trackID, "error", err)
-		if err.Error() == "track not found" {
-			sendErrorResponse(w, http.StatusNotFound, "Track not found")
+		if errors.Is(err, repository.ErrNotFound) {
+			sendServiceError(w, http.StatusNotFound, "Track not found", err)
 			return
 		}
 		sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete track")
@@ -272,3 +1001,258 @@ func (h *AdminHandler) DeleteTrack(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Track deleted successfully by admin", "track_id", trackID)
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// TopTracks returns the most played tracks of a recent period (admin only)
+// @Summary Top Tracks Analytics
+// @Description Tracks ranked by plays recorded in play history within the last day, week (default) or month. period_plays is the count within the window; plays_count stays the all-time total, which also includes plays from before play history was kept.
+// @Security BearerAuth
+// @Tags admin
+// @Produce json
+// @Param period query string false "Time window" Enums(day, week, month) default(week)
+// @Param limit query int false "Number of tracks (max 100)" default(20)
+// @Success 200 {object} models.TopTracksResponse "Most played tracks"
+// @Failure 400 {object} map[string]string "Bad request - invalid period"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/analytics/top-tracks [get]
+func (h *AdminHandler) TopTracks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	period := strings.ToLower(strings.TrimSpace(query.Get("period")))
+	if period == "" {
+		period = "week"
+	}
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	response, err := h.trackService.GetTopTracks(r.Context(), period, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPeriod) {
+			sendServiceError(w, http.StatusBadRequest, "Invalid period. Allowed: day, week, month", err)
+			return
+		}
+		h.logger.Error("Failed to get top tracks", "period", period, "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to get top tracks")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, response)
+}
+
+// ListActivity returns the recent activity feed (admin only)
+// @Summary Admin Activity Feed
+// @Description Registrations, track uploads and album creations, newest first.
+// @Security BearerAuth
+// @Tags admin
+// @Produce json
+// @Param type query string false "Activity type (registration, track_upload, album_created)"
+// @Param from query string false "Start date, inclusive (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "End date, inclusive for YYYY-MM-DD, exclusive for RFC3339"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page (max 100)" default(50)
+// @Success 200 {object} models.ActivityListResponse "Activity feed with pagination"
+// @Failure 400 {object} map[string]string "Bad request - invalid filter"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/activity [get]
+func (h *AdminHandler) ListActivity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	// Get pagination parameters
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	filter := models.ActivityFilter{
+		Type: strings.TrimSpace(query.Get("type")),
+	}
+	if filter.Type != "" && !slices.Contains(models.ActivityTypes, filter.Type) {
+		sendErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid activity type. Allowed: %s", strings.Join(models.ActivityTypes, ", ")))
+		return
+	}
+
+	if from := query.Get("from"); from != "" {
+		t, _, err := parseActivityDate(from)
+		if err != nil {
+			sendErrorResponse(w, http.StatusBadRequest, "Invalid 'from' date. Use YYYY-MM-DD or RFC3339")
+			return
+		}
+		filter.From = &t
+	}
+
+	if to := query.Get("to"); to != "" {
+		t, dateOnly, err := parseActivityDate(to)
+		if err != nil {
+			sendErrorResponse(w, http.StatusBadRequest, "Invalid 'to' date. Use YYYY-MM-DD or RFC3339")
+			return
+		}
+		if dateOnly {
+			// Include the whole last day
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = &t
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		sendErrorResponse(w, http.StatusBadRequest, "'from' must be before 'to'")
+		return
+	}
+
+	activities, total, err := h.activityService.ListActivity(ctx, page, limit, filter)
+	if err != nil {
+		h.logger.Error("Failed to list activity", "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to list activity")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.ActivityListResponse{
+		Activities: activities,
+		Pagination: models.Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		},
+	})
+}
+
+// ListUsers returns registered and guest users (admin only)
+// @Summary List Users
+// @Description Newest first. Only profile fields are returned; password hashes and OAuth IDs never are.
+// @Security BearerAuth
+// @Tags admin
+// @Produce json
+// @Param role query string false "Role filter (user, admin, guest)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page (max 100)" default(20)
+// @Success 200 {object} models.AdminUserListResponse "Users with pagination"
+// @Failure 400 {object} map[string]string "Bad request - invalid role"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/users [get]
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	// Get pagination parameters
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	role := strings.TrimSpace(query.Get("role"))
+	users, total, err := h.userService.ListUsers(ctx, page, limit, role)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			sendServiceError(w, http.StatusBadRequest, fmt.Sprintf("Invalid role. Allowed: %s", strings.Join(models.UserRoles, ", ")), err)
+			return
+		}
+		h.logger.Error("Failed to list users", "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to list users")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.AdminUserListResponse{
+		Users: users,
+		Pagination: models.Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		},
+	})
+}
+
+// UpdateUserRole promotes or demotes a user (admin only)
+// @Summary Update User Role
+// @Description Sets the user's role. The change applies to the next admin request, since admin access is checked against the database. The last remaining admin can't be demoted.
+// @Security BearerAuth
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param input body models.UpdateRoleRequest true "New role"
+// @Success 200 {object} models.AdminUser "Updated user"
+// @Failure 400 {object} map[string]string "Bad request - invalid user ID or role"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 409 {object} map[string]string "Conflict - the user is the last admin"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/admin/users/{id}/role [put]
+func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || userID < 1 {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidID, Message: "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	adminID, _ := middleware.GetUserID(ctx)
+	user, err := h.userService.UpdateUserRole(ctx, userID, strings.TrimSpace(req.Role))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRole):
+			sendServiceError(w, http.StatusBadRequest, fmt.Sprintf("Invalid role. Allowed: %s", strings.Join(models.UserRoles, ", ")), err)
+		case errors.Is(err, service.ErrLastAdmin):
+			sendServiceError(w, http.StatusConflict, "Cannot demote the last remaining admin", err)
+		case errors.Is(err, repository.ErrUserNotFound):
+			sendServiceError(w, http.StatusNotFound, "User not found", err)
+		default:
+			h.logger.Error("Failed to update user role", "user_id", userID, "error", err)
+			sendErrorResponse(w, http.StatusInternalServerError, "Failed to update user role")
+		}
+		return
+	}
+
+	h.logger.Info("User role changed by admin", "admin_id", adminID, "user_id", userID, "role", user.Role)
+	sendJSONResponse(w, http.StatusOK, user)
+}
+
+// parseActivityDate parses a YYYY-MM-DD date or an RFC3339 timestamp as UTC.
+// dateOnly reports whether the value had no time part.
+func parseActivityDate(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t.UTC(), false, nil
+}
@@ -2,36 +2,55 @@ package handler
 
 import (
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
+	"koteyye_music_be/internal/middleware"
+	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/repository"
 	"koteyye_music_be/internal/service"
 )
 
+// minReleaseYear is the earliest release year accepted by album filters
+const minReleaseYear = 1900
+
 type AlbumHandler struct {
-	albumService *service.AlbumService
+	albumService     *service.AlbumService
+	thumbnailService *service.ThumbnailService
+	// preloadHints adds Link preload headers for the cover and first track to album details
+	preloadHints bool
 	logger       *slog.Logger
 }
 
-func NewAlbumHandler(albumService *service.AlbumService, log *slog.Logger) *AlbumHandler {
+func NewAlbumHandler(albumService *service.AlbumService, thumbnailService *service.ThumbnailService, preloadHints bool, log *slog.Logger) *AlbumHandler {
 	return &AlbumHandler{
-		albumService: albumService,
-		logger:       log,
+		albumService:     albumService,
+		thumbnailService: thumbnailService,
+		preloadHints:     preloadHints,
+		logger:           log,
 	}
 }
 
-// GetAlbums returns a list of all albums with optional genre filtering
+// GetAlbums returns a paginated list of albums with optional genre and year filtering
 // @Summary Get Albums
 // @Tags albums
 // @Produce json
 // @Param page query int false "Page number" default(1) minimum(1)
 // @Param limit query int false "Items per page" default(20) minimum(1) maximum(100)
-// @Param genre query string false "Filter by genre" example(rock)
-// @Success 200 {array} models.AlbumResponse
+// @Param genre query string false "Filter by genre (aliases like hiphop or rnb are accepted)" example(rock)
+// @Param year query int false "Filter by release year" example(2023)
+// @Param featured query bool false "true lists only featured albums, false excludes them; omit for all albums"
+// @Param created_after query string false "Only albums created at or after this RFC 3339 time" example(2024-01-01T00:00:00Z)
+// @Param created_before query string false "Only albums created at or before this RFC 3339 time" example(2024-02-01T00:00:00Z)
+// @Success 200 {object} models.AlbumListResponse
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/albums [get]
@@ -55,28 +74,259 @@ func (h *AlbumHandler) GetAlbums(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	// Get genre filter
-	genreFilter := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("genre")))
+	genre, ok := parseGenreFilter(w, r)
+	if !ok {
+		return
+	}
+	filter := models.AlbumFilter{
+		Genre: genre,
+	}
+
+	// Get release year filter
+	if yearParam := strings.TrimSpace(r.URL.Query().Get("year")); yearParam != "" {
+		year, err := strconv.Atoi(yearParam)
+		if err != nil || year < minReleaseYear || year > time.Now().Year()+1 {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: fmt.Sprintf("Year must be between %d and %d", minReleaseYear, time.Now().Year()+1)})
+			return
+		}
+		filter.Year = year
+	}
+
+	// Get featured filter
+	if featuredParam := strings.TrimSpace(r.URL.Query().Get("featured")); featuredParam != "" {
+		featured, err := strconv.ParseBool(featuredParam)
+		if err != nil {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: "featured must be true or false"})
+			return
+		}
+		filter.Featured = &featured
+	}
+
+	// Get creation time range
+	if filter.CreatedAfter, filter.CreatedBefore, ok = parseCreatedRange(w, r); !ok {
+		return
+	}
 
 	// Get albums
-	albums, err := h.albumService.GetAllAlbums(ctx, limit, offset, genreFilter)
+	albums, total, err := h.albumService.GetAllAlbums(ctx, limit, offset, filter)
 	if err != nil {
 		h.logger.Error("Failed to get albums", "error", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to get albums")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get albums"})
 		return
 	}
 
 	h.logger.Info("Albums retrieved successfully", "count", len(albums), "page", page)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(albums)
+	sendJSONResponse(w, http.StatusOK, models.AlbumListResponse{
+		Albums: albums,
+		Pagination: models.Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		},
+	})
+}
+
+// GetFeaturedAlbums returns the curated albums of the home page
+// @Summary Get Featured Albums
+// @Description Albums marked as featured by admins, ordered by their featured position
+// @Tags albums
+// @Produce json
+// @Param limit query int false "Maximum number of albums" default(20) minimum(1) maximum(100)
+// @Success 200 {object} models.FeaturedAlbumsResponse
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/albums/featured [get]
+func (h *AlbumHandler) GetFeaturedAlbums(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	albums, err := h.albumService.GetFeaturedAlbums(r.Context(), limit)
+	if err != nil {
+		h.logger.Error("Failed to get featured albums", "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get featured albums"})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.FeaturedAlbumsResponse{Albums: albums})
+}
+
+// GetArtistAlbums returns a page of an artist's albums and the artist's track count
+// @Summary Get Artist Albums
+// @Description Albums whose artist matches the name ignoring case, newest release first. The name is URL-decoded, so names with slashes or other special characters must be percent-encoded.
+// @Tags albums
+// @Produce json
+// @Param name path string true "Artist name" Example(Queen)
+// @Param page query int false "Page number" default(1) minimum(1)
+// @Param limit query int false "Items per page" default(20) minimum(1) maximum(100)
+// @Success 200 {object} models.ArtistAlbumsResponse
+// @Failure 400 {object} map[string]string "Bad request - invalid artist name"
+// @Failure 404 {object} map[string]string "Not found - artist has no albums or tracks"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/artists/{name}/albums [get]
+func (h *AlbumHandler) GetArtistAlbums(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// chi matches against the raw path when it has escapes, so the parameter is still encoded
+	artist := chi.URLParam(r, "name")
+	if r.URL.RawPath != "" {
+		decoded, err := url.PathUnescape(artist)
+		if err != nil {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: "Invalid artist name"})
+			return
+		}
+		artist = decoded
+	}
+	artist = strings.TrimSpace(artist)
+	if artist == "" || utf8.RuneCountInString(artist) > models.MaxArtistFilterLength {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: fmt.Sprintf("Artist name must be 1 to %d characters", models.MaxArtistFilterLength)})
+		return
+	}
+
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	albums, total, tracksCount, err := h.albumService.GetAlbumsByArtist(ctx, artist, limit, (page-1)*limit)
+	if err != nil {
+		if errors.Is(err, service.ErrArtistNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeNotFound, Message: "Artist not found"})
+			return
+		}
+		h.logger.Error("Failed to get artist albums", "artist", artist, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get artist albums"})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.ArtistAlbumsResponse{
+		Artist:      artist,
+		TracksCount: tracksCount,
+		Albums:      albums,
+		Pagination: models.Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		},
+	})
+}
+
+// GetSimilarAlbums returns albums related to an album by artist or genre
+// @Summary Get Similar Albums
+// @Description Albums sharing the artist or genre of the given album, excluding it. Same-artist albums come first, each group newest release first.
+// @Tags albums
+// @Produce json
+// @Param id path string true "Album ID" Example(550e8400-e29b-41d4-a716-446655440001)
+// @Param limit query int false "Maximum number of albums" default(10) minimum(1) maximum(50)
+// @Success 200 {object} models.SimilarAlbumsResponse
+// @Failure 404 {object} map[string]string "Not found - album does not exist"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/albums/{id}/similar [get]
+func (h *AlbumHandler) GetSimilarAlbums(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	albums, err := h.albumService.GetSimilarAlbums(r.Context(), albumID, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrAlbumNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeAlbumNotFound, Message: "Album not found"})
+			return
+		}
+		h.logger.Error("Failed to get similar albums", "album_id", albumID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get similar albums"})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.SimilarAlbumsResponse{Albums: albums})
+}
+
+// GetRecentAlbums returns albums the current user recently played tracks from
+// @Summary Get Recently Played Albums
+// @Description Distinct albums ordered by the latest play of any of their tracks. Guests always get an empty list.
+// @Security BearerAuth
+// @Tags users
+// @Produce json
+// @Param page query int false "Page number" default(1) minimum(1)
+// @Param limit query int false "Items per page" default(20) minimum(1) maximum(100)
+// @Success 200 {object} models.AlbumListResponse
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/users/me/recent-albums [get]
+func (h *AlbumHandler) GetRecentAlbums(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		sendAPIError(w, APIError{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Unauthorized"})
+		return
+	}
+
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset := (page - 1) * limit
+
+	// Guests don't get a history row
+	albums, total := []models.AlbumResponse{}, 0
+	if role, _ := middleware.GetRole(ctx); !service.PermissionsFor(role).IsGuest {
+		var err error
+		albums, total, err = h.albumService.GetRecentlyPlayedAlbums(ctx, userID, limit, offset)
+		if err != nil {
+			h.logger.Error("Failed to get recently played albums", "user_id", userID, "error", err)
+			sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get recently played albums"})
+			return
+		}
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.AlbumListResponse{
+		Albums: albums,
+		Pagination: models.Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		},
+	})
 }
 
-// GetAlbumByID returns album details with tracks
-// @Summary Get Album Details
+// GetAlbumByID returns album details with tracks and optional like status for authenticated users
+// @Summary Get Album Details (Optional Auth)
 // @Tags albums
 // @Produce json
 // @Param id path string true "Album ID"
+// @Param Authorization header string false "Bearer token for authenticated access (shows like status)"
 // @Success 200 {object} models.AlbumDetail
+// @Header 200 {string} Link "Preload hints for the cover and the first track's stream, when PRELOAD_HINTS is enabled"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 404 {object} map[string]string "Album not found"
 // @Failure 500 {object} map[string]string "Internal server error"
@@ -85,26 +335,38 @@ func (h *AlbumHandler) GetAlbumByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Get album ID from URL
-	albumID := strings.TrimPrefix(r.URL.Path, "/api/albums/")
+	albumID := chi.URLParam(r, "id")
 	if albumID == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "Album ID is required")
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Album ID is required"})
 		return
 	}
 
-	// Get album with tracks
-	albumDetail, err := h.albumService.GetAlbumWithTracks(ctx, albumID)
+	// Get user ID if authenticated (optional)
+	userID, _ := middleware.GetUserID(ctx)
+
+	// Get album with tracks and optional like status
+	albumDetail, err := h.albumService.GetAlbumWithTracks(ctx, albumID, userID, canViewDrafts(ctx))
 	if err != nil {
-		h.logger.Error("Failed to get album", "album_id", albumID, "error", err)
-		if strings.Contains(err.Error(), "not found") {
-			sendErrorResponse(w, http.StatusNotFound, "Album not found")
+		h.logger.Error("Failed to get album", "album_id", albumID, "user_id", userID, "error", err)
+		if errors.Is(err, repository.ErrNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeAlbumNotFound, Message: "Album not found"})
 			return
 		}
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to get album")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get album"})
 		return
 	}
 
 	h.logger.Info("Album retrieved successfully", "album_id", albumID, "tracks_count", len(albumDetail.Tracks))
 
+	if h.preloadHints {
+		if albumDetail.Album.CoverURL != "" {
+			w.Header().Add("Link", fmt.Sprintf("</api/albums/%s/cover>; rel=preload; as=image", albumDetail.Album.ID))
+		}
+		if len(albumDetail.Tracks) > 0 {
+			w.Header().Add("Link", fmt.Sprintf("</api/tracks/%s/stream>; rel=preload; as=audio", albumDetail.Tracks[0].ID))
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(albumDetail)
 }
@@ -123,22 +385,21 @@ func (h *AlbumHandler) GetAlbumInfo(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Get album ID from URL
-	albumID := strings.TrimPrefix(r.URL.Path, "/api/albums/")
-	albumID = strings.TrimSuffix(albumID, "/info")
+	albumID := chi.URLParam(r, "id")
 	if albumID == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "Album ID is required")
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Album ID is required"})
 		return
 	}
 
 	// Get album info
-	album, err := h.albumService.GetAlbumByID(ctx, albumID)
+	album, err := h.albumService.GetAlbumByID(ctx, albumID, canViewDrafts(ctx))
 	if err != nil {
 		h.logger.Error("Failed to get album info", "album_id", albumID, "error", err)
-		if strings.Contains(err.Error(), "not found") {
-			sendErrorResponse(w, http.StatusNotFound, "Album not found")
+		if errors.Is(err, repository.ErrNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeAlbumNotFound, Message: "Album not found"})
 			return
 		}
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to get album info")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get album info"})
 		return
 	}
 
@@ -148,12 +409,45 @@ func (h *AlbumHandler) GetAlbumInfo(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(album)
 }
 
+// ExportAlbumM3U returns the album as an extended M3U playlist for external players
+// @Summary Export Album as M3U
+// @Description Each entry has an #EXTINF line with the duration and "Artist - Title" followed by the absolute stream URL built from PUBLIC_BASE_URL
+// @Tags albums
+// @Produce audio/x-mpegurl
+// @Param id path string true "Album ID"
+// @Success 200 {file} binary "M3U playlist"
+// @Failure 404 {object} map[string]string "Album not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/albums/{id}/export.m3u [get]
+func (h *AlbumHandler) ExportAlbumM3U(w http.ResponseWriter, r *http.Request) {
+	albumID := chi.URLParam(r, "id")
+
+	album, playlist, err := h.albumService.ExportAlbumM3U(r.Context(), albumID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendServiceError(w, http.StatusNotFound, "Album not found", err)
+			return
+		}
+		h.logger.Error("Failed to export album playlist", "album_id", albumID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to export playlist"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/x-mpegurl")
+	w.Header().Set("Content-Disposition", contentDisposition("attachment", album.Artist+" - "+album.Title+".m3u"))
+	w.Header().Set("Content-Length", strconv.Itoa(len(playlist)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(playlist)
+}
+
 // GetAlbumCover returns the cover image for an album
 // @Summary Get Album Cover Image
 // @Tags albums
 // @Param id path string true "Album ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param size query string false "Thumbnail size from COVER_THUMBNAIL_SIZES, original when omitted" example(small)
 // @Success 200 {file} binary "Cover image"
-// @Failure 404 {object} map[string]string "Not found - album or cover does not exist"
+// @Failure 400 {object} map[string]string "Bad request - unknown size"
+// @Failure 404 {object} map[string]string "Not found - album or cover does not exist, or the cover is missing in storage (file_missing)"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/albums/{id}/cover [get]
 func (h *AlbumHandler) GetAlbumCover(w http.ResponseWriter, r *http.Request) {
@@ -162,38 +456,52 @@ func (h *AlbumHandler) GetAlbumCover(w http.ResponseWriter, r *http.Request) {
 	// Get album ID from URL parameter
 	albumID := chi.URLParam(r, "id")
 	if albumID == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "Album ID is required")
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Album ID is required"})
+		return
+	}
+
+	size, ok := coverSize(w, r, h.thumbnailService)
+	if !ok {
 		return
 	}
 
 	// Get album info
 	album, err := h.albumService.GetAlbumRaw(ctx, albumID)
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeAlbumNotFound, Message: "Album not found"})
+			return
+		}
 		h.logger.Error("Failed to get album", "album_id", albumID, "error", err)
-		sendErrorResponse(w, http.StatusNotFound, "Album not found")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get album"})
 		return
 	}
 
 	// Check if album has cover
 	if album.CoverImageKey == "" {
-		sendErrorResponse(w, http.StatusNotFound, "Album has no cover image")
+		sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeCoverNotFound, Message: "Album has no cover image"})
 		return
 	}
 
-	// Get image from MinIO through album service
-	object, err := h.albumService.GetCoverImage(ctx, album.CoverImageKey)
+	coverKey := resolveCoverKey(ctx, h.thumbnailService, h.logger, album.ID, album.CoverImageKey, size)
+
+	// Get object info for content type; a cover deleted from MinIO is a 404
+	info, err := h.albumService.GetCoverImageInfo(ctx, coverKey)
 	if err != nil {
-		h.logger.Error("Failed to get cover from MinIO", "album_id", albumID, "cover_key", album.CoverImageKey, "error", err)
-		sendErrorResponse(w, http.StatusNotFound, "Cover image not found")
-		return
+		if sendIfObjectMissing(w, h.logger, err, coverKey, "album_id", albumID) {
+			return
+		}
+		h.logger.Warn("Failed to get object info", "cover_key", coverKey, "error", err)
 	}
-	defer object.Close()
 
-	// Get object info for content type
-	info, err := h.albumService.GetCoverImageInfo(ctx, album.CoverImageKey)
+	// Get image from MinIO through album service
+	object, err := h.albumService.GetCoverImage(ctx, coverKey)
 	if err != nil {
-		h.logger.Warn("Failed to get object info", "cover_key", album.CoverImageKey, "error", err)
+		h.logger.Error("Failed to get cover from MinIO", "album_id", albumID, "cover_key", coverKey, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeCoverNotFound, Message: "Cover image not found"})
+		return
 	}
+	defer object.Close()
 
 	// Set content type
 	contentType := "image/jpeg" // default
@@ -201,21 +509,26 @@ func (h *AlbumHandler) GetAlbumCover(w http.ResponseWriter, r *http.Request) {
 		contentType = info.ContentType
 	} else {
 		// Try to detect from file extension
-		if strings.HasSuffix(strings.ToLower(album.CoverImageKey), ".png") {
+		if strings.HasSuffix(strings.ToLower(coverKey), ".png") {
 			contentType = "image/png"
-		} else if strings.HasSuffix(strings.ToLower(album.CoverImageKey), ".webp") {
+		} else if strings.HasSuffix(strings.ToLower(coverKey), ".webp") {
 			contentType = "image/webp"
 		}
 	}
 
-	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Cache-Control", "public, max-age=31536000") // Cache for 1 year
 
-	// Stream the file to the response
-	_, err = io.Copy(w, object)
-	if err != nil {
-		h.logger.Error("Failed to stream cover image", "album_id", albumID, "error", err)
-		// Can't send error response here as we already started writing the body
+	var etag string
+	var modTime time.Time
+	if info != nil {
+		etag = info.ETag
+		modTime = info.LastModified
+	}
+
+	// Covers are small, so buffer them to support range and conditional requests
+	if err := serveBufferedObject(w, r, object, coverKey, contentType, etag, modTime); err != nil {
+		h.logger.Error("Failed to serve cover image", "album_id", albumID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to read cover image"})
 		return
 	}
 
@@ -6,6 +6,8 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"koteyye_music_be/internal/models"
 	"koteyye_music_be/internal/service"
@@ -30,6 +32,7 @@ func NewAuthHandler(authService *service.AuthService, log *slog.Logger) *AuthHan
 // @Accept json
 // @Produce json
 // @Param input body models.RegisterRequest true "Registration data"
+// @Description Sends an email verification link. While REQUIRE_EMAIL_VERIFICATION is on, no tokens are returned until the email is verified.
 // @Success 201 {object} models.AuthResponse "User successfully registered"
 // @Failure 400 {object} map[string]string "Bad request - invalid input"
 // @Failure 409 {object} map[string]string "Conflict - user already exists"
@@ -41,6 +44,9 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		h.logger.Error("Failed to read request body", "error", err)
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
 		sendErrorResponse(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
@@ -58,16 +64,20 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		sendErrorResponse(w, http.StatusBadRequest, "Email is required")
 		return
 	}
-	if len(req.Password) < 6 {
-		sendErrorResponse(w, http.StatusBadRequest, "Password must be at least 6 characters")
+	if req.Password == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "Password is required")
 		return
 	}
 
 	// Call auth service
 	response, err := h.authService.Register(ctx, &req)
 	if err != nil {
-		if errors.Is(err, errors.New("user with this email already exists")) {
-			sendErrorResponse(w, http.StatusConflict, err.Error())
+		if errors.Is(err, service.ErrWeakPassword) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		if errors.Is(err, service.ErrUserExists) {
+			sendServiceError(w, http.StatusConflict, service.ErrUserExists.Error(), err)
 			return
 		}
 		h.logger.Error("Registration failed", "error", err)
@@ -87,6 +97,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} models.AuthResponse "User successfully logged in"
 // @Failure 400 {object} map[string]string "Bad request - invalid input"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid credentials"
+// @Failure 403 {object} map[string]string "Forbidden - email not verified"
 // @Router /api/auth/login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -95,6 +106,9 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		h.logger.Error("Failed to read request body", "error", err)
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
 		sendErrorResponse(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
@@ -120,8 +134,12 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Call auth service
 	response, err := h.authService.Login(ctx, &req)
 	if err != nil {
-		if errors.Is(err, errors.New("invalid email or password")) {
-			sendErrorResponse(w, http.StatusUnauthorized, err.Error())
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			sendServiceError(w, http.StatusUnauthorized, service.ErrInvalidCredentials.Error(), err)
+			return
+		}
+		if errors.Is(err, service.ErrEmailNotVerified) {
+			sendServiceError(w, http.StatusForbidden, service.ErrEmailNotVerified.Error(), err)
 			return
 		}
 		h.logger.Error("Login failed", "error", err)
@@ -156,24 +174,182 @@ func (h *AuthHandler) GuestLogin(w http.ResponseWriter, r *http.Request) {
 	sendJSONResponse(w, http.StatusOK, response)
 }
 
-// sendJSONResponse sends a JSON response
-func sendJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+// Refresh exchanges a refresh token for a new access token
+// @Summary Refresh Access Token
+// @Description Returns a new access token. The refresh token is rotated: use the returned refresh_token next time.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.TokenResponse "New access and refresh tokens"
+// @Failure 400 {object} map[string]string "Bad request - invalid input"
+// @Failure 401 {object} map[string]string "Invalid or expired refresh token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/auth/refresh [post]
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		logger.Log.Error("Failed to encode JSON response", "error", err)
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+		return
 	}
+	if req.RefreshToken == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "Refresh token is required")
+		return
+	}
+
+	response, err := h.authService.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			sendServiceError(w, http.StatusUnauthorized, service.ErrInvalidRefreshToken.Error(), err)
+			return
+		}
+		h.logger.Error("Token refresh failed", "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Token refresh failed")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, response)
 }
 
-// sendErrorResponse sends an error JSON response
-func sendErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+// Logout revokes a refresh token
+// @Summary Logout
+// @Description Revokes the given refresh token. Access tokens stay valid until they expire.
+// @Tags auth
+// @Accept json
+// @Param input body models.RefreshRequest true "Refresh token to revoke"
+// @Success 204 "Refresh token revoked"
+// @Failure 400 {object} map[string]string "Bad request - invalid input"
+// @Failure 401 {object} map[string]string "Invalid or expired refresh token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/auth/logout [post]
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	if req.RefreshToken == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "Refresh token is required")
+		return
+	}
+
+	if err := h.authService.Logout(ctx, req.RefreshToken); err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			sendServiceError(w, http.StatusUnauthorized, service.ErrInvalidRefreshToken.Error(), err)
+			return
+		}
+		h.logger.Error("Logout failed", "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Logout failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail confirms an email address with the token from the verification link
+// @Summary Verify Email
+// @Description Opened from the link in the verification email. Tokens are single-use and expire after EMAIL_VERIFICATION_TTL.
+// @Tags auth
+// @Produce json
+// @Param token query string true "Verification token from the email"
+// @Success 200 {object} map[string]string "Email verified"
+// @Failure 400 {object} map[string]string "Missing, invalid or expired token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/auth/verify [get]
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Token is required"})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(r.Context(), token); err != nil {
+		if errors.Is(err, service.ErrInvalidVerificationToken) {
+			sendServiceError(w, http.StatusBadRequest, service.ErrInvalidVerificationToken.Error(), err)
+			return
+		}
+		h.logger.Error("Email verification failed", "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Email verification failed")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, map[string]string{"message": "Email verified"})
+}
+
+// ResendVerification sends a new verification link
+// @Summary Resend Verification Email
+// @Description Always answers 202 so the response doesn't reveal whether an account exists; only unverified accounts with a password get an email. Earlier links stop working.
+// @Tags auth
+// @Accept json
+// @Param input body models.ResendVerificationRequest true "Account email"
+// @Success 202 "Accepted"
+// @Failure 400 {object} map[string]string "Bad request - invalid input"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/auth/verify/resend [post]
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req models.ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	if req.Email == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	if err := h.authService.ResendVerification(r.Context(), req.Email); err != nil {
+		h.logger.Error("Failed to resend verification email", "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to resend verification email")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ServerTime returns the server's current UTC time and the expiry of the presented token
+// @Summary Server Time
+// @Description Lets clients with skewed clocks schedule token refreshes by server time. Token fields are only set when a valid bearer token is sent; an invalid or missing token is not an error.
+// @Tags auth
+// @Produce json
+// @Param Authorization header string false "Bearer token whose expiry should be reported"
+// @Success 200 {object} models.ServerTimeResponse "Server time and optional token expiry"
+// @Router /api/time [get]
+func (h *AuthHandler) ServerTime(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	response := models.ServerTimeResponse{ServerTime: now}
+
+	if tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if expiresAt, err := h.authService.TokenExpiry(tokenString); err == nil {
+			expiresAt = expiresAt.UTC()
+			expiresIn := int64(expiresAt.Sub(now).Seconds())
+			response.TokenExpiresAt = &expiresAt
+			response.TokenExpiresIn = &expiresIn
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	sendJSONResponse(w, http.StatusOK, response)
+}
+
+// sendJSONResponse sends a JSON response
+func sendJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
-	response := map[string]interface{}{
-		"error": message,
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.Log.Error("Failed to encode JSON response", "error", err)
 	}
-
-	json.NewEncoder(w).Encode(response)
 }
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"koteyye_music_be/internal/middleware"
+)
+
+func TestOversizedJSONBodyIsRejected(t *testing.T) {
+	h := NewAuthHandler(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	srv := middleware.MaxBodyBytes(1024)(http.HandlerFunc(h.Register))
+
+	body := `{"email":"user@example.com","password":"` + strings.Repeat("a", 2048) + `"}`
+	for _, contentType := range []string{"application/json", "multipart/form-data; boundary=x"} {
+		t.Run(contentType, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/register", strings.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+			}
+			var resp struct {
+				Code string `json:"code"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Code != CodeRequestTooLarge {
+				t.Errorf("code = %q, want %q", resp.Code, CodeRequestTooLarge)
+			}
+		})
+	}
+}
+
+func TestRouteLimitRaisesGlobalLimit(t *testing.T) {
+	var read int
+	srv := middleware.MaxBodyBytes(16)(middleware.MaxBodyBytes(4096)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+		}
+		read = len(data)
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(strings.Repeat("a", 1024)))
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+
+	if read != 1024 {
+		t.Errorf("read %d bytes, want 1024", read)
+	}
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"koteyye_music_be/internal/service"
+)
+
+// coverSize reads the optional ?size= thumbnail parameter of cover endpoints.
+// For unknown sizes it writes a 400 response and returns false.
+func coverSize(w http.ResponseWriter, r *http.Request, thumbnails *service.ThumbnailService) (string, bool) {
+	size := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("size")))
+	if size != "" && !thumbnails.HasSize(size) {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: "Size must be one of: " + strings.Join(thumbnails.Sizes(), ", ")})
+		return "", false
+	}
+	return size, true
+}
+
+// resolveCoverKey returns the key of the requested cover thumbnail, or coverKey itself
+// when no size was requested or the thumbnail can't be produced
+func resolveCoverKey(ctx context.Context, thumbnails *service.ThumbnailService, log *slog.Logger, albumID, coverKey, size string) string {
+	if size == "" {
+		return coverKey
+	}
+
+	key, err := thumbnails.CoverThumbnail(ctx, albumID, coverKey, size)
+	if err != nil {
+		log.Warn("Serving original cover, thumbnail unavailable", "album_id", albumID, "size", size, "error", err)
+		return coverKey
+	}
+	return key
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	minioPkg "koteyye_music_be/pkg/minio"
+)
+
+// sendIfObjectMissing answers 404 and logs the orphaned reference when err
+// reports that a stored object is missing. It returns whether it responded.
+func sendIfObjectMissing(w http.ResponseWriter, log *slog.Logger, err error, key string, args ...any) bool {
+	if !errors.Is(err, minioPkg.ErrObjectNotFound) {
+		return false
+	}
+	log.Warn("Object referenced in database is missing from storage", append([]any{"key", key}, args...)...)
+	sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeFileMissing, Message: "File missing in storage"})
+	return true
+}
+
+// sanitizeFilename strips path separators and control characters from a download filename
+func sanitizeFilename(name string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\':
+			return '-'
+		case r == '"':
+			return '\''
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, name)
+
+	cleaned = strings.TrimSpace(cleaned)
+	cleaned = strings.Trim(cleaned, ".")
+	if cleaned == "" {
+		return "download"
+	}
+	return cleaned
+}
+
+// contentDisposition builds a Content-Disposition header value with an ASCII fallback
+// filename and an RFC 5987 encoded filename* for non-ASCII names
+func contentDisposition(disposition, filename string) string {
+	filename = sanitizeFilename(filename)
+
+	fallback := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII {
+			return '_'
+		}
+		return r
+	}, filename)
+
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, disposition, fallback, url.PathEscape(filename))
+}
+
+// serveBufferedObject buffers a small object in memory and serves it with http.ServeContent,
+// which handles Range, If-Modified-Since, If-Range and If-None-Match requests.
+// etag is the raw MinIO object ETag; it is quoted for the response header.
+// A request whose If-None-Match matches is answered with 304 before the
+// object is read, so revalidations don't download it from MinIO.
+func serveBufferedObject(w http.ResponseWriter, r *http.Request, object io.Reader, name, contentType, etag string, modTime time.Time) error {
+	if etag != "" {
+		etag = `"` + strings.Trim(etag, `"`) + `"`
+		w.Header().Set("ETag", etag)
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			if !modTime.IsZero() {
+				w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return fmt.Errorf("failed to read object: %w", err)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, name, modTime, bytes.NewReader(data))
+	return nil
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using the
+// weak comparison RFC 9110 prescribes for that header
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServeBufferedObjectRange(t *testing.T) {
+	cover := []byte("0123456789abcdef")
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/albums/1/cover", nil)
+	req.Header.Set("Range", "bytes=4-9")
+	rec := httptest.NewRecorder()
+
+	if err := serveBufferedObject(rec, req, bytes.NewReader(cover), "cover.jpg", "image/jpeg", "abc", modTime); err != nil {
+		t.Fatalf("serveBufferedObject: %v", err)
+	}
+
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusPartialContent)
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 4-9/16" {
+		t.Errorf("Content-Range = %q, want %q", got, "bytes 4-9/16")
+	}
+	if got := rec.Body.String(); got != "456789" {
+		t.Errorf("body = %q, want %q", got, "456789")
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/jpeg" {
+		t.Errorf("Content-Type = %q, want image/jpeg", got)
+	}
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"context"
+
+	"koteyye_music_be/internal/middleware"
+	"koteyye_music_be/internal/service"
+)
+
+// canViewDrafts reports whether the caller may see draft albums and tracks.
+// Only roles that manage albums can; anonymous requests carry no role.
+func canViewDrafts(ctx context.Context) bool {
+	role, _ := middleware.GetRole(ctx)
+	return service.PermissionsFor(role).CanManageAlbums
+}
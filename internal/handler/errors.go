@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"koteyye_music_be/internal/realtime"
+	"koteyye_music_be/internal/repository"
+	"koteyye_music_be/internal/service"
+	"koteyye_music_be/pkg/filetype"
+	"koteyye_music_be/pkg/logger"
+)
+
+// Error response formats
+const (
+	ErrorFormatFlat       = "flat"
+	ErrorFormatStructured = "structured"
+)
+
+// Machine-readable error codes returned in the "code" field. They are part of
+// the API contract: clients switch on them, so existing values must not change.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeConflict            = "conflict"
+	CodeRequestTooLarge     = "request_too_large"
+	CodeInternal            = "internal_error"
+	CodeTrackNotFound       = "track_not_found"
+	CodeAlbumNotFound       = "album_not_found"
+	CodeUserNotFound        = "user_not_found"
+	CodeUserExists          = "user_exists"
+	CodeAlbumExists         = "album_exists"
+	CodeInvalidCredentials  = "invalid_credentials"
+	CodeInvalidFileType     = "invalid_file_type"
+	CodeInvalidRefresh      = "invalid_refresh_token"
+	CodeInvalidVolume       = "invalid_volume"
+	CodeInvalidPosition     = "invalid_position"
+	CodeInvalidRole         = "invalid_role"
+	CodeInvalidName         = "invalid_name"
+	CodeLastAdmin           = "last_admin"
+	CodeTooManySubscribers  = "too_many_subscribers"
+	CodeShuttingDown        = "shutting_down"
+	CodeInvalidArchive      = "invalid_archive"
+	CodeInvalidGenre        = "invalid_genre"
+	CodeFileTooLarge        = "file_too_large"
+	CodeInvalidID           = "invalid_id"
+	CodeInvalidJSON         = "invalid_json"
+	CodeInvalidParameter    = "invalid_parameter"
+	CodeMissingField        = "missing_field"
+	CodeCoverNotFound       = "cover_not_found"
+	CodeFileMissing         = "file_missing"
+	CodeLyricsNotFound      = "lyrics_not_found"
+	CodeInvalidLyrics       = "invalid_lyrics"
+	CodeInvalidTrackOrder   = "invalid_track_order"
+	CodeDuplicateTrack      = "duplicate_track"
+	CodeIdempotencyConflict = "idempotency_conflict"
+	CodeInvalidVerification = "invalid_verification_token"
+	CodeEmailNotVerified    = "email_not_verified"
+	CodeWeakPassword        = "weak_password"
+	CodeTimeout             = "timeout"
+)
+
+// APIError is an error response with a stable machine-readable code
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// errorFormat is set once at startup via SetErrorFormat
+var errorFormat = ErrorFormatFlat
+
+// SetErrorFormat selects the error response shape for all handlers
+func SetErrorFormat(format string) {
+	if format == ErrorFormatStructured {
+		errorFormat = ErrorFormatStructured
+		return
+	}
+	errorFormat = ErrorFormatFlat
+}
+
+// sentinelCodes maps sentinel errors to their error codes, checked in order
+var sentinelCodes = []struct {
+	err  error
+	code string
+}{
+	{repository.ErrTrackNotFound, CodeTrackNotFound},
+	{repository.ErrLyricsNotFound, CodeLyricsNotFound},
+	{repository.ErrUserNotFound, CodeUserNotFound},
+	{service.ErrAlbumNotFound, CodeAlbumNotFound},
+	{service.ErrUserExists, CodeUserExists},
+	{service.ErrAlbumExists, CodeAlbumExists},
+	{service.ErrInvalidCredentials, CodeInvalidCredentials},
+	{service.ErrInvalidRefreshToken, CodeInvalidRefresh},
+	{service.ErrInvalidVerificationToken, CodeInvalidVerification},
+	{service.ErrEmailNotVerified, CodeEmailNotVerified},
+	{service.ErrInvalidVolume, CodeInvalidVolume},
+	{service.ErrInvalidPosition, CodeInvalidPosition},
+	{service.ErrInvalidRole, CodeInvalidRole},
+	{service.ErrInvalidName, CodeInvalidName},
+	{service.ErrWeakPassword, CodeWeakPassword},
+	{service.ErrLastAdmin, CodeLastAdmin},
+	{service.ErrPasswordConfirmation, CodeInvalidCredentials},
+	{realtime.ErrTooManySubscribers, CodeTooManySubscribers},
+	{service.ErrInvalidArchive, CodeInvalidArchive},
+	{service.ErrInvalidGenre, CodeInvalidGenre},
+	{service.ErrTrackOrderMismatch, CodeInvalidTrackOrder},
+	{service.ErrDuplicateTrack, CodeDuplicateTrack},
+	{service.ErrInvalidIdempotencyKey, CodeInvalidParameter},
+	{service.ErrIdempotencyKeyInProgress, CodeIdempotencyConflict},
+	{service.ErrIdempotencyKeyReused, CodeIdempotencyConflict},
+	{service.ErrInvalidLyrics, CodeInvalidLyrics},
+	{service.ErrInvalidFeaturedOrder, CodeInvalidParameter},
+	{service.ErrInvalidPeriod, CodeInvalidParameter},
+	{service.ErrInvalidTrackIDs, CodeInvalidID},
+	{service.ErrInvalidStatus, CodeInvalidParameter},
+	{service.ErrShuttingDown, CodeShuttingDown},
+	{filetype.ErrMismatch, CodeInvalidFileType},
+}
+
+// statusCode returns the generic error code for an HTTP status
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodeRequestTooLarge
+	default:
+		return CodeInternal
+	}
+}
+
+// errorCode resolves the code for err, falling back to the status code
+func errorCode(err error, status int) string {
+	if err != nil {
+		for _, sc := range sentinelCodes {
+			if errors.Is(err, sc.err) {
+				return sc.code
+			}
+		}
+	}
+	return statusCode(status)
+}
+
+// sendAPIError sends an error JSON response with the error's status and code
+func sendAPIError(w http.ResponseWriter, apiErr APIError) {
+	writeError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+}
+
+// sendErrorResponse sends an error JSON response with a generic code derived from
+// the status. Prefer sendAPIError with a specific code.
+func sendErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	writeError(w, statusCode, errorCode(nil, statusCode), message)
+}
+
+// sendServiceError sends an error JSON response with a code derived from err
+func sendServiceError(w http.ResponseWriter, statusCode int, message string, err error) {
+	writeError(w, statusCode, errorCode(err, statusCode), message)
+}
+
+// sendIfBodyTooLarge answers 413 and reports true when reading the request
+// body failed because it exceeded the body size limit
+func sendIfBodyTooLarge(w http.ResponseWriter, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return false
+	}
+	sendAPIError(w, APIError{Status: http.StatusRequestEntityTooLarge, Code: CodeRequestTooLarge, Message: "Request body too large"})
+	return true
+}
+
+// NotFound answers requests for unknown routes
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Warn("Route not found", "method", r.Method, "path", r.URL.Path, "host", r.Host)
+	sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeNotFound, Message: "Route not found"})
+}
+
+// RequestTimedOut answers a request whose handler ran past its timeout
+func RequestTimedOut(w http.ResponseWriter, r *http.Request) {
+	sendAPIError(w, APIError{Status: http.StatusGatewayTimeout, Code: CodeTimeout, Message: "Request timed out"})
+}
+
+// MethodNotAllowed returns the handler for requests to a route of routes with a
+// method it doesn't serve. chi only sets the Allow header in its default
+// handler, so it's rebuilt here by matching the path against each method.
+func MethodNotAllowed(routes chi.Routes) http.HandlerFunc {
+	methods := []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, method := range methods {
+			if routes.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				w.Header().Add("Allow", method)
+			}
+		}
+		sendAPIError(w, APIError{Status: http.StatusMethodNotAllowed, Code: CodeMethodNotAllowed, Message: "Method not allowed"})
+	}
+}
+
+// writeError encodes the error body in the configured format
+func writeError(w http.ResponseWriter, statusCode int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	var response map[string]interface{}
+	if errorFormat == ErrorFormatStructured {
+		response = map[string]interface{}{
+			"error": map[string]string{
+				"code":    code,
+				"message": message,
+			},
+		}
+	} else {
+		response = map[string]interface{}{
+			"error": message,
+			"code":  code,
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Log.Error("Failed to encode error response", "error", err)
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/service"
+)
+
+type GenreHandler struct {
+	genreService *service.GenreService
+	logger       *slog.Logger
+}
+
+func NewGenreHandler(genreService *service.GenreService, log *slog.Logger) *GenreHandler {
+	return &GenreHandler{
+		genreService: genreService,
+		logger:       log,
+	}
+}
+
+// ListGenres returns all allowed genres with album and track counts
+// @Summary List Genres
+// @Description Every allowed genre with its album and track counts, including genres without content. Counts may be up to a minute old.
+// @Tags genres
+// @Produce json
+// @Success 200 {object} models.GenreListResponse "Genres with counts"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/genres [get]
+func (h *GenreHandler) ListGenres(w http.ResponseWriter, r *http.Request) {
+	genres, err := h.genreService.ListGenres(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list genres", "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to list genres")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.GenreListResponse{Genres: genres})
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"koteyye_music_be/internal/models"
+	"koteyye_music_be/pkg/database"
+	minioPkg "koteyye_music_be/pkg/minio"
+)
+
+// readinessTimeout bounds each dependency check so a hung dependency fails the probe quickly
+const readinessTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	db          *database.DB
+	minioClient *minioPkg.Client
+	logger      *slog.Logger
+}
+
+func NewHealthHandler(db *database.DB, minioClient *minioPkg.Client, log *slog.Logger) *HealthHandler {
+	return &HealthHandler{
+		db:          db,
+		minioClient: minioClient,
+		logger:      log,
+	}
+}
+
+// Ready reports whether the database and MinIO are reachable
+// @Summary Readiness Check
+// @Description Pings PostgreSQL and checks the MinIO buckets. Use /health for a cheap liveness check.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.ReadinessResponse "All dependencies are reachable"
+// @Failure 503 {object} models.ReadinessResponse "At least one dependency failed"
+// @Router /health/ready [get]
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]func(ctx context.Context) error{
+		"database": func(ctx context.Context) error {
+			return h.db.Pool.Ping(ctx)
+		},
+		"minio": func(ctx context.Context) error {
+			for _, bucket := range h.minioClient.Buckets().Unique() {
+				exists, err := h.minioClient.BucketExists(ctx, bucket)
+				if err != nil {
+					return err
+				}
+				if !exists {
+					return fmt.Errorf("bucket %q does not exist", bucket)
+				}
+			}
+			return nil
+		},
+	}
+
+	response := models.ReadinessResponse{
+		Status: models.HealthStatusOK,
+		Checks: make(map[string]string, len(checks)),
+	}
+
+	// Run checks concurrently so the probe takes at most one timeout
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+			defer cancel()
+
+			status := models.HealthStatusOK
+			if err := check(ctx); err != nil {
+				h.logger.Warn("Readiness check failed", "dependency", name, "error", err)
+				status = models.HealthStatusFail
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[name] = status
+			if status != models.HealthStatusOK {
+				response.Status = models.HealthStatusFail
+			}
+		}()
+	}
+	wg.Wait()
+
+	statusCode := http.StatusOK
+	if response.Status != models.HealthStatusOK {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	sendJSONResponse(w, statusCode, response)
+}
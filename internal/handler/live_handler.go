@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"koteyye_music_be/internal/realtime"
+	"koteyye_music_be/internal/repository"
+	"koteyye_music_be/internal/service"
+)
+
+const (
+	// liveWriteWait bounds a single write to a live client
+	liveWriteWait = 10 * time.Second
+	// livePongWait is how long a live client may stay silent before it is dropped
+	livePongWait = 60 * time.Second
+	// livePingInterval must be shorter than livePongWait
+	livePingInterval = livePongWait * 9 / 10
+)
+
+// LiveHandler serves WebSocket streams of track counters
+type LiveHandler struct {
+	trackService *service.TrackService
+	upgrader     websocket.Upgrader
+	logger       *slog.Logger
+}
+
+// NewLiveHandler creates a LiveHandler. allowedOrigins restricts browser
+// origins the same way CORS_ALLOWED_ORIGINS does; empty allows any origin.
+func NewLiveHandler(trackService *service.TrackService, allowedOrigins []string, log *slog.Logger) *LiveHandler {
+	return &LiveHandler{
+		trackService: trackService,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return len(allowedOrigins) == 0 || origin == "" || slices.Contains(allowedOrigins, origin)
+			},
+		},
+		logger: log,
+	}
+}
+
+// TrackStats streams a track's play and like counts over a WebSocket
+// @Summary Live Track Stats
+// @Description Upgrades to a WebSocket that sends {"plays_count", "likes_count"} right away and again whenever the track is played or liked. Only the latest counts are delivered to slow clients. Updates come from the API instance the client is connected to.
+// @Tags tracks
+// @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Success 101 {object} models.TrackStats "Switching protocols; messages carry track stats"
+// @Failure 400 {object} map[string]string "Bad request - invalid track ID or not a WebSocket request"
+// @Failure 404 {object} map[string]string "Track not found"
+// @Failure 503 {object} map[string]string "Too many live subscribers for this track"
+// @Router /api/tracks/{id}/live [get]
+func (h *LiveHandler) TrackStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	trackID := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(trackID); err != nil {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidID, Message: "Invalid track ID"})
+		return
+	}
+
+	stats, err := h.trackService.GetTrackStats(ctx, trackID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeTrackNotFound, Message: "Track not found"})
+			return
+		}
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get track stats"})
+		return
+	}
+
+	// Subscribe before upgrading so the limit is reported as a plain HTTP error
+	sub, err := h.trackService.SubscribeStats(trackID)
+	if err != nil {
+		if errors.Is(err, realtime.ErrTooManySubscribers) {
+			sendServiceError(w, http.StatusServiceUnavailable, "Too many live subscribers for this track", err)
+			return
+		}
+		h.logger.Error("Failed to subscribe to track stats", "track_id", trackID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to subscribe to track stats"})
+		return
+	}
+	defer sub.Close()
+
+	// Upgrade replies with an HTTP error itself when the handshake is invalid
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logger.Debug("WebSocket upgrade failed", "track_id", trackID, "error", err)
+		return
+	}
+	defer conn.Close()
+
+	// Clients send nothing but control frames; reading detects disconnects
+	// and keeps the deadline moving on every pong
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(livePongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(livePongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+
+	send := func(v any) error {
+		conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+		return conn.WriteJSON(v)
+	}
+
+	if err := send(stats); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-disconnected:
+			return
+		case update, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := send(update); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
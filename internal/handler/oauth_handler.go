@@ -1,14 +1,22 @@
 package handler
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"time"
 
 	"koteyye_music_be/internal/middleware"
 	"koteyye_music_be/internal/service"
+
+	"github.com/go-chi/chi/v5"
 )
 
+// oauthStateCookie holds the nonce of the OAuth flow this browser started;
+// the callback only completes a flow whose state carries the same nonce
+const oauthStateCookie = "oauth_state"
+
 type OAuthHandler struct {
 	oauthService *service.OAuthService
 	logger       *slog.Logger
@@ -50,7 +58,7 @@ func (h *OAuthHandler) OAuthLogin(w http.ResponseWriter, r *http.Request) {
 		h.logger.Info("Guest attempting OAuth login", "guest_user_id", userID)
 	}
 
-	authURL, err := h.oauthService.GetAuthURL(provider, guestUserID)
+	authURL, nonce, err := h.oauthService.GetAuthURL(provider, guestUserID)
 	if err != nil {
 		h.logger.Error("Failed to get OAuth URL", "provider", provider, "error", err)
 		// Return the actual error message to help with configuration
@@ -58,18 +66,66 @@ func (h *OAuthHandler) OAuthLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setOAuthStateCookie(w, r, nonce)
 	// Redirect to OAuth provider
 	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
 }
 
+// LinkAccount starts linking an OAuth provider account to the signed-in user
+// @Summary Link OAuth account
+// @Description Returns the provider's authorization URL for the frontend to navigate to, and sets the oauth_state cookie the callback requires, so only this browser can complete the link. A cross-origin frontend must call it with credentials (fetch credentials: 'include') from an origin listed in CORS_ALLOWED_ORIGINS, or the browser drops the cookie. The callback links the provider account to the current user and redirects to the frontend with linked={provider}
+// @Tags oauth
+// @Security BearerAuth
+// @Produce json
+// @Param provider path string true "OAuth Provider" Enums(google, yandex) Example(yandex)
+// @Success 200 {object} map[string]string "Authorization URL in the url field"
+// @Failure 400 {object} map[string]string "Bad request - unsupported provider"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Guests cannot link accounts"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/users/me/link/{provider} [get]
+func (h *OAuthHandler) LinkAccount(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+
+	if provider != "google" && provider != "yandex" {
+		h.logger.Error("Unsupported OAuth provider", "provider", provider)
+		http.Error(w, "Unsupported OAuth provider", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	if role, _ := middleware.GetRole(r.Context()); role == "guest" {
+		http.Error(w, service.ErrGuestCannotLinkOAuth.Error(), http.StatusForbidden)
+		return
+	}
+
+	authURL, nonce, err := h.oauthService.GetLinkURL(provider, userID)
+	if err != nil {
+		h.logger.Error("Failed to get OAuth link URL", "provider", provider, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The cookie ties the link to this browser: the URL alone, handed to
+	// someone else, can't attach their provider account to this user
+	setOAuthStateCookie(w, r, nonce)
+	sendJSONResponse(w, http.StatusOK, map[string]string{"url": authURL})
+}
+
 // OAuthCallback handles OAuth callback from provider
 // @Summary OAuth Callback
 // @Tags oauth
 // @Param provider path string true "OAuth Provider" Enums(google, yandex) Example(google)
 // @Param code query string true "Authorization Code" Example(4/0AX4XfWhi_abc123xyz)
-// @Param state query string false "State Parameter" Example(random_state_string)
-// @Success 307 "Temporary Redirect to frontend with JWT token in query"
-// @Failure 400 {object} map[string]string "Bad request - missing or invalid code"
+// @Param state query string true "State issued by the login endpoint" Example(random_state_string)
+// @Success 307 "Temporary Redirect to frontend with JWT token in query, or with linked={provider} after linking"
+// @Failure 400 {object} map[string]string "Bad request - missing code, or invalid or expired state, or state started in another browser"
+// @Failure 403 {object} map[string]string "Guests cannot link accounts"
+// @Failure 409 {object} map[string]string "Provider account is linked to another user"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/auth/{provider}/callback [get]
 func (h *OAuthHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
@@ -91,8 +147,27 @@ func (h *OAuthHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The state is single-use for this browser whatever the outcome
+	var browserNonce string
+	if cookie, err := r.Cookie(oauthStateCookie); err == nil {
+		browserNonce = cookie.Value
+	}
+	setOAuthStateCookie(w, r, "")
+
 	// Process OAuth callback
-	token, user, err := h.oauthService.HandleCallback(r.Context(), provider, code, state)
+	result, err := h.oauthService.HandleCallback(r.Context(), provider, code, state, browserNonce)
+	if errors.Is(err, service.ErrInvalidOAuthState) {
+		http.Error(w, "Invalid or expired OAuth state, please start the login again", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrOAuthAccountLinked) {
+		http.Error(w, "This account is already linked to another user", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, service.ErrGuestCannotLinkOAuth) {
+		http.Error(w, service.ErrGuestCannotLinkOAuth.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to process OAuth callback", "provider", provider, "error", err)
 		http.Error(w, "Failed to authenticate", http.StatusInternalServerError)
@@ -107,16 +182,40 @@ func (h *OAuthHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Add token to query parameters
+	// Add token to query parameters; a linked user keeps the token they have
 	query := redirectURL.Query()
-	query.Set("token", token)
+	if result.Linked {
+		query.Set("linked", provider)
+	} else {
+		query.Set("token", result.Token)
+	}
 	query.Set("provider", provider)
 	redirectURL.RawQuery = query.Encode()
 
-	h.logger.Info("Redirecting to frontend with token",
-		"user_id", user.ID,
-		"provider", provider)
+	h.logger.Info("Redirecting to frontend after OAuth callback",
+		"user_id", result.User.ID,
+		"provider", provider,
+		"linked", result.Linked)
 
 	// Redirect to frontend
 	http.Redirect(w, r, redirectURL.String(), http.StatusTemporaryRedirect)
 }
+
+// setOAuthStateCookie stores nonce for the OAuth callback, or clears the
+// cookie when nonce is empty. SameSite=Lax still sends it on the provider's
+// top-level redirect back to the callback.
+func setOAuthStateCookie(w http.ResponseWriter, r *http.Request, nonce string) {
+	maxAge := int(service.OAuthStateTTL / time.Second)
+	if nonce == "" {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    nonce,
+		Path:     "/api/auth",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"koteyye_music_be/internal/models"
+)
+
+// parseGenreFilter reads the optional ?genre= filter and normalizes it. An unknown
+// genre would silently match nothing, so it gets a 400 listing the valid genres
+// and false is returned.
+func parseGenreFilter(w http.ResponseWriter, r *http.Request) (string, bool) {
+	raw := strings.TrimSpace(r.URL.Query().Get("genre"))
+	if raw == "" {
+		return "", true
+	}
+
+	genre, ok := models.NormalizeGenre(raw)
+	if !ok {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidGenre, Message: fmt.Sprintf("Invalid genre %q. Allowed genres: %s", raw, strings.Join(models.AllowedGenres, ", "))})
+		return "", false
+	}
+	return genre, true
+}
+
+// parseCreatedRange reads the optional ?created_after= and ?created_before=
+// RFC 3339 bounds of a creation time filter; either may be omitted for an
+// open-ended range. Malformed or reversed bounds get a 400 and false is returned.
+func parseCreatedRange(w http.ResponseWriter, r *http.Request) (after, before *time.Time, ok bool) {
+	for _, bound := range []struct {
+		param string
+		dst   **time.Time
+	}{
+		{"created_after", &after},
+		{"created_before", &before},
+	} {
+		raw := strings.TrimSpace(r.URL.Query().Get(bound.param))
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: fmt.Sprintf("%s must be an RFC 3339 timestamp such as 2024-01-15T10:30:00Z", bound.param)})
+			return nil, nil, false
+		}
+		// created_at columns hold UTC without a zone
+		t = t.UTC()
+		*bound.dst = &t
+	}
+
+	if after != nil && before != nil && after.After(*before) {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: "created_after must not be later than created_before"})
+		return nil, nil, false
+	}
+	return after, before, true
+}
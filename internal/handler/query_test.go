@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListRejectsUnknownGenre(t *testing.T) {
+	lists := map[string]http.HandlerFunc{
+		"tracks": NewTrackHandler(nil, nil, UploadLimits{}, discardLogger).ListTracks,
+		"albums": NewAlbumHandler(nil, nil, false, discardLogger).GetAlbums,
+	}
+	for name, list := range lists {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			list(rec, httptest.NewRequest(http.MethodGet, "/api/"+name+"?genre=rokc", nil))
+			assertErrorCode(t, rec, http.StatusBadRequest, CodeInvalidGenre)
+		})
+	}
+}
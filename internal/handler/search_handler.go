@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"koteyye_music_be/internal/middleware"
+	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/service"
+)
+
+type SearchHandler struct {
+	searchService *service.SearchService
+	logger        *slog.Logger
+}
+
+func NewSearchHandler(searchService *service.SearchService, log *slog.Logger) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+		logger:        log,
+	}
+}
+
+// Search finds tracks and albums by title or artist
+// @Summary Search Tracks and Albums (Optional Auth)
+// @Description Case-insensitive substring search on titles and artists. Exact and prefix title matches rank first.
+// @Tags search
+// @Produce json
+// @Param q query string true "Search query" example(queen)
+// @Param type query string false "Restrict results to one kind" Enums(all, tracks, albums) default(all)
+// @Param limit query int false "Maximum results of each kind" default(10) minimum(1) maximum(50)
+// @Param facets query bool false "Also count all matches by genre and artist (top 10 values of each)" default(false)
+// @Param Authorization header string false "Bearer token for authenticated access (shows like status)"
+// @Success 200 {object} models.SearchResponse "Matching tracks and albums"
+// @Failure 400 {object} map[string]string "Bad request - missing query, invalid type or facets"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/search [get]
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		sendErrorResponse(w, http.StatusBadRequest, "Search query is required")
+		return
+	}
+	if utf8.RuneCountInString(query) > models.MaxSearchQueryLength {
+		sendErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Search query must be at most %d characters", models.MaxSearchQueryLength))
+		return
+	}
+
+	searchType := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("type")))
+	switch searchType {
+	case "":
+		searchType = models.SearchTypeAll
+	case models.SearchTypeAll, models.SearchTypeTracks, models.SearchTypeAlbums:
+	default:
+		sendErrorResponse(w, http.StatusBadRequest, "Type must be one of: all, tracks, albums")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	facets := false
+	if facetsParam := strings.TrimSpace(r.URL.Query().Get("facets")); facetsParam != "" {
+		parsed, err := strconv.ParseBool(facetsParam)
+		if err != nil {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: "facets must be true or false"})
+			return
+		}
+		facets = parsed
+	}
+
+	// Get user ID from context (optional)
+	userID, _ := middleware.GetUserID(ctx)
+
+	results, err := h.searchService.Search(ctx, query, searchType, limit, userID, facets)
+	if err != nil {
+		h.logger.Error("Search failed", "query", query, "type", searchType, "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Search failed")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, results)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"koteyye_music_be/internal/middleware"
+)
+
+// waitForContext blocks until the request context ends, or returns after wait
+func waitForContext(wait time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(wait):
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestTimeoutRespondsWithJSONError(t *testing.T) {
+	srv := middleware.Timeout(10*time.Millisecond, RequestTimedOut)(waitForContext(time.Second))
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/albums", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+	var resp struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Code != CodeTimeout {
+		t.Errorf("code = %q, want %q", resp.Code, CodeTimeout)
+	}
+}
+
+func TestRouteTimeoutReplacesGlobalTimeout(t *testing.T) {
+	global := middleware.Timeout(10*time.Millisecond, RequestTimedOut)
+
+	t.Run("lifted", func(t *testing.T) {
+		srv := global(middleware.RouteTimeout(0)(waitForContext(50 * time.Millisecond)))
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tracks/1/stream", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("own timeout", func(t *testing.T) {
+		srv := global(middleware.RouteTimeout(30 * time.Millisecond)(waitForContext(time.Second)))
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/albums/1/export", nil))
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+		}
+		if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+			t.Errorf("timed out after %s, before the route timeout", elapsed)
+		}
+	})
+}
@@ -1,31 +1,41 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"koteyye_music_be/internal/middleware"
+	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/repository"
 	"koteyye_music_be/internal/service"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type TrackHandler struct {
-	trackService *service.TrackService
-	logger       *slog.Logger
+	trackService     *service.TrackService
+	thumbnailService *service.ThumbnailService
+	uploadLimits     UploadLimits
+	logger           *slog.Logger
 }
 
-func NewTrackHandler(trackService *service.TrackService, log *slog.Logger) *TrackHandler {
+func NewTrackHandler(trackService *service.TrackService, thumbnailService *service.ThumbnailService, uploadLimits UploadLimits, log *slog.Logger) *TrackHandler {
 	return &TrackHandler{
-		trackService: trackService,
-		logger:       log,
+		trackService:     trackService,
+		thumbnailService: thumbnailService,
+		uploadLimits:     uploadLimits,
+		logger:           log,
 	}
 }
 
@@ -43,6 +53,7 @@ func NewTrackHandler(trackService *service.TrackService, log *slog.Logger) *Trac
 // @Success 201 {object} models.Track "Track successfully uploaded"
 // @Failure 400 {object} map[string]string "Bad request - invalid input"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 413 {object} map[string]string "Audio or cover file too large"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/admin/tracks/upload [post]
 func (h *TrackHandler) UploadTrack(w http.ResponseWriter, r *http.Request) {
@@ -51,14 +62,12 @@ func (h *TrackHandler) UploadTrack(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
 	userID, ok := middleware.GetUserID(ctx)
 	if !ok {
-		sendErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		sendAPIError(w, APIError{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Unauthorized"})
 		return
 	}
 
-	// Limit upload size to 100MB
-	if err := r.ParseMultipartForm(100 << 20); err != nil {
-		h.logger.Error("Failed to parse multipart form", "error", err)
-		sendErrorResponse(w, http.StatusBadRequest, "Failed to parse form data")
+	// Limit upload size to the audio and cover limits combined
+	if !parseUploadForm(w, r, h.uploadLimits.MaxAudioSize+h.uploadLimits.MaxCoverSize, "Upload is too large") {
 		return
 	}
 
@@ -69,7 +78,7 @@ func (h *TrackHandler) UploadTrack(w http.ResponseWriter, r *http.Request) {
 
 	// Validate required fields
 	if title == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "Title is required")
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Title is required"})
 		return
 	}
 
@@ -77,7 +86,7 @@ func (h *TrackHandler) UploadTrack(w http.ResponseWriter, r *http.Request) {
 	audioFile, _, err := r.FormFile("audio")
 	if err != nil {
 		h.logger.Error("Failed to get audio file", "error", err)
-		sendErrorResponse(w, http.StatusBadRequest, "Audio file is required")
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Audio file is required"})
 		return
 	}
 	defer audioFile.Close()
@@ -97,6 +106,11 @@ func (h *TrackHandler) UploadTrack(w http.ResponseWriter, r *http.Request) {
 		imageHeader = r.MultipartForm.File["cover"][0]
 	}
 
+	if !checkFileSize(w, audioHeader, h.uploadLimits.MaxAudioSize, h.uploadLimits.audioTooLargeMessage()) ||
+		!checkFileSize(w, imageHeader, h.uploadLimits.MaxCoverSize, h.uploadLimits.coverTooLargeMessage()) {
+		return
+	}
+
 	// Call track service
 	h.logger.Info("Starting track upload",
 		"user_id", userID,
@@ -114,61 +128,111 @@ func (h *TrackHandler) UploadTrack(w http.ResponseWriter, r *http.Request) {
 	track, err := h.trackService.UploadTrack(ctx, userID, title, artist, album, audioHeader, imageHeader)
 	if err != nil {
 		h.logger.Error("Failed to upload track", "error", err, "details", err.Error())
-		sendErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Failed to upload track: %v", err))
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: fmt.Sprintf("Failed to upload track: %v", err)})
 		return
 	}
 
 	sendJSONResponse(w, http.StatusCreated, track)
 }
 
+// streamQuality reads the ?quality= parameter, defaulting to high. It writes a 400
+// response and returns false for a quality that isn't configured.
+func streamQuality(w http.ResponseWriter, r *http.Request, tracks *service.TrackService) (string, bool) {
+	quality := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("quality")))
+	if quality == "" {
+		return service.QualityHigh, true
+	}
+	if !tracks.HasQuality(quality) {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: "Quality must be one of: " + strings.Join(tracks.Qualities(), ", ")})
+		return "", false
+	}
+	return quality, true
+}
+
 // StreamTrack handles audio streaming with Range Request support
 // @Summary Stream Track Audio (Public Access)
+// @Description Tracks without the requested rendition are streamed in high quality.
 // @Tags tracks
 // @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param quality query string false "high or a rendition from AUDIO_RENDITIONS" default(high) Example(low)
+// @Param download query bool false "Serve as an attachment named \"Artist - Title.mp3\" instead of inline" default(false)
 // @Success 200 {file} binary "Audio file stream"
-// @Failure 404 {object} map[string]string "Not found - track does not exist"
+// @Failure 400 {object} map[string]string "Bad request - unknown quality or invalid download flag"
+// @Failure 404 {object} map[string]string "Not found - track does not exist or its audio is missing in storage (file_missing)"
 // @Router /api/tracks/{id}/stream [get]
 func (h *TrackHandler) StreamTrack(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Get track ID from URL parameter
-	trackID := r.URL.Path[len("/api/tracks/"):]
-	if idx := len(trackID) - len("/stream"); idx > 0 {
-		trackID = trackID[:idx]
+	trackID := chi.URLParam(r, "id")
+
+	quality, ok := streamQuality(w, r, h.trackService)
+	if !ok {
+		return
 	}
 
-	// Get track information
-	track, err := h.trackService.GetTrack(ctx, trackID)
+	// The default inline mode suits <audio>; download=1 makes browsers save the file
+	download := false
+	if downloadParam := strings.TrimSpace(r.URL.Query().Get("download")); downloadParam != "" {
+		parsed, err := strconv.ParseBool(downloadParam)
+		if err != nil {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: "download must be true or false"})
+			return
+		}
+		download = parsed
+	}
+
+	// Get track information; drafts are only streamed to admins
+	track, err := h.trackService.GetVisibleTrack(ctx, trackID, canViewDrafts(ctx))
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeTrackNotFound, Message: "Track not found"})
+			return
+		}
 		h.logger.Error("Failed to get track", "track_id", trackID, "error", err)
-		sendErrorResponse(w, http.StatusNotFound, "Track not found")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get track"})
 		return
 	}
 
-	// Get object from MinIO through track service
-	object, err := h.trackService.GetAudioFile(ctx, track.AudioFileKey)
+	disposition, filename := "inline", track.Title+".mp3"
+	if download {
+		disposition = "attachment"
+		if filename, err = h.trackService.DownloadFilename(ctx, track); err != nil {
+			h.logger.Error("Failed to build download filename", "track_id", trackID, "error", err)
+			sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get track"})
+			return
+		}
+	}
+
+	// Get object info for size and modification time; this also catches
+	// audio deleted from MinIO before it is read
+	audioKey := h.trackService.AudioKey(track, quality)
+	info, err := h.trackService.GetAudioFileInfo(ctx, audioKey)
 	if err != nil {
-		h.logger.Error("Failed to get object from MinIO", "track_id", trackID, "error", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to get audio file")
+		if sendIfObjectMissing(w, h.logger, err, audioKey, "track_id", trackID) {
+			return
+		}
+		h.logger.Error("Failed to get object info", "track_id", trackID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get audio info"})
 		return
 	}
-	defer object.Close()
 
-	// Get object info for size and modification time
-	info, err := h.trackService.GetAudioFileInfo(ctx, track.AudioFileKey)
+	// Get object from MinIO through track service
+	object, err := h.trackService.GetAudioFile(ctx, audioKey)
 	if err != nil {
-		h.logger.Error("Failed to get object info", "track_id", trackID, "error", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to get audio info")
+		h.logger.Error("Failed to get object from MinIO", "track_id", trackID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get audio file"})
 		return
 	}
+	defer object.Close()
 
 	// Create a ReadSeeker from the object
 	// Note: For production with large files, you might want to implement
 	// proper Range Request handling directly with MinIO SeekRead functionality
-	tempFile, err := os.CreateTemp("", "stream-*.mp3")
+	tempFile, err := os.CreateTemp(h.trackService.TempDir(), "stream-*.mp3")
 	if err != nil {
 		h.logger.Error("Failed to create temp file", "error", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to prepare streaming")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to prepare streaming"})
 		return
 	}
 	defer os.Remove(tempFile.Name())
@@ -177,20 +241,21 @@ func (h *TrackHandler) StreamTrack(w http.ResponseWriter, r *http.Request) {
 	// Copy object to temp file
 	if _, err := io.Copy(tempFile, object); err != nil {
 		h.logger.Error("Failed to copy object to temp file", "error", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to prepare streaming")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to prepare streaming"})
 		return
 	}
 
 	// Seek to beginning
 	if _, err := tempFile.Seek(0, 0); err != nil {
 		h.logger.Error("Failed to seek temp file", "error", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to prepare streaming")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to prepare streaming"})
 		return
 	}
 
 	// Set content type
 	w.Header().Set("Content-Type", "audio/mpeg")
 	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Disposition", contentDisposition(disposition, filename))
 
 	// Use http.ServeContent to handle Range Requests properly
 	modTime := info.LastModified
@@ -198,7 +263,7 @@ func (h *TrackHandler) StreamTrack(w http.ResponseWriter, r *http.Request) {
 		modTime = time.Now()
 	}
 
-	http.ServeContent(w, r, track.Title+".mp3", modTime, tempFile)
+	http.ServeContent(w, r, filename, modTime, tempFile)
 }
 
 // ListTracks returns a paginated list of tracks (supports optional authentication)
@@ -208,9 +273,14 @@ func (h *TrackHandler) StreamTrack(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param page query int false "Page number" default(1) Example(1)
 // @Param limit query int false "Items per page" default(20) Example(20)
-// @Param genre query string false "Filter by genre" Example(rock)
+// @Param genre query string false "Filter by genre (aliases like hiphop or rnb are accepted)" Example(rock)
+// @Param artist query string false "Filter by artist name (case-insensitive)" Example(Radiohead)
+// @Param created_after query string false "Only tracks uploaded at or after this RFC 3339 time" Example(2024-01-01T00:00:00Z)
+// @Param created_before query string false "Only tracks uploaded at or before this RFC 3339 time" Example(2024-02-01T00:00:00Z)
+// @Param cursor query string false "Opaque cursor from next_cursor of the previous page; takes precedence over page"
 // @Param Authorization header string false "Bearer token for authenticated access (shows like status)" Example(Bearer eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...)
 // @Success 200 {object} models.TrackListResponse "List of tracks with pagination"
+// @Failure 400 {object} map[string]string "Bad request - unknown genre, artist filter too long, malformed created range or invalid cursor"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/tracks [get]
 func (h *TrackHandler) ListTracks(w http.ResponseWriter, r *http.Request) {
@@ -234,14 +304,33 @@ func (h *TrackHandler) ListTracks(w http.ResponseWriter, r *http.Request) {
 	userID, _ := middleware.GetUserID(ctx)
 	// userID will be 0 if user is not authenticated, which is fine
 
-	// Get genre filter
-	genreFilter := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("genre")))
+	// Get genre and artist filters
+	genre, ok := parseGenreFilter(w, r)
+	if !ok {
+		return
+	}
+	filter := models.TrackFilter{
+		Genre:  genre,
+		Artist: strings.TrimSpace(r.URL.Query().Get("artist")),
+	}
+	if utf8.RuneCountInString(filter.Artist) > models.MaxArtistFilterLength {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: fmt.Sprintf("Artist filter must be at most %d characters", models.MaxArtistFilterLength)})
+		return
+	}
+	if filter.CreatedAfter, filter.CreatedBefore, ok = parseCreatedRange(w, r); !ok {
+		return
+	}
 
 	// Call track service with optional user (now returns TrackResponse)
-	tracks, total, err := h.trackService.ListTracksWithOptionalUser(ctx, page, limit, userID, genreFilter)
+	cursor := r.URL.Query().Get("cursor")
+	tracks, total, nextCursor, err := h.trackService.ListTracksWithOptionalUser(ctx, page, limit, userID, filter, cursor)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidParameter, Message: "Invalid cursor"})
+			return
+		}
 		h.logger.Error("Failed to list tracks", "error", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to list tracks")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to list tracks"})
 		return
 	}
 
@@ -254,10 +343,151 @@ func (h *TrackHandler) ListTracks(w http.ResponseWriter, r *http.Request) {
 			"total": total,
 		},
 	}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+	}
 
 	sendJSONResponse(w, http.StatusOK, response)
 }
 
+// Radio returns a shuffled batch of tracks of a genre
+// @Summary Genre Radio (Optional Auth)
+// @Description Random tracks of the genre, skipping the excluded IDs. Clients pass the IDs already queued as exclude to keep the stream fresh. For authenticated users tracks played in the last week come only after all others.
+// @Tags tracks
+// @Produce json
+// @Param genre query string true "Genre (aliases like hiphop or rnb are accepted)" Example(rock)
+// @Param exclude query string false "Comma-separated track IDs to skip (at most 200)"
+// @Param limit query int false "Number of tracks" default(20) minimum(1) maximum(50)
+// @Param Authorization header string false "Bearer token; avoids recently played tracks and shows like status"
+// @Success 200 {object} models.RadioResponse "Shuffled tracks"
+// @Failure 400 {object} map[string]string "Bad request - missing or unknown genre, malformed or too many excluded IDs"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/radio [get]
+func (h *TrackHandler) Radio(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	genre, ok := parseGenreFilter(w, r)
+	if !ok {
+		return
+	}
+	if genre == "" {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Parameter 'genre' is required"})
+		return
+	}
+
+	excludeIDs := []string{}
+	for _, id := range strings.Split(r.URL.Query().Get("exclude"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			excludeIDs = append(excludeIDs, id)
+		}
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	userID, _ := middleware.GetUserID(ctx)
+
+	tracks, err := h.trackService.GetRadio(ctx, genre, excludeIDs, userID, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTrackIDs) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		h.logger.Error("Failed to get radio tracks", "genre", genre, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get radio tracks"})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.RadioResponse{Genre: genre, Tracks: tracks})
+}
+
+// ListAlbumTracks returns an album's tracks in album order without the album details
+// @Summary List Album Tracks (Optional Auth)
+// @Tags albums
+// @Produce json
+// @Param id path string true "Album ID" Example(550e8400-e29b-41d4-a716-446655440001)
+// @Param page query int false "Page number" default(1) minimum(1)
+// @Param limit query int false "Items per page" default(50) minimum(1) maximum(100)
+// @Param Authorization header string false "Bearer token for authenticated access (shows like status)"
+// @Success 200 {object} models.TrackListResponse "Album tracks ordered as in the album"
+// @Failure 404 {object} map[string]string "Not found - album does not exist"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/albums/{id}/tracks [get]
+func (h *TrackHandler) ListAlbumTracks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	albumID := chi.URLParam(r, "id")
+	if albumID == "" {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Album ID is required"})
+		return
+	}
+
+	// Get pagination parameters
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	// Get user ID from context (optional)
+	userID, _ := middleware.GetUserID(ctx)
+
+	tracks, total, err := h.trackService.ListTracksByAlbum(ctx, albumID, limit, (page-1)*limit, userID, canViewDrafts(ctx))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeAlbumNotFound, Message: "Album not found"})
+			return
+		}
+		h.logger.Error("Failed to list album tracks", "album_id", albumID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to list album tracks"})
+		return
+	}
+	if tracks == nil {
+		tracks = []models.TrackResponse{}
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.TrackListResponse{
+		Tracks: tracks,
+		Pagination: models.Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		},
+	})
+}
+
+// ListArtists returns distinct artists with their track counts
+// @Summary List Artists
+// @Tags tracks
+// @Produce json
+// @Success 200 {object} models.ArtistListResponse "Artists with track counts"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/artists [get]
+func (h *TrackHandler) ListArtists(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	artists, err := h.trackService.ListArtists(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list artists", "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to list artists"})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.ArtistListResponse{Artists: artists})
+}
+
 // GetTrack returns a single track by ID with optional like status for authenticated users
 // @Summary Get Track by ID (Optional Auth)
 // @Tags tracks
@@ -276,7 +506,7 @@ func (h *TrackHandler) GetTrack(w http.ResponseWriter, r *http.Request) {
 	// Get track ID from URL parameter
 	trackID := chi.URLParam(r, "id")
 	if trackID == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "Track ID is required")
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Track ID is required"})
 		return
 	}
 
@@ -285,24 +515,131 @@ func (h *TrackHandler) GetTrack(w http.ResponseWriter, r *http.Request) {
 	// userID will be 0 if user is not authenticated, which is fine
 
 	// Get track with album info and optional like status
-	track, err := h.trackService.GetTrackWithAlbumInfo(ctx, trackID, userID)
+	track, err := h.trackService.GetTrackWithAlbumInfo(ctx, trackID, userID, canViewDrafts(ctx))
 
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeTrackNotFound, Message: "Track not found"})
+			return
+		}
 		h.logger.Error("Failed to get track", "track_id", trackID, "user_id", userID, "error", err)
-		sendErrorResponse(w, http.StatusNotFound, "Track not found")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get track"})
 		return
 	}
 
 	sendJSONResponse(w, http.StatusOK, track)
 }
 
-// GetUserTracks returns all tracks for the authenticated user
+// GetNextTrack recommends the track to play after the given one
+// @Summary Recommend Next Track (Optional Auth)
+// @Description Returns the next track of the same album, otherwise a track of the same genre the user hasn't played in the last week, otherwise the most played track. The reason field tells which rule matched.
+// @Tags tracks
+// @Produce json
+// @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param Authorization header string false "Bearer token for authenticated access (excludes recently played tracks, shows like status)"
+// @Success 200 {object} models.NextTrackResponse "Recommended track"
+// @Success 204 "No other track to recommend"
+// @Failure 404 {object} map[string]string "Not found - track does not exist"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/tracks/{id}/next [get]
+func (h *TrackHandler) GetNextTrack(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	trackID := chi.URLParam(r, "id")
+	userID, _ := middleware.GetUserID(ctx)
+
+	next, err := h.trackService.RecommendNext(ctx, trackID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeTrackNotFound, Message: "Track not found"})
+			return
+		}
+		h.logger.Error("Failed to recommend next track", "track_id", trackID, "user_id", userID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to recommend next track"})
+		return
+	}
+	if next == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, next)
+}
+
+// GetLyrics returns the lyrics of a track
+// @Summary Get Track Lyrics
+// @Tags tracks
+// @Produce json
+// @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Success 200 {object} models.Lyrics "Plain or LRC lyrics"
+// @Failure 404 {object} map[string]string "Not found - track does not exist or has no lyrics"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/tracks/{id}/lyrics [get]
+func (h *TrackHandler) GetLyrics(w http.ResponseWriter, r *http.Request) {
+	trackID := chi.URLParam(r, "id")
+
+	lyrics, err := h.trackService.GetLyrics(r.Context(), trackID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrLyricsNotFound):
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeLyricsNotFound, Message: "Track has no lyrics"})
+		case errors.Is(err, repository.ErrNotFound):
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeTrackNotFound, Message: "Track not found"})
+		default:
+			h.logger.Error("Failed to get track lyrics", "track_id", trackID, "error", err)
+			sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get lyrics"})
+		}
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, lyrics)
+}
+
+// GetStreamURL returns a temporary direct URL of a track's audio
+// @Summary Get Track Stream URL
+// @Description Returns a presigned MinIO URL that native players can fetch directly, without proxying through the API. The link expires after STREAM_URL_EXPIRY.
+// @Tags tracks
+// @Produce json
+// @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param quality query string false "high or a rendition from AUDIO_RENDITIONS" default(high) Example(low)
+// @Success 200 {object} models.StreamURLResponse "Presigned audio URL and its expiry"
+// @Failure 400 {object} map[string]string "Bad request - unknown quality"
+// @Failure 404 {object} map[string]string "Not found - track does not exist"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/tracks/{id}/stream-url [get]
+func (h *TrackHandler) GetStreamURL(w http.ResponseWriter, r *http.Request) {
+	trackID := chi.URLParam(r, "id")
+
+	quality, ok := streamQuality(w, r, h.trackService)
+	if !ok {
+		return
+	}
+
+	streamURL, err := h.trackService.GetStreamURL(r.Context(), trackID, quality, canViewDrafts(r.Context()))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeTrackNotFound, Message: "Track not found"})
+			return
+		}
+		h.logger.Error("Failed to get stream URL", "track_id", trackID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get stream URL"})
+		return
+	}
+
+	// The URL grants access on its own, so it must not be cached past its expiry
+	w.Header().Set("Cache-Control", "no-store")
+	sendJSONResponse(w, http.StatusOK, streamURL)
+}
+
+// GetUserTracks returns the authenticated user's tracks, newest first
 // @Summary Get User's Tracks
 // @Security BearerAuth
 // @Tags tracks
 // @Accept json
 // @Produce json
-// @Success 200 {object} models.UserTracksResponse "List of user's tracks"
+// @Param page query int false "Page number" default(1) Example(1)
+// @Param limit query int false "Items per page (max 100)" default(20) Example(20)
+// @Success 200 {object} models.TrackListResponse "User's tracks with pagination"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/tracks/my [get]
@@ -312,70 +649,94 @@ func (h *TrackHandler) GetUserTracks(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
 	userID, ok := middleware.GetUserID(ctx)
 	if !ok {
-		sendErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		sendAPIError(w, APIError{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Unauthorized"})
 		return
 	}
 
+	// Get pagination parameters
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
 	// Call track service with album info
-	tracks, err := h.trackService.GetUserTracksWithAlbumInfo(ctx, userID)
+	tracks, total, err := h.trackService.GetUserTracksWithAlbumInfo(ctx, userID, page, limit)
 	if err != nil {
 		h.logger.Error("Failed to get user tracks", "user_id", userID, "error", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to get user tracks")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get user tracks"})
 		return
 	}
 
-	sendJSONResponse(w, http.StatusOK, map[string]interface{}{
-		"tracks": tracks,
+	sendJSONResponse(w, http.StatusOK, models.TrackListResponse{
+		Tracks: tracks,
+		Pagination: models.TrackPagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		},
 	})
 }
 
-// DeleteTrack deletes a track
-// @Summary Delete Track (Admin)
+// DeleteTrack deletes a track uploaded by the current user
+// @Summary Delete Own Track
+// @Description Only the user who uploaded the track can delete it; admins delete any track via DELETE /api/admin/tracks/{id}.
 // @Security BearerAuth
-// @Tags admin
+// @Tags tracks
 // @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
 // @Success 204 "No Content - track deleted successfully"
 // @Failure 400 {object} map[string]string "Bad request - invalid track ID"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
-// @Failure 403 {object} map[string]string "Forbidden - insufficient permissions"
+// @Failure 403 {object} map[string]string "Forbidden - the track belongs to another user"
 // @Failure 404 {object} map[string]string "Not found - track does not exist"
 // @Failure 500 {object} map[string]string "Internal server error"
-// @Router /api/admin/tracks/{id} [delete]
+// @Router /api/tracks/{id} [delete]
 func (h *TrackHandler) DeleteTrack(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Get user ID from context
 	userID, ok := middleware.GetUserID(ctx)
 	if !ok {
-		sendErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		sendAPIError(w, APIError{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Unauthorized"})
 		return
 	}
 
 	// Get track ID from URL parameter
-	trackID := r.URL.Path[len("/api/tracks/"):]
+	trackID := chi.URLParam(r, "id")
 	if trackID == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "Track ID is required")
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Track ID is required"})
 		return
 	}
 
 	// Get track to verify ownership
 	track, err := h.trackService.GetTrack(ctx, trackID)
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeTrackNotFound, Message: "Track not found"})
+			return
+		}
 		h.logger.Error("Failed to get track", "track_id", trackID, "error", err)
-		sendErrorResponse(w, http.StatusNotFound, "Track not found")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get track"})
 		return
 	}
 
 	// Check ownership
 	if track.UserID != userID {
-		sendErrorResponse(w, http.StatusForbidden, "You don't have permission to delete this track")
+		sendAPIError(w, APIError{Status: http.StatusForbidden, Code: CodeForbidden, Message: "You don't have permission to delete this track"})
 		return
 	}
 
 	// Delete track
 	if err := h.trackService.DeleteTrack(ctx, trackID); err != nil {
 		h.logger.Error("Failed to delete track", "track_id", trackID, "error", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete track")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to delete track"})
 		return
 	}
 
@@ -384,6 +745,8 @@ func (h *TrackHandler) DeleteTrack(w http.ResponseWriter, r *http.Request) {
 
 // ToggleLike toggles a like for a track
 // @Summary Toggle Track Like
+// @Description Deprecated: a repeated request undoes the first one. Use POST or DELETE /api/tracks/{id}/like instead.
+// @Deprecated
 // @Security BearerAuth
 // @Tags tracks
 // @Accept json
@@ -394,21 +757,21 @@ func (h *TrackHandler) DeleteTrack(w http.ResponseWriter, r *http.Request) {
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
 // @Failure 404 {object} map[string]string "Not found - track does not exist"
 // @Failure 500 {object} map[string]string "Internal server error"
-// @Router /api/tracks/{id}/like [post]
+// @Router /api/tracks/{id}/like/toggle [post]
 func (h *TrackHandler) ToggleLike(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Get user ID from context
 	userID, ok := middleware.GetUserID(ctx)
 	if !ok {
-		sendErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		sendAPIError(w, APIError{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Unauthorized"})
 		return
 	}
 
 	// Get track ID from URL parameter
 	trackID := chi.URLParam(r, "id")
 	if trackID == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "Track ID is required")
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Track ID is required"})
 		return
 	}
 
@@ -416,7 +779,7 @@ func (h *TrackHandler) ToggleLike(w http.ResponseWriter, r *http.Request) {
 	isLiked, likesCount, err := h.trackService.ToggleLike(ctx, userID, trackID)
 	if err != nil {
 		h.logger.Error("Failed to toggle like", "track_id", trackID, "user_id", userID, "error", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to toggle like")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to toggle like"})
 		return
 	}
 
@@ -428,14 +791,271 @@ func (h *TrackHandler) ToggleLike(w http.ResponseWriter, r *http.Request) {
 	sendJSONResponse(w, http.StatusOK, response)
 }
 
-// IncrementPlays increments the play count for a track
-// @Summary Increment Track Play Count
+// AddLike likes a track idempotently
+// @Summary Like Track
+// @Description Likes the track. Liking an already liked track is a no-op that returns the current count.
+// @Security BearerAuth
+// @Tags tracks
+// @Produce json
+// @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Success 200 {object} models.ToggleLikeResponse "Current like status"
+// @Failure 400 {object} map[string]string "Bad request - invalid track ID"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Not found - track does not exist"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/tracks/{id}/like [post]
+func (h *TrackHandler) AddLike(w http.ResponseWriter, r *http.Request) {
+	h.changeLike(w, r, true)
+}
+
+// RemoveLike removes the user's like idempotently
+// @Summary Unlike Track
+// @Description Removes the like. Removing a like that doesn't exist is a no-op that returns the current count.
+// @Security BearerAuth
 // @Tags tracks
+// @Produce json
 // @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
-// @Success 200 "OK - Play count incremented"
+// @Success 200 {object} models.ToggleLikeResponse "Current like status"
 // @Failure 400 {object} map[string]string "Bad request - invalid track ID"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Not found - track does not exist"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/tracks/{id}/like [delete]
+func (h *TrackHandler) RemoveLike(w http.ResponseWriter, r *http.Request) {
+	h.changeLike(w, r, false)
+}
+
+// changeLike adds or removes the current user's like and writes the resulting state
+func (h *TrackHandler) changeLike(w http.ResponseWriter, r *http.Request, liked bool) {
+	ctx := r.Context()
+
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		sendAPIError(w, APIError{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Unauthorized"})
+		return
+	}
+
+	trackID := chi.URLParam(r, "id")
+	if trackID == "" {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Track ID is required"})
+		return
+	}
+
+	var likesCount int
+	var err error
+	if liked {
+		likesCount, err = h.trackService.AddLike(ctx, userID, trackID)
+	} else {
+		likesCount, err = h.trackService.RemoveLike(ctx, userID, trackID)
+	}
+	if err != nil {
+		h.logger.Error("Failed to change like", "track_id", trackID, "user_id", userID, "liked", liked, "error", err)
+		if strings.Contains(err.Error(), "invalid track ID") {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidID, Message: "Invalid track ID"})
+			return
+		}
+		if errors.Is(err, repository.ErrTrackNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeTrackNotFound, Message: "Track not found"})
+			return
+		}
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to update like"})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.ToggleLikeResponse{
+		Liked:      liked,
+		LikesCount: likesCount,
+	})
+}
+
+// SetLike sets the like state of a track idempotently
+// @Summary Set Track Like State
+// @Description Sets the like state to the requested value. Repeating the request is a no-op.
+// @Security BearerAuth
+// @Tags tracks
+// @Accept json
+// @Produce json
+// @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param input body models.SetLikeRequest true "Desired like state"
+// @Success 200 {object} models.ToggleLikeResponse "Current like status"
+// @Failure 400 {object} map[string]string "Bad request - invalid track ID or body"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
 // @Failure 404 {object} map[string]string "Not found - track does not exist"
 // @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/tracks/{id}/like [put]
+func (h *TrackHandler) SetLike(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Get user ID from context
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		sendAPIError(w, APIError{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Unauthorized"})
+		return
+	}
+
+	// Get track ID from URL parameter
+	trackID := chi.URLParam(r, "id")
+	if trackID == "" {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Track ID is required"})
+		return
+	}
+
+	var req models.SetLikeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidJSON, Message: "Invalid JSON"})
+		return
+	}
+	if req.Liked == nil {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Field 'liked' is required"})
+		return
+	}
+
+	isLiked, likesCount, err := h.trackService.SetLike(ctx, userID, trackID, *req.Liked)
+	if err != nil {
+		h.logger.Error("Failed to set like", "track_id", trackID, "user_id", userID, "error", err)
+		if strings.Contains(err.Error(), "invalid track ID") {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidID, Message: "Invalid track ID"})
+			return
+		}
+		if strings.Contains(err.Error(), "track not found") {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeTrackNotFound, Message: "Track not found"})
+			return
+		}
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to set like"})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.ToggleLikeResponse{
+		Liked:      isLiked,
+		LikesCount: likesCount,
+	})
+}
+
+// CheckLikes reports the current user's like status for a list of tracks
+// @Summary Check Track Like Status
+// @Description Returns a map of track ID to whether the current user liked the track. At most 100 IDs per request; unknown tracks are reported as not liked.
+// @Security BearerAuth
+// @Tags tracks
+// @Accept json
+// @Produce json
+// @Param input body models.CheckLikesRequest true "Track IDs to check"
+// @Success 200 {object} map[string]bool "Like status by track ID"
+// @Failure 400 {object} map[string]string "Bad request - invalid body, malformed track ID or too many IDs"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/tracks/likes/check [post]
+func (h *TrackHandler) CheckLikes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		sendAPIError(w, APIError{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Unauthorized"})
+		return
+	}
+
+	var req models.CheckLikesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidJSON, Message: "Invalid JSON"})
+		return
+	}
+	if req.TrackIDs == nil {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Field 'track_ids' is required"})
+		return
+	}
+
+	likes, err := h.trackService.CheckLikes(ctx, userID, req.TrackIDs)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTrackIDs) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		h.logger.Error("Failed to check likes", "user_id", userID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to check likes"})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, likes)
+}
+
+// GetTrackLikers returns users who liked a track, newest first
+// @Summary List Track Likers
+// @Description Returns public profiles (id, name, avatar) of users who liked the track. Emails are never exposed.
+// @Security BearerAuth
+// @Tags tracks
+// @Produce json
+// @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param page query int false "Page number" default(1) Example(1)
+// @Param limit query int false "Items per page (max 50)" default(20) Example(20)
+// @Success 200 {object} models.TrackLikersResponse "Users who liked the track with pagination"
+// @Failure 400 {object} map[string]string "Bad request - invalid track ID"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Not found - track does not exist"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/tracks/{id}/likes [get]
+func (h *TrackHandler) GetTrackLikers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Get track ID from URL parameter
+	trackID := chi.URLParam(r, "id")
+	if trackID == "" {
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Track ID is required"})
+		return
+	}
+
+	// Get pagination parameters
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	likers, total, err := h.trackService.GetTrackLikers(ctx, trackID, page, limit)
+	if err != nil {
+		h.logger.Error("Failed to get track likers", "track_id", trackID, "error", err)
+		if strings.Contains(err.Error(), "invalid track ID") {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidID, Message: "Invalid track ID"})
+			return
+		}
+		if strings.Contains(err.Error(), "track not found") {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeTrackNotFound, Message: "Track not found"})
+			return
+		}
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get track likers"})
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, models.TrackLikersResponse{
+		Users: likers,
+		Pagination: models.Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		},
+	})
+}
+
+// IncrementPlays increments the play count for a track and records it in the user's play history
+// @Summary Increment Track Play Count (Optional Auth)
+// @Description Repeated plays of a track by the same user (or client IP when anonymous) within PLAY_DEBOUNCE_INTERVAL are ignored. Counted plays are written in batches every PLAY_FLUSH_INTERVAL; anonymous plays don't go to play history.
+// @Tags tracks
+// @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param Authorization header string false "Bearer token; plays of authenticated users are recorded in their history"
+// @Success 200 "OK - Play accepted"
+// @Failure 400 {object} map[string]string "Bad request - invalid track ID"
+// @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/tracks/{id}/play [post]
 func (h *TrackHandler) IncrementPlays(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -443,14 +1063,29 @@ func (h *TrackHandler) IncrementPlays(w http.ResponseWriter, r *http.Request) {
 	// Get track ID from URL parameter
 	trackID := chi.URLParam(r, "id")
 	if trackID == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "Track ID is required")
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Track ID is required"})
 		return
 	}
 
+	// Anonymous plays are debounced per client IP
+	userID, ok := middleware.GetUserID(ctx)
+	listener := service.UserListener(userID)
+	if !ok {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		listener = service.IPListener(host)
+	}
+
 	// Increment plays
-	if err := h.trackService.IncrementPlays(ctx, trackID); err != nil {
-		h.logger.Error("Failed to increment plays", "track_id", trackID, "error", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "Failed to increment plays")
+	if _, err := h.trackService.IncrementPlays(ctx, trackID, userID, listener); err != nil {
+		if strings.Contains(err.Error(), "invalid track ID") {
+			sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidID, Message: "Invalid track ID"})
+			return
+		}
+		h.logger.Error("Failed to increment plays", "track_id", trackID, "user_id", userID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to increment plays"})
 		return
 	}
 
@@ -461,8 +1096,10 @@ func (h *TrackHandler) IncrementPlays(w http.ResponseWriter, r *http.Request) {
 // @Summary Get Track Cover Image
 // @Tags tracks
 // @Param id path string true "Track ID" Example(550e8400-e29b-41d4-a716-446655440000)
+// @Param size query string false "Thumbnail size from COVER_THUMBNAIL_SIZES, original when omitted" example(small)
 // @Success 200 {file} binary "Cover image"
-// @Failure 404 {object} map[string]string "Not found - track or cover does not exist"
+// @Failure 400 {object} map[string]string "Bad request - unknown size"
+// @Failure 404 {object} map[string]string "Not found - track or cover does not exist, or the cover is missing in storage (file_missing)"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/tracks/{id}/cover [get]
 func (h *TrackHandler) GetTrackCover(w http.ResponseWriter, r *http.Request) {
@@ -471,41 +1108,57 @@ func (h *TrackHandler) GetTrackCover(w http.ResponseWriter, r *http.Request) {
 	// Get track ID from URL parameter
 	trackID := chi.URLParam(r, "id")
 	if trackID == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "Track ID is required")
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeMissingField, Message: "Track ID is required"})
+		return
+	}
+
+	size, ok := coverSize(w, r, h.thumbnailService)
+	if !ok {
 		return
 	}
 	
 	h.logger.Info("GetTrackCover called", "track_id", trackID, "path", r.URL.Path)
 
 	// Get track with album info
-	trackResponse, err := h.trackService.GetTrackWithAlbumInfo(ctx, trackID, 0) // No user ID needed for cover
+	// No user ID needed for cover; covers of drafts stay reachable for admin <img> tags, which can't send a token
+	trackResponse, err := h.trackService.GetTrackWithAlbumInfo(ctx, trackID, 0, true)
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeTrackNotFound, Message: "Track not found"})
+			return
+		}
 		h.logger.Error("Failed to get track with album info", "track_id", trackID, "error", err)
-		sendErrorResponse(w, http.StatusNotFound, "Track not found")
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to get track"})
 		return
 	}
 
-	// Check if track has cover (from album)
+	// Check if track has cover (its own or the album's)
 	if trackResponse.CoverImageKey == "" {
 		h.logger.Warn("Track has no cover image", "track_id", trackID, "cover_key", trackResponse.CoverImageKey)
-		sendErrorResponse(w, http.StatusNotFound, "Track has no cover image")
+		sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeCoverNotFound, Message: "Track has no cover image"})
 		return
 	}
 
-	// Get image from MinIO through track service
-	object, err := h.trackService.GetCoverImage(ctx, trackResponse.CoverImageKey)
+	// Thumbnails are cached per cover key, so tracks using the album cover share its thumbnails
+	coverKey := resolveCoverKey(ctx, h.thumbnailService, h.logger, trackResponse.AlbumID, trackResponse.CoverImageKey, size)
+
+	// Get object info for content type; a cover deleted from MinIO is a 404
+	info, err := h.trackService.GetCoverImageInfo(ctx, coverKey)
 	if err != nil {
-		h.logger.Error("Failed to get cover from MinIO", "track_id", trackID, "cover_key", trackResponse.CoverImageKey, "error", err)
-		sendErrorResponse(w, http.StatusNotFound, "Cover image not found")
-		return
+		if sendIfObjectMissing(w, h.logger, err, coverKey, "track_id", trackID) {
+			return
+		}
+		h.logger.Warn("Failed to get object info", "cover_key", coverKey, "error", err)
 	}
-	defer object.Close()
 
-	// Get object info for content type
-	info, err := h.trackService.GetCoverImageInfo(ctx, trackResponse.CoverImageKey)
+	// Get image from MinIO through track service
+	object, err := h.trackService.GetCoverImage(ctx, coverKey)
 	if err != nil {
-		h.logger.Warn("Failed to get object info", "cover_key", trackResponse.CoverImageKey, "error", err)
+		h.logger.Error("Failed to get cover from MinIO", "track_id", trackID, "cover_key", coverKey, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusNotFound, Code: CodeCoverNotFound, Message: "Cover image not found"})
+		return
 	}
+	defer object.Close()
 
 	// Set content type
 	contentType := "image/jpeg" // default
@@ -513,18 +1166,25 @@ func (h *TrackHandler) GetTrackCover(w http.ResponseWriter, r *http.Request) {
 		contentType = info.ContentType
 	} else {
 		// Try to detect from file extension
-		if strings.HasSuffix(strings.ToLower(trackResponse.CoverImageKey), ".png") {
+		if strings.HasSuffix(strings.ToLower(coverKey), ".png") {
 			contentType = "image/png"
-		} else if strings.HasSuffix(strings.ToLower(trackResponse.CoverImageKey), ".webp") {
+		} else if strings.HasSuffix(strings.ToLower(coverKey), ".webp") {
 			contentType = "image/webp"
 		}
 	}
-	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Cache-Control", "public, max-age=31536000") // Cache for 1 year
 
-	// Copy image data to response
-	if _, err := io.Copy(w, object); err != nil {
+	var etag string
+	var modTime time.Time
+	if info != nil {
+		etag = info.ETag
+		modTime = info.LastModified
+	}
+
+	// Covers are small, so buffer them to support range and conditional requests
+	if err := serveBufferedObject(w, r, object, coverKey, contentType, etag, modTime); err != nil {
 		h.logger.Error("Failed to serve cover image", "track_id", trackID, "error", err)
+		sendAPIError(w, APIError{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Failed to read cover image"})
 		return
 	}
 }
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+)
+
+// UploadLimits bounds the size of uploaded files in bytes
+type UploadLimits struct {
+	MaxCoverSize   int64
+	MaxAudioSize   int64
+	MaxAvatarSize  int64
+	MaxArchiveSize int64
+}
+
+// MultipartOverhead allows for form fields and multipart framing on top of the file limits
+const MultipartOverhead = 1 << 20
+
+// multipartMemory is how much of a multipart form is kept in memory before spilling to disk
+const multipartMemory = 32 << 20
+
+// parseUploadForm caps the request body at maxBodySize plus framing overhead and parses the
+// multipart form. On failure it writes the error response (413 when the body is too large)
+// and returns false.
+func parseUploadForm(w http.ResponseWriter, r *http.Request, maxBodySize int64, tooLargeMessage string) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize+MultipartOverhead)
+
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			sendAPIError(w, APIError{Status: http.StatusRequestEntityTooLarge, Code: CodeFileTooLarge, Message: tooLargeMessage})
+			return false
+		}
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeBadRequest, Message: "Invalid form data"})
+		return false
+	}
+
+	return true
+}
+
+// checkFileSize writes a 413 response and returns false when an uploaded file exceeds limit
+func checkFileSize(w http.ResponseWriter, header *multipart.FileHeader, limit int64, tooLargeMessage string) bool {
+	if header != nil && header.Size > limit {
+		sendAPIError(w, APIError{Status: http.StatusRequestEntityTooLarge, Code: CodeFileTooLarge, Message: tooLargeMessage})
+		return false
+	}
+	return true
+}
+
+// coverTooLargeMessage describes the cover size limit
+func (l UploadLimits) coverTooLargeMessage() string {
+	return fmt.Sprintf("Cover image is too large. Maximum allowed: %s", formatSize(l.MaxCoverSize))
+}
+
+// audioTooLargeMessage describes the audio size limit
+func (l UploadLimits) audioTooLargeMessage() string {
+	return fmt.Sprintf("Audio file is too large. Maximum allowed: %s", formatSize(l.MaxAudioSize))
+}
+
+// archiveTooLargeMessage describes the bulk upload archive size limit
+func (l UploadLimits) archiveTooLargeMessage() string {
+	return fmt.Sprintf("Archive is too large. Maximum allowed: %s", formatSize(l.MaxArchiveSize))
+}
+
+// avatarTooLargeMessage describes the avatar size limit
+func (l UploadLimits) avatarTooLargeMessage() string {
+	return fmt.Sprintf("Avatar is too large. Maximum allowed: %s", formatSize(l.MaxAvatarSize))
+}
+
+// formatSize renders a byte count as MB when it is a whole number of megabytes
+func formatSize(size int64) string {
+	if size >= 1<<20 && size%(1<<20) == 0 {
+		return fmt.Sprintf("%dMB", size>>20)
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"koteyye_music_be/internal/middleware"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// multipartBody builds a form with the given fields and one file field
+func multipartBody(t *testing.T, fields map[string]string, fileField string, file []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatalf("write field: %v", err)
+		}
+	}
+	part, err := mw.CreateFormFile(fileField, fileField+".jpg")
+	if err != nil {
+		t.Fatalf("create file part: %v", err)
+	}
+	if _, err := part.Write(file); err != nil {
+		t.Fatalf("write file part: %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("close multipart writer: %v", err)
+	}
+	return &body, mw.FormDataContentType()
+}
+
+// assertErrorCode checks the status and error code of a flat error response
+func assertErrorCode(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, status, rec.Body)
+	}
+	var resp struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Code != code {
+		t.Errorf("code = %q, want %q", resp.Code, code)
+	}
+}
+
+func TestCreateAlbumRejectsOversizedCover(t *testing.T) {
+	h := NewAdminHandler(nil, nil, nil, nil, nil, UploadLimits{MaxCoverSize: 1024}, discardLogger)
+	fields := map[string]string{"title": "Album", "artist": "Artist", "genre": "rock", "release_date": "2024-01-01"}
+
+	for name, size := range map[string]int{
+		"file over limit":    2048,
+		"body over overhead": 2 * MultipartOverhead,
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, contentType := multipartBody(t, fields, "cover", bytes.Repeat([]byte{0xff}, size))
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/albums", body)
+			req.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+
+			h.CreateAlbum(rec, req)
+
+			assertErrorCode(t, rec, http.StatusRequestEntityTooLarge, CodeFileTooLarge)
+		})
+	}
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// zeros is an endless stream of zero bytes
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestUploadRejectsTooLargeBodyBeforeReadingFile(t *testing.T) {
+	const fileSize = 16 << 20
+	limits := UploadLimits{MaxAudioSize: 1024, MaxCoverSize: 1024, MaxAvatarSize: 1024}
+	uploads := map[string]http.HandlerFunc{
+		"track":  NewTrackHandler(nil, nil, limits, discardLogger).UploadTrack,
+		"avatar": NewUserHandler(nil, limits, discardLogger).UploadAvatar,
+	}
+
+	for name, upload := range uploads {
+		t.Run(name, func(t *testing.T) {
+			const boundary = "limit-test"
+			body := &countingReader{r: io.MultiReader(
+				bytes.NewReader([]byte("--"+boundary+"\r\nContent-Disposition: form-data; name=\"file\"; filename=\"big.mp3\"\r\n\r\n")),
+				io.LimitReader(zeros{}, fileSize),
+				bytes.NewReader([]byte("\r\n--"+boundary+"--\r\n")),
+			)}
+			req := httptest.NewRequest(http.MethodPost, "/upload", body)
+			req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, 1))
+			rec := httptest.NewRecorder()
+
+			upload(rec, req)
+
+			if rec.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+			}
+			if body.n > 2*MultipartOverhead {
+				t.Errorf("read %d bytes of a %d byte upload before rejecting it", body.n, fileSize)
+			}
+		})
+	}
+}
@@ -2,28 +2,33 @@ package handler
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
 	"koteyye_music_be/internal/middleware"
 	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/repository"
 	"koteyye_music_be/internal/service"
+	"koteyye_music_be/pkg/filetype"
 )
 
 type UserHandler struct {
-	userService *service.UserService
-	logger      *slog.Logger
+	userService  *service.UserService
+	uploadLimits UploadLimits
+	logger       *slog.Logger
 }
 
-func NewUserHandler(userService *service.UserService, log *slog.Logger) *UserHandler {
+func NewUserHandler(userService *service.UserService, uploadLimits UploadLimits, log *slog.Logger) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		logger:      log,
+		userService:  userService,
+		uploadLimits: uploadLimits,
+		logger:       log,
 	}
 }
 
@@ -61,17 +66,19 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 
 // UpdateMe updates current user profile
 // @Summary Update Current User Profile
+// @Description Partial update: omitted fields are left unchanged, fields sent as null are cleared. PUT and PATCH behave the same.
 // @Security BearerAuth
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param input body models.UpdateProfileRequest true "Profile update data"
 // @Success 200 {object} models.UserProfileResponse "Updated user profile"
-// @Failure 400 {object} map[string]string "Bad request - invalid input"
+// @Failure 400 {object} map[string]string "Bad request - invalid input or name empty or longer than 100 characters"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
 // @Failure 404 {object} map[string]string "User not found"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/users/me [put]
+// @Router /api/users/me [patch]
 func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -86,6 +93,9 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		h.logger.Error("Failed to read request body", "error", err)
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
 		sendErrorResponse(w, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
@@ -101,6 +111,10 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	// Update user profile
 	profile, err := h.userService.UpdateUserProfile(ctx, userID, &req)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidName) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
 		h.logger.Error("Failed to update user profile", "user_id", userID, "error", err)
 		sendErrorResponse(w, http.StatusInternalServerError, "Failed to update user profile")
 		return
@@ -109,13 +123,123 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	sendJSONResponse(w, http.StatusOK, profile)
 }
 
+// GetPermissions returns what the current user is allowed to do
+// @Summary Get Current User Permissions
+// @Description Returns capability flags derived from the user's role so clients don't have to guess from the role name.
+// @Security BearerAuth
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.Permissions "Current user permissions"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/users/me/permissions [get]
+func (h *UserHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	permissions, err := h.userService.GetUserPermissions(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to get user permissions", "user_id", userID, "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to get user permissions")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, permissions)
+}
+
+// GetStats returns aggregate stats of the current user
+// @Summary Get Current User Stats
+// @Description Counts of the user's liked and uploaded tracks and the total plays of their uploads.
+// @Security BearerAuth
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.UserStats "Current user stats"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/users/me/stats [get]
+func (h *UserHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	stats, err := h.userService.GetUserStats(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to get user stats", "user_id", userID, "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to get user stats")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, stats)
+}
+
+// DeleteMe deletes the current user's account
+// @Summary Delete Current User
+// @Description Deletes the account with its likes, uploaded tracks and avatar. Users registered with email and password must confirm with their password; OAuth and guest users send an empty body. The last admin can't be deleted.
+// @Security BearerAuth
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param input body models.DeleteAccountRequest false "Password confirmation"
+// @Success 204 "Account deleted"
+// @Failure 400 {object} map[string]string "Bad request - invalid JSON"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - missing or wrong password"
+// @Failure 409 {object} map[string]string "Conflict - the user is the last admin"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /api/users/me [delete]
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok {
+		sendErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// The body is optional, accounts without a password send none
+	var req models.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
+		sendAPIError(w, APIError{Status: http.StatusBadRequest, Code: CodeInvalidJSON, Message: "Invalid request format"})
+		return
+	}
+
+	if err := h.userService.DeleteAccount(ctx, userID, req.Password); err != nil {
+		switch {
+		case errors.Is(err, service.ErrPasswordConfirmation):
+			sendServiceError(w, http.StatusForbidden, "Password confirmation failed", err)
+		case errors.Is(err, service.ErrLastAdmin):
+			sendServiceError(w, http.StatusConflict, "Cannot delete the last remaining admin", err)
+		case errors.Is(err, repository.ErrUserNotFound):
+			sendServiceError(w, http.StatusNotFound, "User not found", err)
+		default:
+			h.logger.Error("Failed to delete account", "user_id", userID, "error", err)
+			sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete account")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // UploadAvatar handles avatar file upload
 // @Summary Upload User Avatar
 // @Security BearerAuth
 // @Tags users
 // @Accept multipart/form-data
 // @Produce json
-// @Param avatar formData file true "Avatar image file (jpg, png, gif, webp, max 5MB)"
+// @Param avatar formData file true "Avatar image file (jpg, png, gif, webp, max MAX_AVATAR_SIZE)"
 // @Success 200 {object} models.UserProfileResponse "Updated user profile with new avatar"
 // @Failure 400 {object} map[string]string "Bad request - invalid file"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
@@ -132,10 +256,8 @@ func (h *UserHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form (max 10MB in memory)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		h.logger.Error("Failed to parse multipart form", "error", err)
-		sendErrorResponse(w, http.StatusBadRequest, "Failed to parse form data")
+	// Parse multipart form, rejecting oversized bodies before the file is read
+	if !parseUploadForm(w, r, h.uploadLimits.MaxAvatarSize, h.uploadLimits.avatarTooLargeMessage()) {
 		return
 	}
 
@@ -148,12 +270,18 @@ func (h *UserHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
+	if !checkFileSize(w, header, h.uploadLimits.MaxAvatarSize, h.uploadLimits.avatarTooLargeMessage()) {
+		return
+	}
+
 	// Upload avatar
 	profile, err := h.userService.UploadAvatar(ctx, userID, file, header)
 	if err != nil {
 		h.logger.Error("Failed to upload avatar", "user_id", userID, "error", err)
-		if strings.Contains(err.Error(), "file too large") || strings.Contains(err.Error(), "unsupported file type") {
-			sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		if strings.Contains(err.Error(), "file too large") {
+			sendServiceError(w, http.StatusRequestEntityTooLarge, h.uploadLimits.avatarTooLargeMessage(), err)
+		} else if strings.Contains(err.Error(), "unsupported file type") || errors.Is(err, filetype.ErrMismatch) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
 		} else {
 			sendErrorResponse(w, http.StatusInternalServerError, "Failed to upload avatar")
 		}
@@ -197,7 +325,10 @@ func (h *UserHandler) RemoveAvatar(w http.ResponseWriter, r *http.Request) {
 // @Summary Get User Avatar
 // @Tags users
 // @Param key path string true "Avatar key" Example(avatars/123/uuid.jpg)
+// @Param Range header string false "Byte range, e.g. bytes=0-1023"
 // @Success 200 {file} binary "Avatar image"
+// @Success 206 {file} binary "Partial avatar image"
+// @Success 304 "Not modified"
 // @Failure 404 {object} map[string]string "Avatar not found"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/avatars/{key} [get]
@@ -205,14 +336,14 @@ func (h *UserHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Extract avatar key from URL path
-	avatarKey := r.URL.Path[len("/api/avatars/"):]
+	avatarKey := chi.URLParam(r, "*")
 	if avatarKey == "" {
 		sendErrorResponse(w, http.StatusBadRequest, "Avatar key is required")
 		return
 	}
 
 	// Get avatar stream
-	stream, contentType, size, err := h.userService.GetAvatarStream(ctx, avatarKey)
+	stream, contentType, info, err := h.userService.GetAvatarStream(ctx, avatarKey)
 	if err != nil {
 		h.logger.Error("Failed to get avatar", "avatar_key", avatarKey, "error", err)
 		sendErrorResponse(w, http.StatusNotFound, "Avatar not found")
@@ -220,14 +351,12 @@ func (h *UserHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 	defer stream.Close()
 
-	// Set headers
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", size))
 	w.Header().Set("Cache-Control", "public, max-age=86400") // Cache for 1 day
 
-	// Copy stream to response
-	if _, err := io.Copy(w, stream); err != nil {
-		h.logger.Error("Failed to stream avatar", "avatar_key", avatarKey, "error", err)
+	// Avatars are small, so buffer them to support range and conditional requests like covers
+	if err := serveBufferedObject(w, r, stream, avatarKey, contentType, info.ETag, info.LastModified); err != nil {
+		h.logger.Error("Failed to serve avatar", "avatar_key", avatarKey, "error", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Failed to read avatar")
 	}
 }
 
@@ -239,8 +368,9 @@ func (h *UserHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param request body models.PlayerStateRequest true "Player state data"
 // @Success 200 "Player state updated successfully"
-// @Failure 400 {object} map[string]string "Bad request - invalid input data"
+// @Failure 400 {object} map[string]string "Bad request - invalid input data or position beyond the track length"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Track not found"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /api/user/player-state [post]
 func (h *UserHandler) UpdatePlayerState(w http.ResponseWriter, r *http.Request) {
@@ -256,6 +386,9 @@ func (h *UserHandler) UpdatePlayerState(w http.ResponseWriter, r *http.Request)
 	// Parse request body
 	var req models.PlayerStateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if sendIfBodyTooLarge(w, err) {
+			return
+		}
 		h.logger.Error("Failed to decode request body", "error", err)
 		sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
 		return
@@ -278,23 +411,28 @@ func (h *UserHandler) UpdatePlayerState(w http.ResponseWriter, r *http.Request)
 		sendErrorResponse(w, http.StatusBadRequest, "Position must be non-negative")
 		return
 	}
-	if req.Volume < 0 || req.Volume > 100 {
-		sendErrorResponse(w, http.StatusBadRequest, "Volume must be between 0 and 100")
+	if err := h.userService.ValidateVolume(req.Volume); err != nil {
+		sendServiceError(w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
 
 	// Update player state
 	err := h.userService.UpdatePlayerState(ctx, userID, req.TrackID, req.Position, req.Volume)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidVolume) || errors.Is(err, service.ErrInvalidPosition) {
+			sendServiceError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+
 		h.logger.Error("Failed to update player state", "user_id", userID, "track_id", req.TrackID, "error", err)
 
 		// Return specific error messages for better UX
 		if strings.Contains(err.Error(), "track not found") {
-			sendErrorResponse(w, http.StatusNotFound, "Track not found")
+			sendServiceError(w, http.StatusNotFound, "Track not found", err)
 			return
 		}
 		if strings.Contains(err.Error(), "user not found") {
-			sendErrorResponse(w, http.StatusNotFound, "User not found")
+			sendServiceError(w, http.StatusNotFound, "User not found", err)
 			return
 		}
 
@@ -2,8 +2,11 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 
+	"koteyye_music_be/internal/models"
 	"koteyye_music_be/internal/repository"
+	"koteyye_music_be/internal/service"
 )
 
 // RequireAdmin creates middleware that only allows admin users to access protected routes
@@ -24,8 +27,8 @@ func RequireAdmin(userRepo *repository.UserRepository) func(http.Handler) http.H
 				return
 			}
 
-			// Check if user has admin role
-			if user.Role != "admin" {
+			// The admin area is gated on the highest capability, user management
+			if !service.PermissionsFor(user.Role).CanManageUsers {
 				http.Error(w, `{"error":"Forbidden: Admin access required"}`, http.StatusForbidden)
 				return
 			}
@@ -55,7 +58,7 @@ func RequireAuth(userRepo *repository.UserRepository) func(http.Handler) http.Ha
 			}
 
 			// Check if user has valid role (user, admin, or guest)
-			if user.Role != "user" && user.Role != "admin" && user.Role != "guest" {
+			if !slices.Contains(models.UserRoles, user.Role) {
 				http.Error(w, `{"error":"Forbidden: Invalid user role"}`, http.StatusForbidden)
 				return
 			}
@@ -65,3 +68,23 @@ func RequireAuth(userRepo *repository.UserRepository) func(http.Handler) http.Ha
 		})
 	}
 }
+
+// RequireNonGuest creates middleware that rejects guests with 403 unless
+// allowGuests is set. It relies on the role of the token, so it must run
+// after AuthMiddleware.
+func RequireNonGuest(allowGuests bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if allowGuests {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := GetRole(r.Context())
+			if service.PermissionsFor(role).IsGuest {
+				http.Error(w, `{"error":"Forbidden: guests cannot perform this action"}`, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
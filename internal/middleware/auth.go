@@ -45,6 +45,7 @@ func AuthMiddleware(authService *service.AuthService) func(http.Handler) http.Ha
 			// Add user ID and role to context
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
 			ctx = context.WithValue(ctx, RoleKey, role)
+			setLogUserID(ctx, userID)
 
 			// Debug logging
 			// TODO: Remove in production
@@ -103,6 +104,7 @@ func OptionalAuthMiddleware(authService *service.AuthService) func(http.Handler)
 			// Add user ID and role to context
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
 			ctx = context.WithValue(ctx, RoleKey, role)
+			setLogUserID(ctx, userID)
 
 			// Call next handler with updated context
 			next.ServeHTTP(w, r.WithContext(ctx))
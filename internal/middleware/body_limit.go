@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+)
+
+// limitedBody is a request body capped by MaxBodyBytes. It keeps the original
+// body so a route-level MaxBodyBytes can replace the global limit instead of
+// being bounded by it.
+type limitedBody struct {
+	io.ReadCloser
+	original io.ReadCloser
+}
+
+// MaxBodyBytes caps request bodies at n bytes: reading past the limit fails
+// with *http.MaxBytesError, which handlers answer with 413. The body isn't
+// rejected up front so that a route can raise the limit with its own
+// MaxBodyBytes, as upload routes do. The cap applies whatever the
+// Content-Type, which the client controls.
+func MaxBodyBytes(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			original := r.Body
+			if limited, ok := original.(*limitedBody); ok {
+				original = limited.original
+			}
+			r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, original, n), original: original}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
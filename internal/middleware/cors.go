@@ -2,41 +2,46 @@ package middleware
 
 import (
 	"net/http"
-
-	"github.com/go-chi/chi/v5/middleware"
+	"strings"
 )
 
-// CORS middleware for allowing cross-origin requests
-func CORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Range")
-		w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Range, Content-Type")
-		w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
-
-		// Handle preflight requests
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-// Logger is a simple request logger middleware (wrapper around chi logger)
-func Logger(next http.Handler) http.Handler {
-	return middleware.Logger(next)
-}
-
-// Recoverer handles panics gracefully
-func Recoverer(next http.Handler) http.Handler {
-	return middleware.Recoverer(next)
-}
-
-// RequestID adds a unique request ID to each request
-func RequestID(next http.Handler) http.Handler {
-	return middleware.RequestID(next)
+// CORS middleware for allowing cross-origin requests. With an empty
+// allowedOrigins list any origin is allowed via "*" and credentials are not
+// permitted; otherwise only listed origins are echoed back, with credentials.
+func CORS(allowedOrigins, allowedMethods, allowedHeaders []string) func(http.Handler) http.Handler {
+	origins := make(map[string]struct{}, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origins[origin] = struct{}{}
+	}
+	methods := strings.Join(allowedMethods, ", ")
+	headers := strings.Join(allowedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Set CORS headers
+			if len(origins) == 0 {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				// The response depends on Origin, so caches must key on it
+				w.Header().Add("Vary", "Origin")
+				origin := r.Header.Get("Origin")
+				if _, ok := origins[origin]; ok {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Range, Content-Type, X-Request-ID")
+			w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+
+			// Handle preflight requests
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// accessLogKey holds the *accessLogEntry of the current request
+const accessLogKey contextKey = "access_log"
+
+// accessLogEntry carries fields that are only known deeper in the middleware
+// chain. Auth middleware runs after Logger with its own derived context, so it
+// records the user here instead of in a context value Logger can't see.
+type accessLogEntry struct {
+	userID  int
+	hasUser bool
+}
+
+// Logger writes one structured access log record per request with the method,
+// path, status, duration in milliseconds, bytes written, request ID and, when the request is
+// authenticated, the user ID. Server errors are logged at error level.
+func Logger(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			entry := &accessLogEntry{}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), accessLogKey, entry)))
+
+			// A handler that never writes still answers 200
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			attrs := []slog.Attr{
+				slog.String("request_id", GetRequestID(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			}
+			if entry.hasUser {
+				attrs = append(attrs, slog.Int("user_id", entry.userID))
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			log.LogAttrs(r.Context(), level, "HTTP request", attrs...)
+		})
+	}
+}
+
+// setLogUserID records the authenticated user for the access log
+func setLogUserID(ctx context.Context, userID int) {
+	if entry, ok := ctx.Value(accessLogKey).(*accessLogEntry); ok {
+		entry.userID = userID
+		entry.hasUser = true
+	}
+}
+
+// Recoverer turns a panic into a 500 response and logs it with its stack trace.
+// It must run after Logger so the access log records the 500.
+func Recoverer(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// net/http uses this panic to abort a response on purpose
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+
+				log.Error("Panic while handling request",
+					"request_id", GetRequestID(r.Context()),
+					"method", r.Method,
+					"path", r.URL.Path,
+					"panic", rec,
+					"stack", string(debug.Stack()))
+
+				if r.Header.Get("Connection") != "Upgrade" {
+					http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+				}
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
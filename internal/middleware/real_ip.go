@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIPHeader carries the client address set by the reverse proxy
+const RealIPHeader = "X-Real-IP"
+
+// RealIP replaces r.RemoteAddr with the X-Real-IP header when the request
+// comes straight from one of trustedProxies, so handlers and logs see the
+// client rather than the proxy. The header of any other peer is ignored: a
+// client could put any address in it. With no trusted proxies it does nothing.
+func RealIP(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(trustedProxies) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if peer, ok := remoteIP(r.RemoteAddr); ok && isTrusted(peer, trustedProxies) {
+				if client, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get(RealIPHeader))); err == nil {
+					r.RemoteAddr = net.JoinHostPort(client.Unmap().String(), "0")
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// remoteIP parses the address of an http.Request.RemoteAddr, with or without a port
+func remoteIP(remoteAddr string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
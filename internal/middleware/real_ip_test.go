@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("172.16.0.0/12")}
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		remoteAddr string
+		realIP     string
+		want       string
+	}{
+		{"trusted proxy", trusted, "172.18.0.5:41234", "203.0.113.7", "203.0.113.7:0"},
+		{"untrusted peer", trusted, "198.51.100.2:41234", "203.0.113.7", "198.51.100.2:41234"},
+		{"invalid header", trusted, "172.18.0.5:41234", "not-an-ip", "172.18.0.5:41234"},
+		{"no trusted proxies", nil, "172.18.0.5:41234", "203.0.113.7", "172.18.0.5:41234"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := RealIP(tt.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/api/tracks/1/play", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(RealIPHeader, tt.realIP)
+
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the header used to receive and echo request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// RequestID assigns an ID to every request and echoes it in the X-Request-ID response header.
+// When trustIncoming is set, the trace ID of a valid W3C traceparent header or a valid
+// X-Request-ID header is reused so logs line up with upstream tracing; otherwise a UUID is generated.
+// The ID is stored under chi's request ID key, so middleware.GetReqID and the request logger see it.
+func RequestID(trustIncoming bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := ""
+			if trustIncoming {
+				if traceID, ok := parseTraceparent(r.Header.Get("traceparent")); ok {
+					requestID = traceID
+				} else if id := r.Header.Get(RequestIDHeader); isValidRequestID(id) {
+					requestID = id
+				}
+			}
+			if requestID == "" {
+				requestID = uuid.New().String()
+			}
+
+			w.Header().Set(RequestIDHeader, requestID)
+			ctx := context.WithValue(r.Context(), middleware.RequestIDKey, requestID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetRequestID returns the request ID assigned by RequestID
+func GetRequestID(ctx context.Context) string {
+	return middleware.GetReqID(ctx)
+}
+
+// parseTraceparent extracts the trace ID from a W3C traceparent header
+// of the form "version-traceid-parentid-flags"
+func parseTraceparent(header string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return "", false
+	}
+
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || !isLowerHex(version) || version == "ff" {
+		return "", false
+	}
+	// Version 00 has exactly four fields, later versions may append more
+	if version == "00" && len(parts) != 4 {
+		return "", false
+	}
+	if len(traceID) != 32 || !isLowerHex(traceID) || strings.Trim(traceID, "0") == "" {
+		return "", false
+	}
+	if len(parentID) != 16 || !isLowerHex(parentID) || strings.Trim(parentID, "0") == "" {
+		return "", false
+	}
+	if len(flags) != 2 || !isLowerHex(flags) {
+		return "", false
+	}
+
+	return traceID, true
+}
+
+// isValidRequestID accepts short IDs made of characters safe to log and echo
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
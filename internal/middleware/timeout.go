@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// writeDeadlineSlack leaves the timeout response room to be written before
+// the server's write deadline closes the connection
+const writeDeadlineSlack = 5 * time.Second
+
+type timeoutScopeKey struct{}
+
+// timeoutScope is what Timeout shares with a RouteTimeout further down the chain
+type timeoutScope struct {
+	// parent is the request context before Timeout bounded it
+	parent    context.Context
+	onTimeout http.HandlerFunc
+	// released is set once a RouteTimeout has taken over
+	released bool
+}
+
+// Timeout bounds each request's context to d so stalled database, MinIO or
+// ffmpeg calls are cancelled. When the deadline passes before the handler has
+// written a response, onTimeout answers instead of whatever error the handler
+// produces for the cancelled call. Routes that legitimately run longer, such
+// as streams and WebSockets, opt out with RouteTimeout.
+func Timeout(d time.Duration, onTimeout http.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := &timeoutScope{parent: r.Context(), onTimeout: onTimeout}
+			ctx, cancel := context.WithTimeout(context.WithValue(r.Context(), timeoutScopeKey{}, scope), d)
+			defer cancel()
+
+			r = r.WithContext(ctx)
+			tw := &timeoutWriter{ResponseWriter: w, r: r, scope: scope}
+			next.ServeHTTP(tw, r)
+			tw.checkTimeout()
+		})
+	}
+}
+
+// RouteTimeout replaces the bound set by Timeout for the routes it wraps with
+// d, or lifts it when d is 0, and moves the server's write deadline to match.
+// The request context is still cancelled when the client goes away.
+func RouteTimeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent := r.Context()
+			scope, bounded := r.Context().Value(timeoutScopeKey{}).(*timeoutScope)
+			if bounded {
+				scope.released = true
+				parent = scope.parent
+				// Timeout's writer has nothing left to do; skipping it keeps
+				// the writer below reachable for WebSocket hijacking
+				if tw, ok := w.(*timeoutWriter); ok {
+					w = tw.ResponseWriter
+				}
+			}
+
+			var writeDeadline time.Time
+			ctx, cancel := context.WithCancel(parent)
+			if d > 0 {
+				ctx, cancel = context.WithTimeout(parent, d)
+				writeDeadline = time.Now().Add(d + writeDeadlineSlack)
+			}
+			defer cancel()
+			// Not every writer supports deadlines, e.g. in tests; nothing to move then
+			_ = http.NewResponseController(w).SetWriteDeadline(writeDeadline)
+
+			// Keep the values added since Timeout, such as the authenticated user
+			r = r.WithContext(valuesFrom{Context: ctx, values: r.Context()})
+			if !bounded || d == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			tw := &timeoutWriter{ResponseWriter: w, r: r, scope: &timeoutScope{onTimeout: scope.onTimeout}}
+			next.ServeHTTP(tw, r)
+			tw.checkTimeout()
+		})
+	}
+}
+
+// valuesFrom is Context with the values of another context
+type valuesFrom struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesFrom) Value(key any) any {
+	return c.values.Value(key)
+}
+
+// timeoutWriter replaces a response started after the deadline with the timeout response
+type timeoutWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	scope       *timeoutScope
+	wroteHeader bool
+	timedOut    bool
+}
+
+// checkTimeout sends the timeout response if the deadline passed and nothing has been written yet
+func (tw *timeoutWriter) checkTimeout() {
+	if tw.scope.released || tw.wroteHeader || !errors.Is(tw.r.Context().Err(), context.DeadlineExceeded) {
+		return
+	}
+	tw.wroteHeader = true
+	tw.timedOut = true
+	tw.scope.onTimeout(tw.ResponseWriter, tw.r)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.checkTimeout()
+	if tw.timedOut {
+		return
+	}
+	tw.wroteHeader = true
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.checkTimeout()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.wroteHeader = true
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
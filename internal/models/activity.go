@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Activity types reported by the admin activity feed
+const (
+	ActivityRegistration = "registration"
+	ActivityTrackUpload  = "track_upload"
+	ActivityAlbumCreated = "album_created"
+)
+
+// ActivityTypes lists the activity types accepted by the type filter
+var ActivityTypes = []string{ActivityRegistration, ActivityTrackUpload, ActivityAlbumCreated}
+
+// Activity is a normalized entry of the admin activity feed
+type Activity struct {
+	Type       string    `json:"type" example:"track_upload"`
+	OccurredAt time.Time `json:"occurred_at" example:"2024-01-15T10:30:00Z"`
+	ActorID    *int      `json:"actor_id,omitempty" example:"1"`
+	ActorName  *string   `json:"actor_name,omitempty" example:"John Doe"`
+	TargetType string    `json:"target_type" example:"track"`
+	TargetID   string    `json:"target_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	TargetName *string   `json:"target_name,omitempty" example:"My Song"`
+}
+
+// ActivityFilter narrows the activity feed; zero values are not applied
+type ActivityFilter struct {
+	Type string
+	From *time.Time // Inclusive
+	To   *time.Time // Exclusive
+}
+
+// ActivityListResponse represents a paginated activity feed
+type ActivityListResponse struct {
+	Activities []Activity `json:"activities"`
+	Pagination Pagination `json:"pagination"`
+}
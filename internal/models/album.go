@@ -3,6 +3,14 @@ package models
 import (
 	"strings"
 	"time"
+	"unicode"
+)
+
+// Publication states of albums and tracks. Drafts are only visible to admins;
+// a track is public when both it and its album are published.
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
 )
 
 type Album struct {
@@ -11,10 +19,17 @@ type Album struct {
 	Artist        string    `json:"artist" example:"Queen"`
 	ReleaseDate   time.Time `json:"release_date" example:"1975-11-21"`
 	Genre         string    `json:"genre" example:"rock"`
-	CoverImageKey string    `json:"cover_image_key" example:"albums/550e8400-e29b-41d4-a716-446655440000/cover.jpg"`
-	CoverURL      string    `json:"cover_url,omitempty" example:"https://s3.amazonaws.com/bucket/albums/550e8400-e29b-41d4-a716-446655440000/cover.jpg"`
+	CoverImageKey string    `json:"-"` // Internal field for service layer, not exposed to frontend
 	CreatedAt     time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`
 	UpdatedAt     time.Time `json:"updated_at" example:"2024-01-15T10:30:00Z"`
+	// Aggregates over the album's tracks, filled in by repository reads
+	TrackCount           int `json:"track_count" example:"12"`
+	TotalDurationSeconds int `json:"total_duration_seconds" example:"2586"`
+	// IsFeatured marks albums of the curated home page section, ordered by FeaturedOrder
+	IsFeatured    bool `json:"is_featured" example:"false"`
+	FeaturedOrder *int `json:"featured_order,omitempty" example:"1"`
+	// Status is StatusDraft or StatusPublished
+	Status string `json:"status" example:"published"`
 }
 
 type AlbumCreate struct {
@@ -22,17 +37,83 @@ type AlbumCreate struct {
 	Artist      string `json:"artist" validate:"required,min=1,max=255" example:"Queen"`
 	ReleaseDate string `json:"release_date" validate:"required" example:"1975-11-21"`
 	Genre       string `json:"genre" validate:"required" example:"rock"`
+	// Force creates the album even if one with the same title, artist and release date exists
+	Force bool `json:"force,omitempty" example:"false"`
 }
 
+// AlbumResponse is the API representation of an album
 type AlbumResponse struct {
-	ID          string    `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
-	Title       string    `json:"title" example:"A Night at the Opera"`
-	Artist      string    `json:"artist" example:"Queen"`
-	ReleaseDate string    `json:"release_date" example:"1975-11-21"`
-	Genre       string    `json:"genre" example:"rock"`
-	CoverURL    string    `json:"cover_url" example:"https://s3.amazonaws.com/bucket/albums/550e8400-e29b-41d4-a716-446655440000/cover.jpg"`
-	Year        int       `json:"year" example:"1975"`
-	CreatedAt   time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`
+	ID                   string    `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Title                string    `json:"title" example:"A Night at the Opera"`
+	Artist               string    `json:"artist" example:"Queen"`
+	ReleaseDate          string    `json:"release_date" example:"1975-11-21"`
+	Genre                string    `json:"genre" example:"rock"`
+	CoverURL             string    `json:"cover_url" example:"/api/albums/550e8400-e29b-41d4-a716-446655440000/cover"`
+	Year                 int       `json:"year" example:"1975"`
+	TrackCount           int       `json:"track_count" example:"12"`
+	TotalDurationSeconds int       `json:"total_duration_seconds" example:"2586"`
+	IsFeatured           bool      `json:"is_featured" example:"true"`
+	FeaturedOrder        *int      `json:"featured_order,omitempty" example:"1"`
+	Status               string    `json:"status" example:"published"` // draft or published
+	CreatedAt            time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`
+}
+
+// FeaturedAlbumsResponse lists the curated albums in display order
+type FeaturedAlbumsResponse struct {
+	Albums []AlbumResponse `json:"albums"`
+}
+
+// SimilarAlbumsResponse lists albums related to another one, same artist first
+type SimilarAlbumsResponse struct {
+	Albums []AlbumResponse `json:"albums"`
+}
+
+// ArtistAlbumsResponse lists an artist's albums with pagination. TracksCount
+// counts all tracks attributed to the artist, including ones on other artists' albums.
+type ArtistAlbumsResponse struct {
+	Artist      string          `json:"artist" example:"Queen"`
+	TracksCount int             `json:"tracks_count" example:"42"`
+	Albums      []AlbumResponse `json:"albums"`
+	Pagination  Pagination      `json:"pagination"`
+}
+
+// AlbumFeaturedRequest adds an album to or removes it from the featured section.
+// Order is the position within the section; when omitted a newly featured album goes last.
+type AlbumFeaturedRequest struct {
+	Featured bool `json:"featured" example:"true"`
+	Order    *int `json:"order,omitempty" example:"1"`
+}
+
+// AlbumListResponse represents response for listing albums with pagination
+type AlbumListResponse struct {
+	Albums     []AlbumResponse `json:"albums"`
+	Pagination Pagination      `json:"pagination"`
+}
+
+// AlbumFilter represents optional filters for album listings
+type AlbumFilter struct {
+	Genre string `json:"genre,omitempty" example:"rock"`
+	Year  int    `json:"year,omitempty" example:"1975"` // 0 means any year
+	// Featured limits the listing to featured (true) or non-featured (false) albums; nil means both
+	Featured *bool `json:"featured,omitempty" example:"false"`
+	// IncludeDrafts lists draft albums too; only admin listings set it
+	IncludeDrafts bool `json:"-"`
+	// Status limits the listing to albums in this state; empty means any listed state
+	Status string `json:"status,omitempty" example:"draft"`
+	// CreatedAfter and CreatedBefore bound the creation time, inclusive; nil leaves the range open
+	CreatedAfter  *time.Time `json:"created_after,omitempty" example:"2024-01-01T00:00:00Z"`
+	CreatedBefore *time.Time `json:"created_before,omitempty" example:"2024-02-01T00:00:00Z"`
+}
+
+// TrackOrder assigns a track its position within an album
+type TrackOrder struct {
+	TrackID string
+	Number  int
+}
+
+// TrackOrderRequest lists all track IDs of an album in the desired order
+type TrackOrderRequest struct {
+	TrackIDs []string `json:"track_ids" validate:"required" example:"550e8400-e29b-41d4-a716-446655440000,660e9511-f30c-52e5-b827-557766551111"`
 }
 
 type AlbumDetail struct {
@@ -47,6 +128,55 @@ var AllowedGenres = []string{
 	"reggae", "country", "latin", "k-pop", "soundtrack", "lo-fi", "chanson",
 }
 
+// genreAliases maps alternative names to allowed genres. Keys are in
+// compactGenre form; spelling variants of a genre ("Hip Hop", "hip_hop",
+// "R&B", "R'n'B") need no entry since they compact to the genre itself.
+var genreAliases = map[string]string{
+	"ost":           "soundtrack",
+	"score":         "soundtrack",
+	"originalscore": "soundtrack",
+	"rhythmnblues":  "r-n-b",
+	"edm":           "electronic",
+	"electronica":   "electronic",
+	"heavymetal":    "metal",
+	"koreanpop":     "k-pop",
+}
+
+// compactGenre lowercases genre, spells "&" and a standalone "and" as "n"
+// and drops everything but letters and digits, so "Hip Hop", "hip_hop" and
+// "HIP-HOP" all become "hiphop" and "R&B", "R & B" and "r and b" become "rnb".
+func compactGenre(genre string) string {
+	words := strings.FieldsFunc(strings.ToLower(genre), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '&'
+	})
+	var b strings.Builder
+	for _, word := range words {
+		if word == "and" {
+			word = "n"
+		}
+		b.WriteString(strings.ReplaceAll(word, "&", "n"))
+	}
+	return b.String()
+}
+
+// NormalizeGenre maps genre, its spelling variants and known aliases to the
+// canonical allowed genre. It reports false when nothing matches.
+func NormalizeGenre(genre string) (string, bool) {
+	compact := compactGenre(genre)
+	if compact == "" {
+		return strings.TrimSpace(genre), false
+	}
+	for _, allowed := range AllowedGenres {
+		if compactGenre(allowed) == compact {
+			return allowed, true
+		}
+	}
+	if alias, ok := genreAliases[compact]; ok {
+		return alias, true
+	}
+	return strings.ToLower(strings.TrimSpace(genre)), false
+}
+
 // IsValidGenre checks if the genre is in the allowed list (case-insensitive)
 func IsValidGenre(genre string) bool {
 	genreLower := strings.ToLower(genre)
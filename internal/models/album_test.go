@@ -0,0 +1,49 @@
+package models
+
+import "testing"
+
+func TestNormalizeGenre(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"rock", "rock", true},
+		{"  Rock ", "rock", true},
+		{"Hip Hop", "hip-hop", true},
+		{"hiphop", "hip-hop", true},
+		{"hip_hop", "hip-hop", true},
+		{"R&B", "r-n-b", true},
+		{"r and b", "r-n-b", true},
+		{"R'n'B", "r-n-b", true},
+		{"Rhythm and Blues", "r-n-b", true},
+		{"K-Pop", "k-pop", true},
+		{"Korean Pop", "k-pop", true},
+		{"lofi", "lo-fi", true},
+		{"OST", "soundtrack", true},
+		{"Original Score", "soundtrack", true},
+		{"EDM", "electronic", true},
+		{"Electronica", "electronic", true},
+		{"Heavy Metal", "metal", true},
+		{"polka", "polka", false},
+		{"", "", false},
+		{"--", "--", false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeGenre(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeGenre(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGenreAliasesPointToAllowedGenres(t *testing.T) {
+	for alias, genre := range genreAliases {
+		if !IsValidGenre(genre) {
+			t.Errorf("alias %q maps to unknown genre %q", alias, genre)
+		}
+		if compactGenre(alias) != alias {
+			t.Errorf("alias key %q is not in compact form", alias)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+// MaxBulkTracks limits the number of audio files in a single bulk upload archive
+const MaxBulkTracks = 200
+
+// BulkTrackResult is a track created from an archive entry
+type BulkTrackResult struct {
+	Filename    string `json:"filename" example:"01 - Bohemian Rhapsody.mp3"`
+	TrackID     string `json:"track_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Title       string `json:"title" example:"Bohemian Rhapsody"`
+	TrackNumber *int   `json:"track_number,omitempty" example:"1"`
+}
+
+// BulkTrackFailure is an archive entry that could not be added
+type BulkTrackFailure struct {
+	Filename string `json:"filename" example:"02 - Broken.mp3"`
+	Error    string `json:"error" example:"invalid audio file: content does not match the declared type"`
+}
+
+// BulkTrackUploadResponse summarizes a bulk upload of tracks into an album
+type BulkTrackUploadResponse struct {
+	Succeeded []BulkTrackResult  `json:"succeeded"`
+	Failed    []BulkTrackFailure `json:"failed"`
+}
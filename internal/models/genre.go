@@ -0,0 +1,13 @@
+package models
+
+// GenreStats represents an allowed genre with the amount of content in it
+type GenreStats struct {
+	Genre       string `json:"genre" example:"rock"`
+	AlbumsCount int    `json:"albums_count" example:"4"`
+	TracksCount int    `json:"tracks_count" example:"37"`
+}
+
+// GenreListResponse represents response for listing genres
+type GenreListResponse struct {
+	Genres []GenreStats `json:"genres"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// Readiness statuses reported per dependency and overall
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+)
+
+// ReadinessResponse represents the result of the readiness check
+type ReadinessResponse struct {
+	Status string `json:"status" example:"ok"`
+	// Checks maps each dependency to "ok" or "fail"
+	Checks map[string]string `json:"checks"`
+}
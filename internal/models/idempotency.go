@@ -0,0 +1,8 @@
+package models
+
+// IdempotencyKey is a stored Idempotency-Key of a track upload
+type IdempotencyKey struct {
+	AlbumID string
+	// TrackID is nil while the first request with the key is still running
+	TrackID *string
+}
@@ -0,0 +1,67 @@
+package models
+
+// Import result statuses
+const (
+	ImportStatusImported = "imported"
+	ImportStatusSkipped  = "skipped"
+	ImportStatusFailed   = "failed"
+)
+
+// MaxImportAlbums limits the number of albums in a single import manifest
+const MaxImportAlbums = 100
+
+// ImportManifest describes albums and their tracks to import into the catalog
+type ImportManifest struct {
+	Albums []ImportAlbum `json:"albums"`
+}
+
+// ImportAlbum is an album entry of an import manifest. The cover comes either from
+// an object already uploaded under albums/{id}/ (cover_key) or from cover_url.
+type ImportAlbum struct {
+	// ID is required so a re-run can skip albums that were already imported
+	ID          string        `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Title       string        `json:"title" example:"A Night at the Opera"`
+	Artist      string        `json:"artist" example:"Queen"`
+	ReleaseDate string        `json:"release_date" example:"1975-11-21"`
+	Genre       string        `json:"genre" example:"rock"`
+	CoverKey    string        `json:"cover_key,omitempty" example:"albums/550e8400-e29b-41d4-a716-446655440000/cover.jpg"`
+	CoverURL    string        `json:"cover_url,omitempty" example:"https://example.com/covers/opera.jpg"`
+	Tracks      []ImportTrack `json:"tracks"`
+}
+
+// ImportTrack is a track entry of an import manifest. The audio comes either from
+// an object already uploaded under albums/{album_id}/ (audio_key) or from audio_url.
+type ImportTrack struct {
+	// ID is optional; a new one is generated when empty
+	ID              string  `json:"id,omitempty" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Title           string  `json:"title" example:"Bohemian Rhapsody"`
+	Artist          *string `json:"artist,omitempty" example:"Queen"`
+	DurationSeconds int     `json:"duration_seconds" example:"354"`
+	AudioKey        string  `json:"audio_key,omitempty" example:"albums/550e8400-e29b-41d4-a716-446655440000/bohemian-rhapsody.mp3"`
+	AudioURL        string  `json:"audio_url,omitempty" example:"https://example.com/audio/bohemian-rhapsody.mp3"`
+}
+
+// ImportTrackResult reports the outcome of one manifest track
+type ImportTrackResult struct {
+	ID     string `json:"id" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Title  string `json:"title" example:"Bohemian Rhapsody"`
+	Status string `json:"status" example:"imported"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportAlbumResult reports the outcome of one manifest album
+type ImportAlbumResult struct {
+	ID     string              `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Title  string              `json:"title" example:"A Night at the Opera"`
+	Status string              `json:"status" example:"imported"`
+	Error  string              `json:"error,omitempty"`
+	Tracks []ImportTrackResult `json:"tracks"`
+}
+
+// ImportResponse summarizes an import run
+type ImportResponse struct {
+	Imported int                 `json:"imported" example:"3"`
+	Skipped  int                 `json:"skipped" example:"1"`
+	Failed   int                 `json:"failed" example:"0"`
+	Albums   []ImportAlbumResult `json:"albums"`
+}
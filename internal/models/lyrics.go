@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Lyrics are the words of a track, either plain text or LRC
+type Lyrics struct {
+	TrackID   string    `json:"track_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Content   string    `json:"content" example:"[00:12.50]Is this the real life?\n[00:15.80]Is this just fantasy?"`
+	IsSynced  bool      `json:"is_synced" example:"true"` // Content has LRC timestamps
+	Language  *string   `json:"language,omitempty" example:"en"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-01-15T10:30:00Z"`
+}
+
+// LyricsRequest sets the lyrics of a track; is_synced is detected from the content
+type LyricsRequest struct {
+	Content  string  `json:"content" validate:"required" example:"[00:12.50]Is this the real life?\n[00:15.80]Is this just fantasy?"`
+	Language *string `json:"language,omitempty" example:"en"`
+}
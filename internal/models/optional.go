@@ -0,0 +1,16 @@
+package models
+
+import "encoding/json"
+
+// OptionalString is a JSON field that tells an omitted key from an explicit
+// null. Set is false when the key was omitted; Value is nil for null.
+type OptionalString struct {
+	Set   bool
+	Value *string
+}
+
+// UnmarshalJSON records that the key was present, including for null
+func (o *OptionalString) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUpdateProfileRequestNullVersusOmitted(t *testing.T) {
+	tests := []struct {
+		body      string
+		wantSet   bool
+		wantValue *string
+	}{
+		{`{}`, false, nil},
+		{`{"avatar_key":"avatars/1/a.jpg"}`, false, nil},
+		{`{"name":null}`, true, nil},
+		{`{"name":"Alice"}`, true, ptr("Alice")},
+	}
+	for _, tt := range tests {
+		var req UpdateProfileRequest
+		if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+			t.Fatalf("unmarshal %s: %v", tt.body, err)
+		}
+		if req.Name.Set != tt.wantSet {
+			t.Errorf("%s: Name.Set = %v, want %v", tt.body, req.Name.Set, tt.wantSet)
+		}
+		switch {
+		case tt.wantValue == nil && req.Name.Value != nil:
+			t.Errorf("%s: Name.Value = %q, want nil", tt.body, *req.Name.Value)
+		case tt.wantValue != nil && (req.Name.Value == nil || *req.Name.Value != *tt.wantValue):
+			t.Errorf("%s: Name.Value = %v, want %q", tt.body, req.Name.Value, *tt.wantValue)
+		}
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,8 @@
+package models
+
+// Pagination represents pagination metadata shared by list responses
+type Pagination struct {
+	Page  int `json:"page" example:"1"`
+	Limit int `json:"limit" example:"20"`
+	Total int `json:"total" example:"42"`
+}
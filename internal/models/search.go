@@ -0,0 +1,34 @@
+package models
+
+// Search result kinds accepted by the type filter
+const (
+	SearchTypeAll    = "all"
+	SearchTypeTracks = "tracks"
+	SearchTypeAlbums = "albums"
+)
+
+// MaxSearchQueryLength is the longest accepted search query (titles and artists are VARCHAR(255))
+const MaxSearchQueryLength = 255
+
+// Search facet dimensions
+const (
+	SearchFacetGenre  = "genre"
+	SearchFacetArtist = "artist"
+)
+
+// MaxSearchFacetValues is how many of the most frequent values a facet returns
+const MaxSearchFacetValues = 10
+
+// SearchResponse represents combined track and album search results
+type SearchResponse struct {
+	Tracks []TrackResponse `json:"tracks"`
+	Albums []AlbumResponse `json:"albums"`
+	Facets *SearchFacets   `json:"facets,omitempty"` // Only with facets=true
+}
+
+// SearchFacets counts all matching tracks and albums of the searched kinds,
+// not just the returned ones, by genre and by artist
+type SearchFacets struct {
+	Genre  map[string]int `json:"genre" example:"rock:12"`
+	Artist map[string]int `json:"artist" example:"Queen:5"`
+}
@@ -10,33 +10,55 @@ type Track struct {
 	Artist          *string   `json:"artist,omitempty" example:"Queen"` // If NULL, uses album artist
 	DurationSeconds int       `json:"duration_seconds" example:"354"`
 	AudioFileKey    string    `json:"-"` // Internal field for service layer, not exposed to frontend
+	CoverImageKey   *string   `json:"-"` // Track's own cover; NULL means the album cover is used
+	TrackNumber     *int      `json:"-"` // Position in the album on insert; nil appends the track
+	AudioSHA256     string    `json:"-"` // Hex SHA-256 of the audio file; empty when unknown
+	Renditions      map[string]string `json:"-"` // Extra MP3 renditions' keys by quality name; AudioFileKey is "high"
+	Bitrate         *int      `json:"bitrate,omitempty" example:"320000"` // Bits per second; nil when unknown
+	Format          *string   `json:"format,omitempty" example:"mp3"`     // ffprobe format name; nil when unknown
 	PlaysCount      int       `json:"plays_count" example:"1250"`
 	LikesCount      int       `json:"likes_count" example:"87"`
 	IsLiked         bool      `json:"is_liked,omitempty" example:"true"` // Only in API responses
+	Status          string    `json:"status,omitempty" example:"published"` // Track's own draft or published state
+	Public          bool      `json:"-"` // Both the track and its album are published
 	CreatedAt       time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`
 }
 
-// TrackResponse represents track data with album info for frontend compatibility
+// TrackResponse is the API representation of a track with its album info.
+// Audio storage keys never leave the service layer; clients stream via AudioURL.
 type TrackResponse struct {
 	ID              string    `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
 	Title           string    `json:"title" example:"Bohemian Rhapsody"`
 	ArtistName      string    `json:"artist_name" example:"Queen"`
 	AlbumID         string    `json:"album_id" example:"550e8400-e29b-41d4-a716-446655440001"`
 	AlbumTitle      string    `json:"album_title" example:"A Night at the Opera"`
-	CoverURL        string    `json:"cover_url" example:"https://s3.amazonaws.com/bucket/albums/550e8400-e29b-41d4-a716-446655440001/cover.jpg"`
+	CoverURL        string    `json:"cover_url" example:"/api/tracks/550e8400-e29b-41d4-a716-446655440000/cover"`
 	ImageKey        *string   `json:"image_key,omitempty" example:"albums/550e8400-e29b-41d4-a716-446655440001/cover.jpg"` // Cover image key for frontend
 	CoverImageKey   string    `json:"-"` // Internal field for service layer, not exposed to frontend
-	AudioURL        string    `json:"audio_url" example:"https://s3.amazonaws.com/bucket/albums/550e8400-e29b-41d4-a716-446655440001/550e8400-e29b-41d4-a716-446655440000.mp3"`
+	AudioURL        string    `json:"audio_url" example:"/api/tracks/550e8400-e29b-41d4-a716-446655440000/stream"`
 	AudioFileKey    string    `json:"-"` // Internal field for service layer, not exposed to frontend
 	ReleaseDate     string    `json:"release_date" example:"1975-11-21"`
 	Genre           string    `json:"genre" example:"rock"`
 	DurationSeconds int       `json:"duration_seconds" example:"354"`
+	Bitrate         *int      `json:"bitrate,omitempty" example:"320000"` // Bits per second; omitted when unknown
+	Format          *string   `json:"format,omitempty" example:"mp3"`     // Omitted when unknown
 	PlaysCount      int       `json:"plays_count" example:"1250"`
 	LikesCount      int       `json:"likes_count" example:"87"`
 	IsLiked         bool      `json:"is_liked,omitempty" example:"true"`
+	Status          string    `json:"status,omitempty" example:"published"` // Only in single-track and album detail reads
 	CreatedAt       time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`
 }
 
+// TrackStatusRequest drafts or publishes a single track
+type TrackStatusRequest struct {
+	Status string `json:"status" example:"draft"` // draft or published
+}
+
+// MoveTrackRequest moves a track to another album
+type MoveTrackRequest struct {
+	AlbumID string `json:"album_id" example:"550e8400-e29b-41d4-a716-446655440001"`
+}
+
 type TrackCreate struct {
 	AlbumID string  `json:"album_id" validate:"required" example:"550e8400-e29b-41d4-a716-446655440001"`
 	Title   string  `json:"title" validate:"required,min=1,max=255" example:"Bohemian Rhapsody"`
@@ -48,22 +70,76 @@ type TrackLike struct {
 	TrackID string `json:"track_id" example:"550e8400-e29b-41d4-a716-446655440000"`
 }
 
+// StreamURLResponse is a temporary direct link to a track's audio
+type StreamURLResponse struct {
+	URL       string    `json:"url" example:"http://localhost:9000/music-files/albums/550e8400-e29b-41d4-a716-446655440001/550e8400-e29b-41d4-a716-446655440000.mp3?X-Amz-Signature=..."`
+	ExpiresAt time.Time `json:"expires_at" example:"2024-01-15T10:45:00Z"`
+}
+
 type TrackStats struct {
 	PlaysCount int `json:"plays_count" example:"1250"`
 	LikesCount int `json:"likes_count" example:"87"`
 }
 
+// TopTrack is a track with the number of plays within an analytics window
+type TopTrack struct {
+	TrackResponse
+	// PeriodPlays counts plays recorded in play_history within the window;
+	// the embedded plays_count is the all-time total
+	PeriodPlays int `json:"period_plays" example:"42"`
+}
+
+// TopTracksResponse lists the most played tracks of a period, most played first
+type TopTracksResponse struct {
+	Period string     `json:"period" example:"week"`
+	Since  time.Time  `json:"since" example:"2024-01-08T10:30:00Z"`
+	Tracks []TopTrack `json:"tracks"`
+}
+
 // TrackListResponse represents response for listing tracks with pagination
 type TrackListResponse struct {
 	Tracks     []TrackResponse `json:"tracks"`
 	Pagination TrackPagination `json:"pagination"`
+	NextCursor string          `json:"next_cursor,omitempty" example:"MjAyNC0wMS0xNVQxMDozMDowMFp8NTUwZTg0MDAtZTI5Yi00MWQ0LWE3MTYtNDQ2NjU1NDQwMDAw"` // Pass as ?cursor= for the next page; omitted on the last page
 }
 
 // TrackPagination represents pagination metadata
-type TrackPagination struct {
-	Page  int `json:"page" example:"1"`
-	Limit int `json:"limit" example:"20"`
-	Total int `json:"total" example:"42"`
+type TrackPagination = Pagination
+
+// TrackFilter narrows track listings; empty fields are not applied
+type TrackFilter struct {
+	Genre  string
+	Artist string // Case-insensitive match against the track or album artist
+	// CreatedAfter and CreatedBefore bound the upload time, inclusive; nil leaves the range open
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// Play is a single play of a track; UserID is 0 for anonymous listeners
+type Play struct {
+	TrackID  string
+	UserID   int
+	PlayedAt time.Time
+}
+
+// TrackCursor is a keyset position in the track listing: the last track of the previous page
+type TrackCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// MaxArtistFilterLength is the longest accepted artist filter (artist columns are VARCHAR(255))
+const MaxArtistFilterLength = 255
+
+// Artist represents an artist name with the number of tracks attributed to it
+type Artist struct {
+	Name        string `json:"name" example:"Radiohead"`
+	TracksCount int    `json:"tracks_count" example:"12"`
+}
+
+// ArtistListResponse represents response for listing artists
+type ArtistListResponse struct {
+	Artists []Artist `json:"artists"`
 }
 
 // UserTracksResponse represents the response for user's tracks
@@ -77,7 +153,91 @@ type ToggleLikeResponse struct {
 	LikesCount int  `json:"likes_count" example:"88"`
 }
 
+// SetLikeRequest represents the desired like state for a track
+type SetLikeRequest struct {
+	Liked *bool `json:"liked" validate:"required" example:"true"`
+}
+
+// Next track recommendation reasons, from the most to the least specific
+const (
+	NextTrackReasonAlbum   = "album"   // Next track of the same album
+	NextTrackReasonGenre   = "genre"   // Same genre, not recently played by the user
+	NextTrackReasonPopular = "popular" // Most played track overall
+)
+
+// NextTrackResponse is the track recommended to play after another one
+type NextTrackResponse struct {
+	Reason string        `json:"reason" example:"album" enums:"album,genre,popular"`
+	Track  TrackResponse `json:"track"`
+}
+
+// MaxRadioExclude limits the number of track IDs a radio request may exclude
+const MaxRadioExclude = 200
+
+// RadioResponse is a shuffled batch of tracks of a genre
+type RadioResponse struct {
+	Genre  string          `json:"genre" example:"rock"`
+	Tracks []TrackResponse `json:"tracks"`
+}
+
+// MaxLikeCheckTracks limits the number of track IDs in a single like status check
+const MaxLikeCheckTracks = 100
+
+// CheckLikesRequest lists tracks to check the current user's like status for
+type CheckLikesRequest struct {
+	TrackIDs []string `json:"track_ids" validate:"required,max=100" example:"550e8400-e29b-41d4-a716-446655440000"`
+}
+
 // GenreFilter represents filter for content by genre
 type GenreFilter struct {
 	Genre string `json:"genre,omitempty" example:"rock"`
 }
+
+// LikedTrack is a track ID the user liked and when
+type LikedTrack struct {
+	TrackID string    `json:"track_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	LikedAt time.Time `json:"liked_at" example:"2024-01-15T10:30:00Z"`
+}
+
+// TrackLiker is the public profile of a user who liked a track
+type TrackLiker struct {
+	ID        int       `json:"id" example:"1"`
+	Name      *string   `json:"name,omitempty" example:"John Doe"`
+	AvatarKey *string   `json:"-"`
+	AvatarURL *string   `json:"avatar_url,omitempty" example:"/avatars/avatars/1/abc123.jpg"`
+	LikedAt   time.Time `json:"liked_at" example:"2024-01-15T10:30:00Z"`
+}
+
+// TrackLikersResponse represents a paginated list of users who liked a track
+type TrackLikersResponse struct {
+	Users      []TrackLiker `json:"users"`
+	Pagination Pagination   `json:"pagination"`
+}
+
+// StorageObject is an object in MinIO
+type StorageObject struct {
+	Bucket string `json:"bucket" example:"music-files"`
+	Key    string `json:"key" example:"albums/550e8400-e29b-41d4-a716-446655440001/550e8400-e29b-41d4-a716-446655440000.mp3"`
+}
+
+// MissingStorageObject is an object a track or album refers to that MinIO doesn't have
+type MissingStorageObject struct {
+	StorageObject
+	Kind    string `json:"kind" example:"track_audio"` // track_audio, track_rendition, track_cover or album_cover
+	OwnerID string `json:"owner_id" example:"550e8400-e29b-41d4-a716-446655440000"` // Track or album ID
+}
+
+// StorageReport lists the drift between the database and MinIO; nothing is deleted
+type StorageReport struct {
+	Checked  int                    `json:"checked" example:"120"` // Referenced objects checked
+	Failed   int                    `json:"failed" example:"0"`    // Objects that couldn't be checked for another reason than being missing
+	Missing  []MissingStorageObject `json:"missing"`
+	Orphaned []StorageObject        `json:"orphaned"` // Objects under albums/ no track or album refers to
+}
+
+// BackfillDurationsResponse reports the result of re-probing tracks with a zero duration or unknown bitrate or format
+type BackfillDurationsResponse struct {
+	Checked int `json:"checked" example:"5"`
+	Fixed   int `json:"fixed" example:"4"`
+	Failed  int `json:"failed" example:"1"`
+}
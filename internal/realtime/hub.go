@@ -0,0 +1,98 @@
+// Package realtime fans out track counter updates to live subscribers
+// within a single API process.
+package realtime
+
+import (
+	"errors"
+	"sync"
+
+	"koteyye_music_be/internal/models"
+)
+
+// ErrTooManySubscribers is returned when a track already has the maximum number of subscribers
+var ErrTooManySubscribers = errors.New("too many live subscribers for track")
+
+// Hub is an in-process pub/sub of track stats keyed by track ID. Updates
+// are not shared between API instances.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*Subscription]struct{}
+	maxPerTrack int
+}
+
+// Subscription receives stats of one track. Only the latest update is kept:
+// a slow reader skips intermediate values instead of blocking publishers.
+type Subscription struct {
+	// C delivers stats updates until the subscription is closed
+	C       <-chan models.TrackStats
+	updates chan models.TrackStats
+	hub     *Hub
+	trackID string
+	once    sync.Once
+}
+
+func NewHub(maxPerTrack int) *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[*Subscription]struct{}),
+		maxPerTrack: maxPerTrack,
+	}
+}
+
+// Subscribe registers a subscriber for trackID. The caller must Close it.
+func (h *Hub) Subscribe(trackID string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subscribers[trackID]
+	if len(subs) >= h.maxPerTrack {
+		return nil, ErrTooManySubscribers
+	}
+	if subs == nil {
+		subs = make(map[*Subscription]struct{})
+		h.subscribers[trackID] = subs
+	}
+
+	updates := make(chan models.TrackStats, 1)
+	sub := &Subscription{C: updates, updates: updates, hub: h, trackID: trackID}
+	subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// HasSubscribers reports whether anyone listens to trackID, so publishers
+// can skip loading stats nobody will receive
+func (h *Hub) HasSubscribers(trackID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers[trackID]) > 0
+}
+
+// Publish sends stats to every subscriber of trackID without blocking
+func (h *Hub) Publish(trackID string, stats models.TrackStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers[trackID] {
+		// Replace an unread update with the newer one
+		select {
+		case <-sub.updates:
+		default:
+		}
+		sub.updates <- stats
+	}
+}
+
+// Close unsubscribes and closes C. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		h := s.hub
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		subs := h.subscribers[s.trackID]
+		delete(subs, s)
+		if len(subs) == 0 {
+			delete(h.subscribers, s.trackID)
+		}
+		close(s.updates)
+	})
+}
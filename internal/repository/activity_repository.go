@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"koteyye_music_be/internal/models"
+)
+
+// activityFeedQuery normalizes registrations, uploads and album creations into one feed.
+// Deletions leave no rows behind, so they cannot be reported from these tables.
+const activityFeedQuery = `
+	SELECT type, occurred_at, actor_id, actor_name, target_type, target_id, target_name
+	FROM (
+		SELECT 'registration' AS type, u.created_at AS occurred_at,
+		       u.id AS actor_id, COALESCE(u.name, u.email) AS actor_name,
+		       'user' AS target_type, u.id::text AS target_id, COALESCE(u.name, u.email) AS target_name
+		FROM users u
+		WHERE u.role <> 'guest'
+		UNION ALL
+		SELECT 'track_upload', t.created_at,
+		       t.user_id, COALESCE(u.name, u.email),
+		       'track', t.id::text, t.title
+		FROM tracks t
+		LEFT JOIN users u ON u.id = t.user_id
+		UNION ALL
+		SELECT 'album_created', a.created_at,
+		       NULL, NULL,
+		       'album', a.id::text, a.title
+		FROM albums a
+	) feed
+	WHERE ($1 = '' OR type = $1)
+	  AND ($2::timestamp IS NULL OR occurred_at >= $2)
+	  AND ($3::timestamp IS NULL OR occurred_at < $3)
+`
+
+type ActivityRepository struct {
+	db *DB
+}
+
+func NewActivityRepository(db *DB) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+// List returns a page of the activity feed, newest first
+func (r *ActivityRepository) List(ctx context.Context, limit, offset int, filter models.ActivityFilter) ([]models.Activity, error) {
+	query := activityFeedQuery + `
+		ORDER BY occurred_at DESC, target_id DESC
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, filter.Type, filter.From, filter.To, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+	defer rows.Close()
+
+	activities := []models.Activity{}
+	for rows.Next() {
+		var activity models.Activity
+		err := rows.Scan(
+			&activity.Type,
+			&activity.OccurredAt,
+			&activity.ActorID,
+			&activity.ActorName,
+			&activity.TargetType,
+			&activity.TargetID,
+			&activity.TargetName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		activities = append(activities, activity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating activity: %w", err)
+	}
+
+	return activities, nil
+}
+
+// Count returns the number of feed entries matching the filter
+func (r *ActivityRepository) Count(ctx context.Context, filter models.ActivityFilter) (int, error) {
+	query := `SELECT COUNT(*) FROM (` + activityFeedQuery + `) filtered`
+
+	var count int
+	if err := r.db.Pool.QueryRow(ctx, query, filter.Type, filter.From, filter.To).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count activity: %w", err)
+	}
+
+	return count, nil
+}
@@ -2,10 +2,12 @@ package repository
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"koteyye_music_be/internal/models"
 )
@@ -18,6 +20,29 @@ func NewAlbumRepository(db *pgxpool.Pool) *AlbumRepository {
 	return &AlbumRepository{db: db}
 }
 
+// albumStatsJoin exposes the track count and total duration of album a's
+// published tracks as stats.track_count and stats.total_duration; both are 0
+// for an album without published tracks
+const albumStatsJoin = `
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS track_count, COALESCE(SUM(duration_seconds), 0) AS total_duration
+		FROM tracks
+		WHERE tracks.album_id = a.id AND tracks.status = 'published'
+	) stats ON true
+`
+
+// albumDraftStatsJoin is albumStatsJoin counting draft tracks too, for admin listings
+const albumDraftStatsJoin = `
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS track_count, COALESCE(SUM(duration_seconds), 0) AS total_duration
+		FROM tracks
+		WHERE tracks.album_id = a.id
+	) stats ON true
+`
+
+// publishedAlbum matches albums a that are visible to everyone
+const publishedAlbum = `a.status = 'published'`
+
 func (r *AlbumRepository) Create(ctx context.Context, album *models.Album) error {
 	query := `
 		INSERT INTO albums (id, title, artist, release_date, genre, cover_image_key, created_at, updated_at)
@@ -37,10 +62,16 @@ func (r *AlbumRepository) Create(ctx context.Context, album *models.Album) error
 }
 
 func (r *AlbumRepository) GetByID(ctx context.Context, id string) (*models.Album, error) {
+	// A malformed ID can't match any row; don't let Postgres turn it into a syntax error
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrAlbumNotFound
+	}
+
 	query := `
-		SELECT id, title, artist, release_date, genre, cover_image_key, created_at, updated_at
-		FROM albums
-		WHERE id = $1
+		SELECT a.id, a.title, a.artist, a.release_date, a.genre, a.cover_image_key, a.created_at, a.updated_at,
+			stats.track_count, stats.total_duration, a.is_featured, a.featured_order, a.status
+		FROM albums a` + albumStatsJoin + `
+		WHERE a.id = $1
 	`
 	var album models.Album
 	err := r.db.QueryRow(ctx, query, id).Scan(
@@ -52,22 +83,172 @@ func (r *AlbumRepository) GetByID(ctx context.Context, id string) (*models.Album
 		&album.CoverImageKey,
 		&album.CreatedAt,
 		&album.UpdatedAt,
+		&album.TrackCount,
+		&album.TotalDurationSeconds,
+		&album.IsFeatured,
+		&album.FeaturedOrder,
+		&album.Status,
 	)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAlbumNotFound
+		}
+		return nil, fmt.Errorf("failed to get album: %w", err)
 	}
 	return &album, nil
 }
 
-func (r *AlbumRepository) GetAll(ctx context.Context, limit, offset int, genreFilter string) ([]models.Album, error) {
+// FindByTitleArtist returns the oldest album with the given release date whose
+// title and artist match ignoring case and surrounding whitespace, or ErrAlbumNotFound
+func (r *AlbumRepository) FindByTitleArtist(ctx context.Context, title, artist string, releaseDate time.Time) (*models.Album, error) {
 	query := `
 		SELECT id, title, artist, release_date, genre, cover_image_key, created_at, updated_at
 		FROM albums
-		WHERE ($3 = '' OR genre = $3)
-		ORDER BY created_at DESC
+		WHERE LOWER(BTRIM(title)) = LOWER(BTRIM($1))
+		  AND LOWER(BTRIM(artist)) = LOWER(BTRIM($2))
+		  AND release_date = $3
+		ORDER BY created_at
+		LIMIT 1
+	`
+	var album models.Album
+	err := r.db.QueryRow(ctx, query, title, artist, releaseDate).Scan(
+		&album.ID,
+		&album.Title,
+		&album.Artist,
+		&album.ReleaseDate,
+		&album.Genre,
+		&album.CoverImageKey,
+		&album.CreatedAt,
+		&album.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAlbumNotFound
+		}
+		return nil, fmt.Errorf("failed to find album: %w", err)
+	}
+	return &album, nil
+}
+
+// Exists reports whether an album with the given ID exists
+func (r *AlbumRepository) Exists(ctx context.Context, id string) (bool, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return false, nil
+	}
+
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM albums WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check album existence: %w", err)
+	}
+	return exists, nil
+}
+
+// CreateWithTracks inserts an album and its tracks in one transaction,
+// so either the whole album is stored or nothing is
+func (r *AlbumRepository) CreateWithTracks(ctx context.Context, album *models.Album, tracks []models.Track) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO albums (id, title, artist, release_date, genre, cover_image_key, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, album.ID, album.Title, album.Artist, album.ReleaseDate, album.Genre, album.CoverImageKey, album.CreatedAt, album.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create album: %w", err)
+	}
+
+	for i, track := range tracks {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO tracks (id, user_id, album_id, title, artist, duration_seconds, audio_file_key, cover_image_key, track_number, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, track.ID, track.UserID, album.ID, track.Title, track.Artist, track.DurationSeconds, track.AudioFileKey, track.CoverImageKey, i+1, track.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create track %s: %w", track.ID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UpdateTrackOrder sets the track numbers of an album in one transaction.
+// order must list every track of the album exactly once; otherwise nothing
+// is changed and ErrTrackOrderMismatch is returned.
+func (r *AlbumRepository) UpdateTrackOrder(ctx context.Context, albumID string, order []models.TrackOrder) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the album's tracks so concurrent uploads or deletes can't change the set
+	rows, err := tx.Query(ctx, `SELECT id FROM tracks WHERE album_id = $1 FOR UPDATE`, albumID)
+	if err != nil {
+		return fmt.Errorf("failed to lock album tracks: %w", err)
+	}
+	albumTracks := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan track id: %w", err)
+		}
+		albumTracks[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating album tracks: %w", err)
+	}
+
+	if len(order) != len(albumTracks) {
+		return fmt.Errorf("%w: album has %d tracks, got %d", ErrTrackOrderMismatch, len(albumTracks), len(order))
+	}
+	for _, item := range order {
+		if !albumTracks[item.TrackID] {
+			return fmt.Errorf("%w: track %s does not belong to the album", ErrTrackOrderMismatch, item.TrackID)
+		}
+	}
+
+	for _, item := range order {
+		if _, err := tx.Exec(ctx, `UPDATE tracks SET track_number = $1 WHERE id = $2`, item.Number, item.TrackID); err != nil {
+			return fmt.Errorf("failed to update track number: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetAll returns a page of albums matching the filter, newest first. Drafts
+// are only listed, with their draft tracks counted, when filter.IncludeDrafts is set.
+func (r *AlbumRepository) GetAll(ctx context.Context, limit, offset int, filter models.AlbumFilter) ([]models.Album, error) {
+	stats := albumStatsJoin
+	if filter.IncludeDrafts {
+		stats = albumDraftStatsJoin
+	}
+
+	query := `
+		SELECT a.id, a.title, a.artist, a.release_date, a.genre, a.cover_image_key, a.created_at, a.updated_at,
+			stats.track_count, stats.total_duration, a.is_featured, a.featured_order, a.status
+		FROM albums a` + stats + `
+		WHERE ($3 = '' OR a.genre = $3)
+		  AND ($4 = 0 OR (a.release_date >= make_date($4, 1, 1) AND a.release_date < make_date($4 + 1, 1, 1)))
+		  AND ($5::boolean IS NULL OR a.is_featured = $5)
+		  AND ($6 OR ` + publishedAlbum + `)
+		  AND ($7 = '' OR a.status = $7)
+		  AND a.created_at BETWEEN COALESCE($8::timestamp, '-infinity') AND COALESCE($9::timestamp, 'infinity')
+		ORDER BY a.created_at DESC
 		LIMIT $1 OFFSET $2
 	`
-	rows, err := r.db.Query(ctx, query, limit, offset, genreFilter)
+	rows, err := r.db.Query(ctx, query, limit, offset, filter.Genre, filter.Year, filter.Featured, filter.IncludeDrafts, filter.Status, filter.CreatedAfter, filter.CreatedBefore)
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +266,11 @@ func (r *AlbumRepository) GetAll(ctx context.Context, limit, offset int, genreFi
 			&album.CoverImageKey,
 			&album.CreatedAt,
 			&album.UpdatedAt,
+			&album.TrackCount,
+			&album.TotalDurationSeconds,
+			&album.IsFeatured,
+			&album.FeaturedOrder,
+			&album.Status,
 		)
 		if err != nil {
 			return nil, err
@@ -94,7 +280,77 @@ func (r *AlbumRepository) GetAll(ctx context.Context, limit, offset int, genreFi
 	return albums, rows.Err()
 }
 
+// GetAlbumsByArtist returns a page of albums whose artist matches ignoring case,
+// newest release first, and the total number of such albums
+func (r *AlbumRepository) GetAlbumsByArtist(ctx context.Context, artist string, limit, offset int) ([]models.Album, int, error) {
+	var total int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM albums WHERE LOWER(artist) = LOWER($1) AND status = 'published'`, artist).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count artist albums: %w", err)
+	}
+
+	query := `
+		SELECT a.id, a.title, a.artist, a.release_date, a.genre, a.cover_image_key, a.created_at, a.updated_at,
+			stats.track_count, stats.total_duration, a.is_featured, a.featured_order, a.status
+		FROM albums a` + albumStatsJoin + `
+		WHERE LOWER(a.artist) = LOWER($1) AND ` + publishedAlbum + `
+		ORDER BY a.release_date DESC, a.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, artist, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get artist albums: %w", err)
+	}
+	defer rows.Close()
+
+	var albums []models.Album
+	for rows.Next() {
+		var album models.Album
+		err := rows.Scan(
+			&album.ID,
+			&album.Title,
+			&album.Artist,
+			&album.ReleaseDate,
+			&album.Genre,
+			&album.CoverImageKey,
+			&album.CreatedAt,
+			&album.UpdatedAt,
+			&album.TrackCount,
+			&album.TotalDurationSeconds,
+			&album.IsFeatured,
+			&album.FeaturedOrder,
+			&album.Status,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		albums = append(albums, album)
+	}
+	return albums, total, rows.Err()
+}
+
+// CountAll returns the number of albums matching the filter
+func (r *AlbumRepository) CountAll(ctx context.Context, filter models.AlbumFilter) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM albums
+		WHERE ($1 = '' OR genre = $1)
+		  AND ($2 = 0 OR (release_date >= make_date($2, 1, 1) AND release_date < make_date($2 + 1, 1, 1)))
+		  AND ($3::boolean IS NULL OR is_featured = $3)
+		  AND ($4 OR status = 'published')
+		  AND ($5 = '' OR status = $5)
+		  AND created_at BETWEEN COALESCE($6::timestamp, '-infinity') AND COALESCE($7::timestamp, 'infinity')
+	`
+	var count int
+	err := r.db.QueryRow(ctx, query, filter.Genre, filter.Year, filter.Featured, filter.IncludeDrafts, filter.Status, filter.CreatedAfter, filter.CreatedBefore).Scan(&count)
+	return count, err
+}
+
 func (r *AlbumRepository) Delete(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrAlbumNotFound
+	}
+
 	query := `DELETE FROM albums WHERE id = $1`
 	result, err := r.db.Exec(ctx, query, id)
 	if err != nil {
@@ -103,34 +359,241 @@ func (r *AlbumRepository) Delete(ctx context.Context, id string) error {
 
 	rowsAffected := result.RowsAffected()
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrAlbumNotFound
+	}
+
+	return nil
+}
+
+// UpdateCoverKey sets a new cover key for an album and returns the previous one.
+// The old key is read under a row lock so concurrent updates can't lose track of it.
+func (r *AlbumRepository) UpdateCoverKey(ctx context.Context, id, coverKey string) (string, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", ErrAlbumNotFound
 	}
 
+	query := `
+		UPDATE albums a
+		SET cover_image_key = $2
+		FROM (SELECT id, cover_image_key FROM albums WHERE id = $1 FOR UPDATE) old
+		WHERE a.id = old.id
+		RETURNING old.cover_image_key
+	`
+	var oldKey string
+	err := r.db.QueryRow(ctx, query, id, coverKey).Scan(&oldKey)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrAlbumNotFound
+		}
+		return "", err
+	}
+	return oldKey, nil
+}
+
+// SetFeatured adds an album to or removes it from the featured section.
+// order sets the position within the section; when nil a newly featured album
+// goes after the others and an already featured one keeps its place.
+func (r *AlbumRepository) SetFeatured(ctx context.Context, albumID string, featured bool, order *int) error {
+	if _, err := uuid.Parse(albumID); err != nil {
+		return ErrAlbumNotFound
+	}
+
+	query := `
+		UPDATE albums
+		SET is_featured = $2,
+			featured_order = CASE
+				WHEN NOT $2 THEN NULL
+				WHEN $3::integer IS NOT NULL THEN $3
+				WHEN is_featured THEN featured_order
+				ELSE (SELECT COALESCE(MAX(featured_order), 0) + 1 FROM albums WHERE is_featured)
+			END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(ctx, query, albumID, featured, order)
+	if err != nil {
+		return fmt.Errorf("failed to set album featured flag: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAlbumNotFound
+	}
 	return nil
 }
 
-func (r *AlbumRepository) GetAlbumWithTracks(ctx context.Context, albumID string) (*models.AlbumDetail, error) {
+// Publish makes a draft album public together with its draft tracks and
+// returns the number of tracks that were published
+func (r *AlbumRepository) Publish(ctx context.Context, albumID string) (int, error) {
+	if _, err := uuid.Parse(albumID); err != nil {
+		return 0, ErrAlbumNotFound
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `UPDATE albums SET status = 'published', updated_at = CURRENT_TIMESTAMP WHERE id = $1`, albumID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish album: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return 0, ErrAlbumNotFound
+	}
+
+	result, err = tx.Exec(ctx, `UPDATE tracks SET status = 'published' WHERE album_id = $1 AND status = 'draft'`, albumID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish album tracks: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// GetFeatured returns up to limit featured albums ordered by featured_order
+func (r *AlbumRepository) GetFeatured(ctx context.Context, limit int) ([]models.Album, error) {
+	query := `
+		SELECT a.id, a.title, a.artist, a.release_date, a.genre, a.cover_image_key, a.created_at, a.updated_at,
+			stats.track_count, stats.total_duration, a.is_featured, a.featured_order, a.status
+		FROM albums a` + albumStatsJoin + `
+		WHERE a.is_featured AND ` + publishedAlbum + `
+		ORDER BY a.featured_order ASC NULLS LAST, a.created_at DESC
+		LIMIT $1
+	`
+	rows, err := r.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get featured albums: %w", err)
+	}
+	defer rows.Close()
+
+	var albums []models.Album
+	for rows.Next() {
+		var album models.Album
+		err := rows.Scan(
+			&album.ID,
+			&album.Title,
+			&album.Artist,
+			&album.ReleaseDate,
+			&album.Genre,
+			&album.CoverImageKey,
+			&album.CreatedAt,
+			&album.UpdatedAt,
+			&album.TrackCount,
+			&album.TotalDurationSeconds,
+			&album.IsFeatured,
+			&album.FeaturedOrder,
+			&album.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+		albums = append(albums, album)
+	}
+	return albums, rows.Err()
+}
+
+// GetSimilarAlbums returns up to limit albums sharing the artist or genre of
+// albumID, same-artist albums first, each group newest release first
+func (r *AlbumRepository) GetSimilarAlbums(ctx context.Context, albumID string, limit int) ([]models.Album, error) {
+	query := `
+		WITH seed AS (
+			SELECT id, LOWER(BTRIM(artist)) AS artist, genre FROM albums WHERE id = $1
+		)
+		SELECT a.id, a.title, a.artist, a.release_date, a.genre, a.cover_image_key, a.created_at, a.updated_at,
+			stats.track_count, stats.total_duration, a.is_featured, a.featured_order, a.status
+		FROM albums a
+		JOIN seed ON a.id <> seed.id
+			AND (LOWER(BTRIM(a.artist)) = seed.artist OR (seed.genre <> '' AND a.genre = seed.genre))` + albumStatsJoin + `
+		WHERE ` + publishedAlbum + `
+		ORDER BY (LOWER(BTRIM(a.artist)) = seed.artist) DESC, a.release_date DESC, a.created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, albumID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get similar albums: %w", err)
+	}
+	defer rows.Close()
+
+	var albums []models.Album
+	for rows.Next() {
+		var album models.Album
+		err := rows.Scan(
+			&album.ID,
+			&album.Title,
+			&album.Artist,
+			&album.ReleaseDate,
+			&album.Genre,
+			&album.CoverImageKey,
+			&album.CreatedAt,
+			&album.UpdatedAt,
+			&album.TrackCount,
+			&album.TotalDurationSeconds,
+			&album.IsFeatured,
+			&album.FeaturedOrder,
+			&album.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+		albums = append(albums, album)
+	}
+	return albums, rows.Err()
+}
+
+// GetAlbumWithTracks returns an album with its tracks. When userID is non-zero
+// each track's is_liked reflects that user's likes. Without includeDrafts a
+// draft album is reported as ErrAlbumNotFound and draft tracks are left out.
+func (r *AlbumRepository) GetAlbumWithTracks(ctx context.Context, albumID string, userID int, includeDrafts bool) (*models.AlbumDetail, error) {
 	// Get album info
 	album, err := r.GetByID(ctx, albumID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get album: %w", err)
+		return nil, err
+	}
+	if !includeDrafts && album.Status != models.StatusPublished {
+		return nil, ErrAlbumNotFound
 	}
 
 	// Get tracks for this album
-	tracksQuery := `
-		SELECT 
-			t.id, t.title, t.duration_seconds, t.plays_count, t.likes_count, t.audio_file_key,
-			a.id as album_id, a.title as album_title, a.cover_image_key,
-			COALESCE(t.artist, a.artist) as final_artist,
-			a.release_date, a.genre,
-			t.created_at, false as is_liked
-		FROM tracks t
-		JOIN albums a ON t.album_id = a.id
-		WHERE t.album_id = $1
-		ORDER BY t.created_at ASC
-	`
+	var tracksQuery string
+	var args []interface{}
 
-	rows, err := r.db.Query(ctx, tracksQuery, albumID)
+	if userID != 0 {
+		// For authenticated users, include like status
+		tracksQuery = `
+			SELECT 
+				t.id, t.title, t.duration_seconds, t.bitrate, t.format, t.plays_count, t.likes_count, t.audio_file_key,
+				a.id as album_id, a.title as album_title, COALESCE(t.cover_image_key, a.cover_image_key) as cover_image_key,
+				COALESCE(t.artist, a.artist) as final_artist,
+				a.release_date, a.genre,
+				t.created_at,
+				EXISTS(SELECT 1 FROM track_likes tl WHERE tl.track_id = t.id AND tl.user_id = $2) as is_liked,
+				t.status
+			FROM tracks t
+			JOIN albums a ON t.album_id = a.id
+			WHERE t.album_id = $1 AND ($3 OR t.status = 'published')
+			ORDER BY t.track_number ASC NULLS LAST, t.created_at ASC
+		`
+		args = []interface{}{albumID, userID, includeDrafts}
+	} else {
+		// For unauthenticated users, no like status
+		tracksQuery = `
+			SELECT 
+				t.id, t.title, t.duration_seconds, t.bitrate, t.format, t.plays_count, t.likes_count, t.audio_file_key,
+				a.id as album_id, a.title as album_title, COALESCE(t.cover_image_key, a.cover_image_key) as cover_image_key,
+				COALESCE(t.artist, a.artist) as final_artist,
+				a.release_date, a.genre,
+				t.created_at, false as is_liked, t.status
+			FROM tracks t
+			JOIN albums a ON t.album_id = a.id
+			WHERE t.album_id = $1 AND ($2 OR t.status = 'published')
+			ORDER BY t.track_number ASC NULLS LAST, t.created_at ASC
+		`
+		args = []interface{}{albumID, includeDrafts}
+	}
+
+	rows, err := r.db.Query(ctx, tracksQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tracks: %w", err)
 	}
@@ -145,6 +608,8 @@ func (r *AlbumRepository) GetAlbumWithTracks(ctx context.Context, albumID string
 			&track.ID,
 			&track.Title,
 			&track.DurationSeconds,
+			&track.Bitrate,
+			&track.Format,
 			&track.PlaysCount,
 			&track.LikesCount,
 			&track.AudioFileKey,
@@ -156,6 +621,7 @@ func (r *AlbumRepository) GetAlbumWithTracks(ctx context.Context, albumID string
 			&track.Genre,
 			&track.CreatedAt,
 			&track.IsLiked,
+			&track.Status,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan track: %w", err)
@@ -167,17 +633,30 @@ func (r *AlbumRepository) GetAlbumWithTracks(ctx context.Context, albumID string
 		tracks = append(tracks, track)
 	}
 
+	// The album stats only cover published tracks; count the drafts listed too
+	if includeDrafts {
+		album.TrackCount, album.TotalDurationSeconds = len(tracks), 0
+		for _, track := range tracks {
+			album.TotalDurationSeconds += track.DurationSeconds
+		}
+	}
+
 	// Convert release date to year
 	year := album.ReleaseDate.Year()
 
 	albumResponse := models.AlbumResponse{
-		ID:          album.ID,
-		Title:       album.Title,
-		Artist:      album.Artist,
-		ReleaseDate: album.ReleaseDate.Format("2006-01-02"),
-		Genre:       album.Genre,
-		Year:        year,
-		CreatedAt:   album.CreatedAt,
+		ID:                   album.ID,
+		Title:                album.Title,
+		Artist:               album.Artist,
+		ReleaseDate:          album.ReleaseDate.Format("2006-01-02"),
+		Genre:                album.Genre,
+		Year:                 year,
+		TrackCount:           album.TrackCount,
+		TotalDurationSeconds: album.TotalDurationSeconds,
+		IsFeatured:           album.IsFeatured,
+		FeaturedOrder:        album.FeaturedOrder,
+		Status:               album.Status,
+		CreatedAt:            album.CreatedAt,
 	}
 
 	return &models.AlbumDetail{
@@ -186,8 +665,176 @@ func (r *AlbumRepository) GetAlbumWithTracks(ctx context.Context, albumID string
 	}, nil
 }
 
+// GetRecentlyPlayed returns albums the user played tracks from, ordered by
+// the latest play within each album
+func (r *AlbumRepository) GetRecentlyPlayed(ctx context.Context, userID, limit, offset int) ([]models.Album, error) {
+	query := `
+		SELECT a.id, a.title, a.artist, a.release_date, a.genre, a.cover_image_key, a.created_at, a.updated_at,
+			stats.track_count, stats.total_duration, a.is_featured, a.featured_order, a.status
+		FROM albums a
+		JOIN (
+			SELECT t.album_id, MAX(ph.played_at) AS last_played_at
+			FROM play_history ph
+			JOIN tracks t ON t.id = ph.track_id
+			WHERE ph.user_id = $1
+			GROUP BY t.album_id
+		) recent ON recent.album_id = a.id` + albumStatsJoin + `
+		WHERE ` + publishedAlbum + `
+		ORDER BY recent.last_played_at DESC, a.id
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var albums []models.Album
+	for rows.Next() {
+		var album models.Album
+		err := rows.Scan(
+			&album.ID,
+			&album.Title,
+			&album.Artist,
+			&album.ReleaseDate,
+			&album.Genre,
+			&album.CoverImageKey,
+			&album.CreatedAt,
+			&album.UpdatedAt,
+			&album.TrackCount,
+			&album.TotalDurationSeconds,
+			&album.IsFeatured,
+			&album.FeaturedOrder,
+			&album.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+		albums = append(albums, album)
+	}
+	return albums, rows.Err()
+}
+
+// CountRecentlyPlayed returns the number of distinct albums the user played tracks from
+func (r *AlbumRepository) CountRecentlyPlayed(ctx context.Context, userID int) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT t.album_id)
+		FROM play_history ph
+		JOIN tracks t ON t.id = ph.track_id
+		JOIN albums a ON a.id = t.album_id
+		WHERE ph.user_id = $1 AND ` + publishedAlbum + `
+	`
+	var count int
+	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+// Search returns albums whose title or artist contains query (case-insensitive).
+// Exact title matches rank first, then title prefixes, then artist prefixes, then newest first.
+func (r *AlbumRepository) Search(ctx context.Context, query string, limit int) ([]models.Album, error) {
+	contains, prefix := searchPatterns(query)
+	sqlQuery := `
+		SELECT a.id, a.title, a.artist, a.release_date, a.genre, a.cover_image_key, a.created_at, a.updated_at,
+			stats.track_count, stats.total_duration, a.is_featured, a.featured_order, a.status
+		FROM albums a` + albumStatsJoin + `
+		WHERE ` + albumSearchMatch + `
+		ORDER BY
+			CASE
+				WHEN LOWER(a.title) = LOWER($2) THEN 0
+				WHEN a.title ILIKE $3 THEN 1
+				WHEN a.artist ILIKE $3 THEN 2
+				ELSE 3
+			END,
+			a.release_date DESC, a.title
+		LIMIT $4
+	`
+	rows, err := r.db.Query(ctx, sqlQuery, contains, query, prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search albums: %w", err)
+	}
+	defer rows.Close()
+
+	var albums []models.Album
+	for rows.Next() {
+		var album models.Album
+		err := rows.Scan(
+			&album.ID,
+			&album.Title,
+			&album.Artist,
+			&album.ReleaseDate,
+			&album.Genre,
+			&album.CoverImageKey,
+			&album.CreatedAt,
+			&album.UpdatedAt,
+			&album.TrackCount,
+			&album.TotalDurationSeconds,
+			&album.IsFeatured,
+			&album.FeaturedOrder,
+			&album.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+		albums = append(albums, album)
+	}
+	return albums, rows.Err()
+}
+
+// albumSearchMatch selects published albums whose title or artist matches the ILIKE pattern $1
+const albumSearchMatch = `(a.title ILIKE $1 OR a.artist ILIKE $1) AND ` + publishedAlbum
+
+// SearchFacetCounts counts the albums Search matches for query by facet
+// (genre or artist), returning the limit most frequent values
+func (r *AlbumRepository) SearchFacetCounts(ctx context.Context, query, facet string, limit int) (map[string]int, error) {
+	sqlQuery, err := facetQuery(albumFacetColumns, facet, "albums a", albumSearchMatch)
+	if err != nil {
+		return nil, err
+	}
+	contains, _ := searchPatterns(query)
+	return scanFacetCounts(r.db.Query(ctx, sqlQuery, contains, limit))
+}
+
+// CountAlbumsByGenre returns the number of albums per genre; genres without albums are absent
+func (r *AlbumRepository) CountAlbumsByGenre(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.Query(ctx, `SELECT genre, COUNT(*) FROM albums WHERE status = 'published' GROUP BY genre`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count albums by genre: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var genre string
+		var count int
+		if err := rows.Scan(&genre, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan genre count: %w", err)
+		}
+		counts[genre] = count
+	}
+	return counts, rows.Err()
+}
+
 func (r *AlbumRepository) Count(ctx context.Context) (int, error) {
 	var count int
 	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM albums").Scan(&count)
 	return count, err
 }
+
+// ListCoverKeys returns the ID and cover key of every album, drafts included
+func (r *AlbumRepository) ListCoverKeys(ctx context.Context) ([]models.Album, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, cover_image_key FROM albums ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list album cover keys: %w", err)
+	}
+	defer rows.Close()
+
+	var albums []models.Album
+	for rows.Next() {
+		var album models.Album
+		if err := rows.Scan(&album.ID, &album.CoverImageKey); err != nil {
+			return nil, fmt.Errorf("failed to scan album: %w", err)
+		}
+		albums = append(albums, album)
+	}
+	return albums, rows.Err()
+}
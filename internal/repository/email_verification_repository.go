@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type EmailVerificationRepository struct {
+	db *DB
+}
+
+func NewEmailVerificationRepository(db *DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+// Replace stores a hashed verification token for a user, invalidating the user's earlier tokens
+func (r *EmailVerificationRepository) Replace(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM email_verification_tokens WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete old verification tokens: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO email_verification_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, tokenHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create verification token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Verify consumes a valid verification token and marks its user's email verified in one transaction.
+// It returns the user, or ErrVerificationTokenNotFound if the token cannot be used.
+func (r *EmailVerificationRepository) Verify(ctx context.Context, tokenHash string) (int, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	err = tx.QueryRow(ctx, `
+		DELETE FROM email_verification_tokens
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING user_id
+	`, tokenHash).Scan(&userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrVerificationTokenNotFound
+		}
+		return 0, fmt.Errorf("failed to consume verification token: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET email_verified = true WHERE id = $1`, userID); err != nil {
+		return 0, fmt.Errorf("failed to mark email verified: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is wrapped by every "row does not exist" error below, so callers
+// can tell a missing resource from a failing database with errors.Is
+var ErrNotFound = errors.New("not found")
+
+var (
+	// ErrTrackNotFound is returned when a track does not exist
+	ErrTrackNotFound = fmt.Errorf("track %w", ErrNotFound)
+	// ErrUserNotFound is returned when a user does not exist
+	ErrUserNotFound = fmt.Errorf("user %w", ErrNotFound)
+	// ErrAlbumNotFound is returned when an album does not exist
+	ErrAlbumNotFound = fmt.Errorf("album %w", ErrNotFound)
+	// ErrRefreshTokenNotFound is returned when a refresh token is unknown, expired or already used
+	ErrRefreshTokenNotFound = fmt.Errorf("refresh token %w", ErrNotFound)
+	// ErrLyricsNotFound is returned when a track has no lyrics
+	ErrLyricsNotFound = fmt.Errorf("lyrics %w", ErrNotFound)
+	// ErrVerificationTokenNotFound is returned when an email verification token is unknown, expired or already used
+	ErrVerificationTokenNotFound = fmt.Errorf("verification token %w", ErrNotFound)
+)
+
+// ErrTrackOrderMismatch is returned when a new track order doesn't list exactly the album's tracks
+var ErrTrackOrderMismatch = errors.New("track order must list every album track exactly once")
+
+// ErrDuplicateTrack is returned when an album already has a track with the same audio content
+var ErrDuplicateTrack = errors.New("album already contains this audio file")
+
+// ErrInvalidRole is returned for a role that isn't one of models.UserRoles
+var ErrInvalidRole = errors.New("invalid role")
+
+// ErrOAuthAccountLinked is returned when an OAuth provider account is already linked to another user
+var ErrOAuthAccountLinked = errors.New("OAuth account is already linked to another user")
+
+// ErrLastAdmin is returned when a role change would leave no admins
+var ErrLastAdmin = errors.New("cannot demote the last remaining admin")
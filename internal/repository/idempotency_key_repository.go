@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"koteyye_music_be/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// reserveAttempts bounds how often Reserve retries a key that vanished under it
+const reserveAttempts = 3
+
+type IdempotencyKeyRepository struct {
+	db *DB
+}
+
+func NewIdempotencyKeyRepository(db *DB) *IdempotencyKeyRepository {
+	return &IdempotencyKeyRepository{db: db}
+}
+
+// Reserve claims an upload idempotency key for a user until expiresAt.
+// It returns nil if the key was free, otherwise the key stored by the earlier request.
+// Expired keys are swept on every call, so they never block a new request.
+func (r *IdempotencyKeyRepository) Reserve(ctx context.Context, userID int, key, albumID string, expiresAt time.Time) (*models.IdempotencyKey, error) {
+	if _, err := r.db.Pool.Exec(ctx, `DELETE FROM upload_idempotency_keys WHERE expires_at < NOW()`); err != nil {
+		return nil, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	// The conflicting row can be released or swept before it is read back;
+	// the key is free again then, so try to claim it once more
+	for attempt := 1; ; attempt++ {
+		result, err := r.db.Pool.Exec(ctx, `
+			INSERT INTO upload_idempotency_keys (user_id, idempotency_key, album_id, expires_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, idempotency_key) DO NOTHING
+		`, userID, key, albumID, expiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+		if result.RowsAffected() == 1 {
+			return nil, nil
+		}
+
+		var existing models.IdempotencyKey
+		err = r.db.Pool.QueryRow(ctx, `
+			SELECT album_id, track_id
+			FROM upload_idempotency_keys
+			WHERE user_id = $1 AND idempotency_key = $2
+		`, userID, key).Scan(&existing.AlbumID, &existing.TrackID)
+		if err == pgx.ErrNoRows && attempt < reserveAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+
+		return &existing, nil
+	}
+}
+
+// Complete records the track created by the request that reserved the key
+func (r *IdempotencyKeyRepository) Complete(ctx context.Context, userID int, key, trackID string) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		UPDATE upload_idempotency_keys
+		SET track_id = $3
+		WHERE user_id = $1 AND idempotency_key = $2
+	`, userID, key, trackID)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// Release frees a reserved key whose request failed, so the client can retry with it
+func (r *IdempotencyKeyRepository) Release(ctx context.Context, userID int, key string) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		DELETE FROM upload_idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND track_id IS NULL
+	`, userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type RefreshTokenRepository struct {
+	db *DB
+}
+
+func NewRefreshTokenRepository(db *DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// Create stores a hashed refresh token for a user
+func (r *RefreshTokenRepository) Create(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, userID, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// Rotate consumes a valid refresh token and stores its replacement in one transaction.
+// It returns the owner of the token, or ErrRefreshTokenNotFound if the token cannot be used.
+func (r *RefreshTokenRepository) Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (int, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	err = tx.QueryRow(ctx, `
+		DELETE FROM refresh_tokens
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING user_id
+	`, oldHash).Scan(&userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrRefreshTokenNotFound
+		}
+		return 0, fmt.Errorf("failed to consume refresh token: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, newHash, expiresAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to store rotated refresh token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return userID, nil
+}
+
+// Delete revokes a refresh token
+func (r *RefreshTokenRepository) Delete(ctx context.Context, tokenHash string) error {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete refresh token: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrRefreshTokenNotFound
+	}
+
+	return nil
+}
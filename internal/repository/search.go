@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"koteyye_music_be/internal/models"
+)
+
+// likeEscaper escapes LIKE wildcards so user input is matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// searchPatterns returns the "contains" and "starts with" ILIKE patterns for a search query
+func searchPatterns(query string) (contains, prefix string) {
+	escaped := likeEscaper.Replace(query)
+	return "%" + escaped + "%", escaped + "%"
+}
+
+// facetQuery builds a query counting rows of from matching where by the
+// column expression of facet, most frequent first. The search pattern is $1
+// and the limit $2.
+func facetQuery(columns map[string]string, facet, from, where string) (string, error) {
+	column, ok := columns[facet]
+	if !ok {
+		return "", fmt.Errorf("unknown search facet %q", facet)
+	}
+	return `SELECT ` + column + `, COUNT(*) FROM ` + from + ` WHERE ` + where +
+		` GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT $2`, nil
+}
+
+// trackFacetColumns and albumFacetColumns are the expressions search facets group by
+var (
+	trackFacetColumns = map[string]string{models.SearchFacetGenre: "a.genre", models.SearchFacetArtist: "COALESCE(t.artist, a.artist)"}
+	albumFacetColumns = map[string]string{models.SearchFacetGenre: "a.genre", models.SearchFacetArtist: "a.artist"}
+)
+
+// scanFacetCounts reads the value and count rows of a facet query
+func scanFacetCounts(rows pgx.Rows, err error) (map[string]int, error) {
+	if err != nil {
+		return nil, fmt.Errorf("failed to count search facet: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var value string
+		var count int
+		if err := rows.Scan(&value, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan search facet: %w", err)
+		}
+		counts[value] = count
+	}
+	return counts, rows.Err()
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type TrackRepository struct {
@@ -19,39 +21,104 @@ func NewTrackRepository(db *DB) *TrackRepository {
 	return &TrackRepository{db: db}
 }
 
-// CreateTrack creates a new track in the database with album association
+// publishedTrack matches tracks t of albums a that are visible to everyone:
+// both the track and its album are published
+const publishedTrack = `t.status = 'published' AND a.status = 'published'`
+
+// trackSearchMatch selects public tracks whose title or artist matches the ILIKE pattern $1
+const trackSearchMatch = `(t.title ILIKE $1 OR COALESCE(t.artist, a.artist) ILIKE $1) AND ` + publishedTrack
+
+// CreateTrack creates a new track in the database with album association.
+// Without a TrackNumber the track is appended to the end of the album.
 func (r *TrackRepository) CreateTrack(ctx context.Context, track *models.Track) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockAlbum(ctx, tx, track.AlbumID); err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO tracks (user_id, album_id, title, artist, duration_seconds, audio_file_key)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO tracks (id, user_id, album_id, title, artist, duration_seconds, audio_file_key, cover_image_key, track_number, audio_sha256, bitrate, format, renditions)
+		VALUES (COALESCE(NULLIF($1, '')::uuid, gen_random_uuid()), $2, $3, $4, $5, $6, $7, $8,
+		        COALESCE($9::int, (SELECT COALESCE(MAX(track_number), 0) + 1 FROM tracks WHERE album_id = $3)),
+		        NULLIF($10, ''), $11, $12, COALESCE($13::jsonb, '{}'::jsonb))
 		RETURNING id, created_at
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
+		track.ID,
 		track.UserID,
 		track.AlbumID,
 		track.Title,
 		track.Artist,
 		track.DurationSeconds,
 		track.AudioFileKey,
+		track.CoverImageKey,
+		track.TrackNumber,
+		track.AudioSHA256,
+		track.Bitrate,
+		track.Format,
+		track.Renditions,
 	).Scan(
 		&track.ID,
 		&track.CreatedAt,
 	)
 
 	if err != nil {
+		// A concurrent upload of the same file can get past the service's duplicate check
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_tracks_album_audio_sha256" {
+			return ErrDuplicateTrack
+		}
 		return fmt.Errorf("failed to create track: %w", err)
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// lockAlbum locks the album row until tx ends, so concurrent uploads and
+// moves can't both take the same next track number
+func lockAlbum(ctx context.Context, tx pgx.Tx, albumID string) error {
+	var id string
+	err := tx.QueryRow(ctx, `SELECT id FROM albums WHERE id = $1 FOR UPDATE`, albumID).Scan(&id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrAlbumNotFound
+		}
+		return fmt.Errorf("failed to lock album: %w", err)
+	}
 	return nil
 }
 
+// ExistsInAlbumByHash reports whether the album has a track with the given audio SHA-256
+func (r *TrackRepository) ExistsInAlbumByHash(ctx context.Context, albumID, audioSHA256 string) (bool, error) {
+	var exists bool
+	err := r.db.Pool.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM tracks WHERE album_id = $1 AND audio_sha256 = $2)`,
+		albumID, audioSHA256,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check track hash: %w", err)
+	}
+	return exists, nil
+}
+
 // GetTrackByID retrieves a track by its ID with album info
 func (r *TrackRepository) GetTrackByID(ctx context.Context, id string) (*models.Track, error) {
 	query := `
 		SELECT t.id, t.user_id, t.album_id, t.title, t.artist, t.duration_seconds, 
-		       t.audio_file_key, t.plays_count, t.likes_count, t.created_at
+		       t.audio_file_key, t.cover_image_key, t.renditions, t.plays_count, t.likes_count, t.created_at,
+		       t.status, ` + publishedTrack + ` AS public
 		FROM tracks t
+		JOIN albums a ON t.album_id = a.id
 		WHERE t.id = $1
 	`
 
@@ -64,14 +131,18 @@ func (r *TrackRepository) GetTrackByID(ctx context.Context, id string) (*models.
 		&track.Artist,
 		&track.DurationSeconds,
 		&track.AudioFileKey,
+		&track.CoverImageKey,
+		&track.Renditions,
 		&track.PlaysCount,
 		&track.LikesCount,
 		&track.CreatedAt,
+		&track.Status,
+		&track.Public,
 	)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("track not found")
+			return nil, ErrTrackNotFound
 		}
 		return nil, fmt.Errorf("failed to get track by ID: %w", err)
 	}
@@ -79,46 +150,44 @@ func (r *TrackRepository) GetTrackByID(ctx context.Context, id string) (*models.
 	return &track, nil
 }
 
-// ListTracksWithAlbumInfo returns a paginated list of tracks with album info for frontend with optional genre filtering
-func (r *TrackRepository) ListTracksWithAlbumInfo(ctx context.Context, limit, offset int, userID int, genreFilter string) ([]models.TrackResponse, error) {
-	var query string
-	var args []interface{}
+// ListTracksWithAlbumInfo returns a paginated list of tracks with album info for frontend with optional genre and artist filtering.
+// Tracks are ordered newest first with the ID as a tie-breaker; when cursor is set only tracks after it are returned.
+func (r *TrackRepository) ListTracksWithAlbumInfo(ctx context.Context, limit, offset int, userID int, filter models.TrackFilter, cursor *models.TrackCursor) ([]models.TrackResponse, error) {
+	args := []interface{}{limit, offset, filter.Genre, filter.Artist, filter.CreatedAfter, filter.CreatedBefore}
 
+	// For unauthenticated users, no like status
+	isLiked := "false"
 	if userID != 0 {
-		// For authenticated users, include like status
-		query = `
-			SELECT 
-				t.id, t.title, t.duration_seconds, t.plays_count, t.likes_count, t.audio_file_key,
-				a.id as album_id, a.title as album_title, a.cover_image_key,
-				COALESCE(t.artist, a.artist) as final_artist,
-				a.release_date, a.genre,
-				t.created_at,
-				EXISTS(SELECT 1 FROM track_likes tl WHERE tl.track_id = t.id AND tl.user_id = $4) as is_liked
-			FROM tracks t
-			JOIN albums a ON t.album_id = a.id
-			WHERE ($3 = '' OR a.genre = $3)
-			ORDER BY t.created_at DESC
-			LIMIT $1 OFFSET $2
-		`
-		args = []interface{}{limit, offset, genreFilter, userID}
-	} else {
-		// For unauthenticated users, no like status
-		query = `
-			SELECT 
-				t.id, t.title, t.duration_seconds, t.plays_count, t.likes_count, t.audio_file_key,
-				a.id as album_id, a.title as album_title, a.cover_image_key,
-				COALESCE(t.artist, a.artist) as final_artist,
-				a.release_date, a.genre,
-				t.created_at, false as is_liked
-			FROM tracks t
-			JOIN albums a ON t.album_id = a.id
-			WHERE ($3 = '' OR a.genre = $3)
-			ORDER BY t.created_at DESC
-			LIMIT $1 OFFSET $2
-		`
-		args = []interface{}{limit, offset, genreFilter}
+		args = append(args, userID)
+		isLiked = fmt.Sprintf("EXISTS(SELECT 1 FROM track_likes tl WHERE tl.track_id = t.id AND tl.user_id = $%d)", len(args))
 	}
 
+	// Keyset condition matching the ORDER BY below
+	afterCursor := ""
+	if cursor != nil {
+		args = append(args, cursor.CreatedAt, cursor.ID)
+		afterCursor = fmt.Sprintf("AND (t.created_at, t.id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT 
+			t.id, t.title, t.duration_seconds, t.bitrate, t.format, t.plays_count, t.likes_count, t.audio_file_key,
+			a.id as album_id, a.title as album_title, COALESCE(t.cover_image_key, a.cover_image_key) as cover_image_key,
+			COALESCE(t.artist, a.artist) as final_artist,
+			a.release_date, a.genre,
+			t.created_at,
+			%s as is_liked
+		FROM tracks t
+		JOIN albums a ON t.album_id = a.id
+		WHERE ($3 = '' OR a.genre = $3)
+		  AND ($4 = '' OR LOWER(COALESCE(t.artist, a.artist)) = LOWER($4))
+		  AND t.created_at BETWEEN COALESCE($5::timestamp, '-infinity') AND COALESCE($6::timestamp, 'infinity')
+		  AND ` + publishedTrack + `
+		  %s
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT $1 OFFSET $2
+	`, isLiked, afterCursor)
+
 	rows, err := r.db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tracks with album info: %w", err)
@@ -134,6 +203,8 @@ func (r *TrackRepository) ListTracksWithAlbumInfo(ctx context.Context, limit, of
 			&track.ID,
 			&track.Title,
 			&track.DurationSeconds,
+			&track.Bitrate,
+			&track.Format,
 			&track.PlaysCount,
 			&track.LikesCount,
 			&track.AudioFileKey,
@@ -159,28 +230,29 @@ func (r *TrackRepository) ListTracksWithAlbumInfo(ctx context.Context, limit, of
 	return tracks, nil
 }
 
-// GetTracksByUserID returns all tracks for a specific user with album info
-func (r *TrackRepository) GetTracksByUserID(ctx context.Context, userID int) ([]models.TrackResponse, error) {
+// GetTracksByUserID returns a page of a user's tracks with album info, newest first
+func (r *TrackRepository) GetTracksByUserID(ctx context.Context, userID int, limit, offset int) ([]models.TrackResponse, error) {
 	query := `
 		SELECT 
-			t.id, t.title, t.duration_seconds, t.plays_count, t.likes_count, t.audio_file_key,
-			a.id as album_id, a.title as album_title, a.cover_image_key,
+			t.id, t.title, t.duration_seconds, t.bitrate, t.format, t.plays_count, t.likes_count, t.audio_file_key,
+			a.id as album_id, a.title as album_title, COALESCE(t.cover_image_key, a.cover_image_key) as cover_image_key,
 			COALESCE(t.artist, a.artist) as final_artist,
 			a.release_date, a.genre,
 			t.created_at, false as is_liked
 		FROM tracks t
 		JOIN albums a ON t.album_id = a.id
 		WHERE t.user_id = $1
-		ORDER BY t.created_at DESC
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, userID)
+	rows, err := r.db.Pool.Query(ctx, query, userID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tracks by user ID: %w", err)
 	}
 	defer rows.Close()
 
-	var tracks []models.TrackResponse
+	tracks := []models.TrackResponse{}
 	for rows.Next() {
 		var track models.TrackResponse
 		var albumID string
@@ -189,6 +261,8 @@ func (r *TrackRepository) GetTracksByUserID(ctx context.Context, userID int) ([]
 			&track.ID,
 			&track.Title,
 			&track.DurationSeconds,
+			&track.Bitrate,
+			&track.Format,
 			&track.PlaysCount,
 			&track.LikesCount,
 			&track.AudioFileKey,
@@ -211,41 +285,57 @@ func (r *TrackRepository) GetTracksByUserID(ctx context.Context, userID int) ([]
 		tracks = append(tracks, track)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user tracks: %w", err)
+	}
+
 	return tracks, nil
 }
 
-// GetTrackWithAlbumInfo retrieves a track by ID with album info and like status
-func (r *TrackRepository) GetTrackWithAlbumInfo(ctx context.Context, trackID string, userID int) (*models.TrackResponse, error) {
+// CountTracksByUserID returns the number of tracks uploaded by a user
+func (r *TrackRepository) CountTracksByUserID(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM tracks WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count user tracks: %w", err)
+	}
+	return count, nil
+}
+
+// GetTrackWithAlbumInfo retrieves a track by ID with album info and like status.
+// Without includeDrafts a track that isn't public is reported as ErrTrackNotFound.
+func (r *TrackRepository) GetTrackWithAlbumInfo(ctx context.Context, trackID string, userID int, includeDrafts bool) (*models.TrackResponse, error) {
 	var query string
 	var args []interface{}
 
 	if userID != 0 {
 		query = `
 			SELECT 
-				t.id, t.title, t.duration_seconds, t.plays_count, t.likes_count, t.audio_file_key,
-				a.id as album_id, a.title as album_title, a.cover_image_key,
+				t.id, t.title, t.duration_seconds, t.bitrate, t.format, t.plays_count, t.likes_count, t.audio_file_key,
+				a.id as album_id, a.title as album_title, COALESCE(t.cover_image_key, a.cover_image_key) as cover_image_key,
 				COALESCE(t.artist, a.artist) as final_artist,
 				a.release_date, a.genre,
 				t.created_at,
-				EXISTS(SELECT 1 FROM track_likes tl WHERE tl.track_id = t.id AND tl.user_id = $2) as is_liked
+				EXISTS(SELECT 1 FROM track_likes tl WHERE tl.track_id = t.id AND tl.user_id = $2) as is_liked,
+				t.status
 			FROM tracks t
 			JOIN albums a ON t.album_id = a.id
-			WHERE t.id = $1
+			WHERE t.id = $1 AND ($3 OR ` + publishedTrack + `)
 		`
-		args = []interface{}{trackID, userID}
+		args = []interface{}{trackID, userID, includeDrafts}
 	} else {
 		query = `
 			SELECT 
-				t.id, t.title, t.duration_seconds, t.plays_count, t.likes_count, t.audio_file_key,
-				a.id as album_id, a.title as album_title, a.cover_image_key,
+				t.id, t.title, t.duration_seconds, t.bitrate, t.format, t.plays_count, t.likes_count, t.audio_file_key,
+				a.id as album_id, a.title as album_title, COALESCE(t.cover_image_key, a.cover_image_key) as cover_image_key,
 				COALESCE(t.artist, a.artist) as final_artist,
 				a.release_date, a.genre,
-				t.created_at, false as is_liked
+				t.created_at, false as is_liked, t.status
 			FROM tracks t
 			JOIN albums a ON t.album_id = a.id
-			WHERE t.id = $1
+			WHERE t.id = $1 AND ($2 OR ` + publishedTrack + `)
 		`
-		args = []interface{}{trackID}
+		args = []interface{}{trackID, includeDrafts}
 	}
 
 	var track models.TrackResponse
@@ -255,6 +345,8 @@ func (r *TrackRepository) GetTrackWithAlbumInfo(ctx context.Context, trackID str
 		&track.ID,
 		&track.Title,
 		&track.DurationSeconds,
+		&track.Bitrate,
+		&track.Format,
 		&track.PlaysCount,
 		&track.LikesCount,
 		&track.AudioFileKey,
@@ -266,11 +358,12 @@ func (r *TrackRepository) GetTrackWithAlbumInfo(ctx context.Context, trackID str
 		&track.Genre,
 		&track.CreatedAt,
 		&track.IsLiked,
+		&track.Status,
 	)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("track not found")
+			return nil, ErrTrackNotFound
 		}
 		return nil, fmt.Errorf("failed to get track with album info: %w", err)
 	}
@@ -292,7 +385,7 @@ func (r *TrackRepository) DeleteTrack(ctx context.Context, id string) error {
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("track not found")
+		return ErrTrackNotFound
 	}
 
 	return nil
@@ -317,7 +410,7 @@ func (r *TrackRepository) UpdateTrack(ctx context.Context, id string, track *mod
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("track not found")
+		return ErrTrackNotFound
 	}
 
 	return nil
@@ -339,7 +432,7 @@ func (r *TrackRepository) ToggleLike(ctx context.Context, userID int, trackID st
 	err = tx.QueryRow(ctx, checkQuery, trackID).Scan(&currentLikesCount)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return false, 0, fmt.Errorf("track not found")
+			return false, 0, ErrTrackNotFound
 		}
 		return false, 0, fmt.Errorf("failed to check track: %w", err)
 	}
@@ -413,168 +506,917 @@ func (r *TrackRepository) ToggleLike(ctx context.Context, userID int, trackID st
 	return isLiked, newLikesCount, nil
 }
 
-// IncrementPlays atomically increments the play count for a track
-func (r *TrackRepository) IncrementPlays(ctx context.Context, trackID string) error {
-	query := `
-		UPDATE tracks
-		SET plays_count = plays_count + 1
-		WHERE id = $1
-	`
-
-	result, err := r.db.Pool.Exec(ctx, query, trackID)
+// SetLike idempotently sets the like state of a track for a user.
+// Nothing changes if the track is already in the requested state.
+// Returns (isLiked, currentLikesCount, error)
+func (r *TrackRepository) SetLike(ctx context.Context, userID int, trackID string, liked bool) (bool, int, error) {
+	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to increment plays: %w", err)
+		return false, 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("track not found")
+	// Lock the track row so the counter stays consistent with track_likes
+	var likesCount int
+	err = tx.QueryRow(ctx, `SELECT likes_count FROM tracks WHERE id = $1 FOR UPDATE`, trackID).Scan(&likesCount)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, 0, ErrTrackNotFound
+		}
+		return false, 0, fmt.Errorf("failed to check track: %w", err)
 	}
 
-	return nil
-}
-
-// GetUserLikedTrackIDs returns a list of track IDs liked by the user
-func (r *TrackRepository) GetUserLikedTrackIDs(ctx context.Context, userID int) ([]string, error) {
-	query := `
-		SELECT track_id
-		FROM track_likes
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-	`
+	var changeQuery, updateCountQuery string
+	if liked {
+		changeQuery = `
+			INSERT INTO track_likes (user_id, track_id)
+			VALUES ($1, $2)
+			ON CONFLICT (user_id, track_id) DO NOTHING
+		`
+		updateCountQuery = `UPDATE tracks SET likes_count = likes_count + 1 WHERE id = $1 RETURNING likes_count`
+	} else {
+		changeQuery = `DELETE FROM track_likes WHERE user_id = $1 AND track_id = $2`
+		updateCountQuery = `UPDATE tracks SET likes_count = likes_count - 1 WHERE id = $1 RETURNING likes_count`
+	}
 
-	rows, err := r.db.Pool.Query(ctx, query, userID)
+	result, err := tx.Exec(ctx, changeQuery, userID, trackID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user liked track IDs: %w", err)
+		return false, 0, fmt.Errorf("failed to set like: %w", err)
 	}
-	defer rows.Close()
 
-	var trackIDs []string
-	for rows.Next() {
-		var trackID string
-		if err := rows.Scan(&trackID); err != nil {
-			return nil, fmt.Errorf("failed to scan track ID: %w", err)
+	// Only touch the counter when the like state actually changed
+	if result.RowsAffected() > 0 {
+		if err := tx.QueryRow(ctx, updateCountQuery, trackID).Scan(&likesCount); err != nil {
+			return false, 0, fmt.Errorf("failed to update likes count: %w", err)
 		}
-		trackIDs = append(trackIDs, trackID)
 	}
 
-	return trackIDs, nil
+	if err := tx.Commit(ctx); err != nil {
+		return false, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return liked, likesCount, nil
 }
 
-// GetTrackStats returns play and like counts for a track
-func (r *TrackRepository) GetTrackStats(ctx context.Context, trackID string) (*models.TrackStats, error) {
+// AddLike likes a track for the user and returns the likes count. Liking an
+// already liked track changes nothing.
+func (r *TrackRepository) AddLike(ctx context.Context, userID int, trackID string) (int, error) {
+	_, likesCount, err := r.SetLike(ctx, userID, trackID, true)
+	return likesCount, err
+}
+
+// RemoveLike removes the user's like from a track and returns the likes
+// count. Removing a missing like changes nothing.
+func (r *TrackRepository) RemoveLike(ctx context.Context, userID int, trackID string) (int, error) {
+	_, likesCount, err := r.SetLike(ctx, userID, trackID, false)
+	return likesCount, err
+}
+
+// RecordPlays adds a batch of plays to the tracks' play counts and the
+// listeners' play history in one transaction. Plays of tracks that no longer
+// exist are dropped; anonymous plays (user ID 0) only count toward plays_count.
+func (r *TrackRepository) RecordPlays(ctx context.Context, plays []models.Play) error {
+	trackIDs := make([]string, len(plays))
+	userIDs := make([]int, len(plays))
+	playedAt := make([]time.Time, len(plays))
+	for i, play := range plays {
+		trackIDs[i] = play.TrackID
+		userIDs[i] = play.UserID
+		playedAt[i] = play.PlayedAt
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		SELECT plays_count, likes_count
-		FROM tracks
-		WHERE id = $1
+		UPDATE tracks t
+		SET plays_count = t.plays_count + p.plays
+		FROM (
+			SELECT track_id, COUNT(*) AS plays
+			FROM unnest($1::uuid[]) AS track_id
+			GROUP BY track_id
+		) p
+		WHERE t.id = p.track_id
 	`
+	if _, err := tx.Exec(ctx, query, trackIDs); err != nil {
+		return fmt.Errorf("failed to increment plays: %w", err)
+	}
 
-	var stats models.TrackStats
-	err := r.db.Pool.QueryRow(ctx, query, trackID).Scan(
-		&stats.PlaysCount,
-		&stats.LikesCount,
-	)
+	historyQuery := `
+		INSERT INTO play_history (user_id, track_id, played_at)
+		SELECT p.user_id, p.track_id, p.played_at
+		FROM unnest($1::uuid[], $2::int[], $3::timestamp[]) AS p(track_id, user_id, played_at)
+		JOIN tracks t ON t.id = p.track_id
+		JOIN users u ON u.id = p.user_id
+	`
+	if _, err := tx.Exec(ctx, historyQuery, trackIDs, userIDs, playedAt); err != nil {
+		return fmt.Errorf("failed to record plays: %w", err)
+	}
 
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("track not found")
-		}
-		return nil, fmt.Errorf("failed to get track stats: %w", err)
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return &stats, nil
+	return nil
 }
 
-// CountTracks returns the total number of tracks
-func (r *TrackRepository) CountTracks(ctx context.Context) (int, error) {
-	var count int
-	query := `SELECT COUNT(*) FROM tracks`
+// GetTopTracks returns up to limit tracks with the most plays recorded in
+// play_history since the given time, most played first
+func (r *TrackRepository) GetTopTracks(ctx context.Context, since time.Time, limit int) ([]models.TopTrack, error) {
+	query := `
+		SELECT 
+			t.id, t.title, t.duration_seconds, t.bitrate, t.format, t.plays_count, t.likes_count, t.audio_file_key,
+			a.id as album_id, a.title as album_title, COALESCE(t.cover_image_key, a.cover_image_key) as cover_image_key,
+			COALESCE(t.artist, a.artist) as final_artist,
+			a.release_date, a.genre,
+			t.created_at, top.plays
+		FROM (
+			SELECT track_id, COUNT(*) AS plays
+			FROM play_history
+			WHERE played_at >= $1
+			GROUP BY track_id
+			ORDER BY plays DESC, track_id
+			LIMIT $2
+		) top
+		JOIN tracks t ON t.id = top.track_id
+		JOIN albums a ON t.album_id = a.id
+		ORDER BY top.plays DESC, t.id
+	`
 
-	err := r.db.Pool.QueryRow(ctx, query).Scan(&count)
+	rows, err := r.db.Pool.Query(ctx, query, since, limit)
 	if err != nil {
-		return 0, fmt.Errorf("failed to count tracks: %w", err)
+		return nil, fmt.Errorf("failed to get top tracks: %w", err)
 	}
+	defer rows.Close()
 
-	return count, nil
-}
+	tracks := []models.TopTrack{}
+	for rows.Next() {
+		var track models.TopTrack
+		var albumID string
+		var releaseDate time.Time
+		err := rows.Scan(
+			&track.ID,
+			&track.Title,
+			&track.DurationSeconds,
+			&track.Bitrate,
+			&track.Format,
+			&track.PlaysCount,
+			&track.LikesCount,
+			&track.AudioFileKey,
+			&albumID,
+			&track.AlbumTitle,
+			&track.CoverImageKey,
+			&track.ArtistName,
+			&releaseDate,
+			&track.Genre,
+			&track.CreatedAt,
+			&track.PeriodPlays,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan top track: %w", err)
+		}
 
-// ParseTrackID validates and parses a track ID from string
-func ParseTrackID(id string) (uuid.UUID, error) {
-	parsedID, err := uuid.Parse(id)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid track ID format: %w", err)
+		track.ReleaseDate = releaseDate.Format("2006-01-02")
+		track.AlbumID = albumID
+		tracks = append(tracks, track)
 	}
-	return parsedID, nil
-}
 
-// Legacy compatibility methods - to be removed after migration
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating top tracks: %w", err)
+	}
 
-// ListTracks - deprecated, use ListTracksWithAlbumInfo
-func (r *TrackRepository) ListTracks(ctx context.Context, limit, offset int) ([]models.Track, error) {
-	return nil, fmt.Errorf("deprecated method - use ListTracksWithAlbumInfo")
+	return tracks, nil
 }
 
-// ListTracksWithLikeStatus - deprecated, use ListTracksWithAlbumInfo
-func (r *TrackRepository) ListTracksWithLikeStatus(ctx context.Context, limit, offset int, userID int) ([]models.Track, error) {
-	return nil, fmt.Errorf("deprecated method - use ListTracksWithAlbumInfo")
-}
+// GetNextTrackInAlbum returns the ID of the track after trackID in album order,
+// or "" if it is the album's last track
+func (r *TrackRepository) GetNextTrackInAlbum(ctx context.Context, trackID string) (string, error) {
+	query := `
+		WITH ordered AS (
+			SELECT t.id, ROW_NUMBER() OVER (ORDER BY t.track_number ASC NULLS LAST, t.created_at ASC, t.id ASC) AS pos
+			FROM tracks t
+			JOIN albums a ON t.album_id = a.id
+			WHERE t.album_id = (SELECT album_id FROM tracks WHERE id = $1)
+			  AND (t.id = $1 OR ` + publishedTrack + `)
+		)
+		SELECT id FROM ordered
+		WHERE pos = (SELECT pos FROM ordered WHERE id = $1) + 1
+	`
 
-// GetUserTracksWithLikeStatus - deprecated, use GetTracksByUserID
-func (r *TrackRepository) GetUserTracksWithLikeStatus(ctx context.Context, userID int) ([]models.Track, error) {
-	return nil, fmt.Errorf("deprecated method - use GetTracksByUserID")
+	var nextID string
+	err := r.db.Pool.QueryRow(ctx, query, trackID).Scan(&nextID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get next track in album: %w", err)
+	}
+	return nextID, nil
 }
 
-// GetTrackWithLikeStatus - deprecated, use GetTrackWithAlbumInfo
-func (r *TrackRepository) GetTrackWithLikeStatus(ctx context.Context, trackID string, userID int) (*models.Track, error) {
-	return nil, fmt.Errorf("deprecated method - use GetTrackWithAlbumInfo")
+// GetRandomTrackInGenre returns the ID of a random track with the same genre as
+// trackID that userID hasn't played since the given time, or "" if there is none
+func (r *TrackRepository) GetRandomTrackInGenre(ctx context.Context, trackID string, userID int, since time.Time) (string, error) {
+	query := `
+		SELECT t.id
+		FROM tracks t
+		JOIN albums a ON t.album_id = a.id
+		WHERE a.genre = (
+			SELECT sa.genre FROM tracks st JOIN albums sa ON st.album_id = sa.id WHERE st.id = $1
+		)
+		  AND t.id <> $1
+		  AND ` + publishedTrack + `
+		  AND NOT EXISTS (
+			SELECT 1 FROM play_history ph
+			WHERE ph.user_id = $2 AND ph.track_id = t.id AND ph.played_at >= $3
+		  )
+		ORDER BY random()
+		LIMIT 1
+	`
+
+	var trackIDInGenre string
+	err := r.db.Pool.QueryRow(ctx, query, trackID, userID, since).Scan(&trackIDInGenre)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get track in genre: %w", err)
+	}
+	return trackIDInGenre, nil
 }
 
-// GetTracksWithZeroDuration returns tracks that have duration_seconds = 0
-func (r *TrackRepository) GetTracksWithZeroDuration(ctx context.Context) ([]models.Track, error) {
+// GetRandomTracksByGenre returns up to limit random tracks of the genre that
+// aren't in excludeIDs. For a non-zero userID tracks the user played since
+// playedSince come last and is_liked reflects the user's likes.
+func (r *TrackRepository) GetRandomTracksByGenre(ctx context.Context, genre string, excludeIDs []string, limit int, userID int, playedSince time.Time) ([]models.TrackResponse, error) {
 	query := `
-		SELECT id, user_id, album_id, title, artist, duration_seconds, audio_file_key, 
-			   plays_count, likes_count, created_at
-		FROM tracks 
-		WHERE duration_seconds = 0 OR duration_seconds IS NULL
-		ORDER BY created_at DESC
+		SELECT 
+			t.id, t.title, t.duration_seconds, t.bitrate, t.format, t.plays_count, t.likes_count, t.audio_file_key,
+			a.id as album_id, a.title as album_title, COALESCE(t.cover_image_key, a.cover_image_key) as cover_image_key,
+			COALESCE(t.artist, a.artist) as final_artist,
+			a.release_date, a.genre,
+			t.created_at,
+			EXISTS(SELECT 1 FROM track_likes tl WHERE tl.track_id = t.id AND tl.user_id = $4) as is_liked
+		FROM tracks t
+		JOIN albums a ON t.album_id = a.id
+		WHERE a.genre = $1
+		  AND NOT (t.id = ANY($2::uuid[]))
+		  AND ` + publishedTrack + `
+		ORDER BY EXISTS(
+			SELECT 1 FROM play_history ph
+			WHERE ph.user_id = $4 AND ph.track_id = t.id AND ph.played_at >= $5
+		), random()
+		LIMIT $3
 	`
-	
-	rows, err := r.db.Pool.Query(ctx, query)
+
+	rows, err := r.db.Pool.Query(ctx, query, genre, excludeIDs, limit, userID, playedSince)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get tracks with zero duration: %w", err)
+		return nil, fmt.Errorf("failed to get random tracks by genre: %w", err)
 	}
 	defer rows.Close()
 
-	var tracks []models.Track
+	tracks := []models.TrackResponse{}
 	for rows.Next() {
-		var track models.Track
+		var track models.TrackResponse
+		var albumID string
+		var releaseDate time.Time
 		err := rows.Scan(
 			&track.ID,
-			&track.UserID,
-			&track.AlbumID,
 			&track.Title,
-			&track.Artist,
 			&track.DurationSeconds,
-			&track.AudioFileKey,
+			&track.Bitrate,
+			&track.Format,
 			&track.PlaysCount,
 			&track.LikesCount,
+			&track.AudioFileKey,
+			&albumID,
+			&track.AlbumTitle,
+			&track.CoverImageKey,
+			&track.ArtistName,
+			&releaseDate,
+			&track.Genre,
 			&track.CreatedAt,
+			&track.IsLiked,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan track: %w", err)
 		}
+
+		track.ReleaseDate = releaseDate.Format("2006-01-02")
+		track.AlbumID = albumID
 		tracks = append(tracks, track)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating random tracks: %w", err)
+	}
+
 	return tracks, nil
 }
 
-// UpdateTrackDuration updates the duration_seconds field for a track
-func (r *TrackRepository) UpdateTrackDuration(ctx context.Context, trackID string, durationSeconds int) error {
-	query := "UPDATE tracks SET duration_seconds = $2 WHERE id = $1"
-	result, err := r.db.Pool.Exec(ctx, query, trackID, durationSeconds)
-	if err != nil {
-		return fmt.Errorf("failed to update track duration: %w", err)
-	}
+// GetMostPlayedTrack returns the ID of the most played track other than
+// excludeID, or "" if there is none
+func (r *TrackRepository) GetMostPlayedTrack(ctx context.Context, excludeID string) (string, error) {
+	query := `
+		SELECT t.id
+		FROM tracks t
+		JOIN albums a ON t.album_id = a.id
+		WHERE t.id <> $1 AND ` + publishedTrack + `
+		ORDER BY t.plays_count DESC, t.created_at DESC, t.id
+		LIMIT 1
+	`
+
+	var trackID string
+	err := r.db.Pool.QueryRow(ctx, query, excludeID).Scan(&trackID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get most played track: %w", err)
+	}
+	return trackID, nil
+}
+
+// SetStatus drafts or publishes a single track
+func (r *TrackRepository) SetStatus(ctx context.Context, trackID, status string) error {
+	result, err := r.db.Pool.Exec(ctx, `UPDATE tracks SET status = $2 WHERE id = $1`, trackID, status)
+	if err != nil {
+		return fmt.Errorf("failed to set track status: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTrackNotFound
+	}
+	return nil
+}
+
+// MoveToAlbum moves a track to another album under new storage keys and
+// appends it to the end of that album
+func (r *TrackRepository) MoveToAlbum(ctx context.Context, track *models.Track) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockAlbum(ctx, tx, track.AlbumID); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE tracks
+		SET album_id = $2, audio_file_key = $3, cover_image_key = $4, renditions = COALESCE($5::jsonb, '{}'::jsonb),
+		    track_number = (SELECT COALESCE(MAX(track_number), 0) + 1 FROM tracks WHERE album_id = $2)
+		WHERE id = $1
+	`
+	result, err := tx.Exec(ctx, query, track.ID, track.AlbumID, track.AudioFileKey, track.CoverImageKey, track.Renditions)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_tracks_album_audio_sha256" {
+			return ErrDuplicateTrack
+		}
+		return fmt.Errorf("failed to move track: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTrackNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetUserLikedTracks returns the tracks liked by the user with the time of
+// each like, newest first. A limit of 0 returns all of them.
+func (r *TrackRepository) GetUserLikedTracks(ctx context.Context, userID, limit, offset int) ([]models.LikedTrack, error) {
+	query := `
+		SELECT track_id, created_at
+		FROM track_likes
+		WHERE user_id = $1
+		ORDER BY created_at DESC, track_id
+		LIMIT NULLIF($2, 0) OFFSET $3
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user liked tracks: %w", err)
+	}
+	defer rows.Close()
+
+	var liked []models.LikedTrack
+	for rows.Next() {
+		var like models.LikedTrack
+		if err := rows.Scan(&like.TrackID, &like.LikedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan liked track: %w", err)
+		}
+		liked = append(liked, like)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate liked tracks: %w", err)
+	}
+
+	return liked, nil
+}
+
+// GetLikedTrackIDsAmong returns which of the given tracks the user has liked
+func (r *TrackRepository) GetLikedTrackIDsAmong(ctx context.Context, userID int, trackIDs []string) ([]string, error) {
+	query := `SELECT track_id FROM track_likes WHERE user_id = $1 AND track_id = ANY($2::uuid[])`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, trackIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check liked tracks: %w", err)
+	}
+	defer rows.Close()
+
+	var liked []string
+	for rows.Next() {
+		var trackID string
+		if err := rows.Scan(&trackID); err != nil {
+			return nil, fmt.Errorf("failed to scan track ID: %w", err)
+		}
+		liked = append(liked, trackID)
+	}
+
+	return liked, rows.Err()
+}
+
+// GetTrackStats returns play and like counts for a track
+func (r *TrackRepository) GetTrackStats(ctx context.Context, trackID string) (*models.TrackStats, error) {
+	query := `
+		SELECT plays_count, likes_count
+		FROM tracks
+		WHERE id = $1
+	`
+
+	var stats models.TrackStats
+	err := r.db.Pool.QueryRow(ctx, query, trackID).Scan(
+		&stats.PlaysCount,
+		&stats.LikesCount,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrTrackNotFound
+		}
+		return nil, fmt.Errorf("failed to get track stats: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// GetTrackLikers returns users who liked a track, newest likes first, with the total like count
+func (r *TrackRepository) GetTrackLikers(ctx context.Context, trackID string, limit, offset int) ([]models.TrackLiker, int, error) {
+	var total int
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM track_likes tl WHERE tl.track_id = t.id)
+		FROM tracks t
+		WHERE t.id = $1
+	`, trackID).Scan(&total)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, 0, ErrTrackNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to count track likes: %w", err)
+	}
+
+	query := `
+		SELECT u.id, u.name, u.avatar_key, tl.created_at
+		FROM track_likes tl
+		JOIN users u ON u.id = tl.user_id
+		WHERE tl.track_id = $1
+		ORDER BY tl.created_at DESC, u.id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, trackID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get track likers: %w", err)
+	}
+	defer rows.Close()
+
+	likers := []models.TrackLiker{}
+	for rows.Next() {
+		var liker models.TrackLiker
+		if err := rows.Scan(&liker.ID, &liker.Name, &liker.AvatarKey, &liker.LikedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan track liker: %w", err)
+		}
+		likers = append(likers, liker)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating track likers: %w", err)
+	}
+
+	return likers, total, nil
+}
+
+// CountTracks returns the number of tracks matching the filter
+func (r *TrackRepository) CountTracks(ctx context.Context, filter models.TrackFilter) (int, error) {
+	var count int
+	query := `
+		SELECT COUNT(*)
+		FROM tracks t
+		JOIN albums a ON t.album_id = a.id
+		WHERE ($1 = '' OR a.genre = $1)
+		  AND ($2 = '' OR LOWER(COALESCE(t.artist, a.artist)) = LOWER($2))
+		  AND t.created_at BETWEEN COALESCE($3::timestamp, '-infinity') AND COALESCE($4::timestamp, 'infinity')
+		  AND ` + publishedTrack + `
+	`
+
+	err := r.db.Pool.QueryRow(ctx, query, filter.Genre, filter.Artist, filter.CreatedAfter, filter.CreatedBefore).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tracks: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListTracksByAlbum returns a page of an album's tracks in album order (oldest first).
+// When userID is non-zero each track's is_liked reflects that user's likes.
+// Draft tracks are only listed with includeDrafts.
+func (r *TrackRepository) ListTracksByAlbum(ctx context.Context, albumID string, limit, offset int, userID int, includeDrafts bool) ([]models.TrackResponse, error) {
+	var query string
+	var args []interface{}
+
+	if userID != 0 {
+		// For authenticated users, include like status
+		query = `
+			SELECT 
+				t.id, t.title, t.duration_seconds, t.bitrate, t.format, t.plays_count, t.likes_count, t.audio_file_key,
+				a.id as album_id, a.title as album_title, COALESCE(t.cover_image_key, a.cover_image_key) as cover_image_key,
+				COALESCE(t.artist, a.artist) as final_artist,
+				a.release_date, a.genre,
+				t.created_at,
+				EXISTS(SELECT 1 FROM track_likes tl WHERE tl.track_id = t.id AND tl.user_id = $4) as is_liked
+			FROM tracks t
+			JOIN albums a ON t.album_id = a.id
+			WHERE t.album_id = $1 AND ($5 OR t.status = 'published')
+			ORDER BY t.track_number ASC NULLS LAST, t.created_at ASC
+			LIMIT $2 OFFSET $3
+		`
+		args = []interface{}{albumID, limit, offset, userID, includeDrafts}
+	} else {
+		// For unauthenticated users, no like status
+		query = `
+			SELECT 
+				t.id, t.title, t.duration_seconds, t.bitrate, t.format, t.plays_count, t.likes_count, t.audio_file_key,
+				a.id as album_id, a.title as album_title, COALESCE(t.cover_image_key, a.cover_image_key) as cover_image_key,
+				COALESCE(t.artist, a.artist) as final_artist,
+				a.release_date, a.genre,
+				t.created_at, false as is_liked
+			FROM tracks t
+			JOIN albums a ON t.album_id = a.id
+			WHERE t.album_id = $1 AND ($4 OR t.status = 'published')
+			ORDER BY t.track_number ASC NULLS LAST, t.created_at ASC
+			LIMIT $2 OFFSET $3
+		`
+		args = []interface{}{albumID, limit, offset, includeDrafts}
+	}
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list album tracks: %w", err)
+	}
+	defer rows.Close()
+
+	var tracks []models.TrackResponse
+	for rows.Next() {
+		var track models.TrackResponse
+		var releaseDate time.Time
+		err := rows.Scan(
+			&track.ID,
+			&track.Title,
+			&track.DurationSeconds,
+			&track.Bitrate,
+			&track.Format,
+			&track.PlaysCount,
+			&track.LikesCount,
+			&track.AudioFileKey,
+			&track.AlbumID,
+			&track.AlbumTitle,
+			&track.CoverImageKey,
+			&track.ArtistName,
+			&releaseDate,
+			&track.Genre,
+			&track.CreatedAt,
+			&track.IsLiked,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan track: %w", err)
+		}
+
+		track.ReleaseDate = releaseDate.Format("2006-01-02")
+		tracks = append(tracks, track)
+	}
+
+	return tracks, rows.Err()
+}
+
+// CountTracksByAlbum returns the number of tracks in an album, counting drafts only with includeDrafts
+func (r *TrackRepository) CountTracksByAlbum(ctx context.Context, albumID string, includeDrafts bool) (int, error) {
+	var count int
+	err := r.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM tracks WHERE album_id = $1 AND ($2 OR status = 'published')`, albumID, includeDrafts).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count album tracks: %w", err)
+	}
+
+	return count, nil
+}
+
+// SearchTracks returns tracks whose title or artist contains query (case-insensitive).
+// Exact title matches rank first, then title prefixes, then artist prefixes, then by popularity.
+// When userID is non-zero each track's is_liked reflects that user's likes.
+func (r *TrackRepository) SearchTracks(ctx context.Context, query string, limit int, userID int) ([]models.TrackResponse, error) {
+	contains, prefix := searchPatterns(query)
+	args := []interface{}{contains, query, prefix, limit}
+
+	likedColumn := "false"
+	if userID != 0 {
+		likedColumn = "EXISTS(SELECT 1 FROM track_likes tl WHERE tl.track_id = t.id AND tl.user_id = $5)"
+		args = append(args, userID)
+	}
+
+	sqlQuery := `
+		SELECT 
+			t.id, t.title, t.duration_seconds, t.bitrate, t.format, t.plays_count, t.likes_count, t.audio_file_key,
+			a.id as album_id, a.title as album_title, COALESCE(t.cover_image_key, a.cover_image_key) as cover_image_key,
+			COALESCE(t.artist, a.artist) as final_artist,
+			a.release_date, a.genre,
+			t.created_at, ` + likedColumn + ` as is_liked
+		FROM tracks t
+		JOIN albums a ON t.album_id = a.id
+		WHERE ` + trackSearchMatch + `
+		ORDER BY
+			CASE
+				WHEN LOWER(t.title) = LOWER($2) THEN 0
+				WHEN t.title ILIKE $3 THEN 1
+				WHEN COALESCE(t.artist, a.artist) ILIKE $3 THEN 2
+				ELSE 3
+			END,
+			t.plays_count DESC, t.title
+		LIMIT $4
+	`
+
+	rows, err := r.db.Pool.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tracks: %w", err)
+	}
+	defer rows.Close()
+
+	var tracks []models.TrackResponse
+	for rows.Next() {
+		var track models.TrackResponse
+		var releaseDate time.Time
+		err := rows.Scan(
+			&track.ID,
+			&track.Title,
+			&track.DurationSeconds,
+			&track.Bitrate,
+			&track.Format,
+			&track.PlaysCount,
+			&track.LikesCount,
+			&track.AudioFileKey,
+			&track.AlbumID,
+			&track.AlbumTitle,
+			&track.CoverImageKey,
+			&track.ArtistName,
+			&releaseDate,
+			&track.Genre,
+			&track.CreatedAt,
+			&track.IsLiked,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan track: %w", err)
+		}
+
+		track.ReleaseDate = releaseDate.Format("2006-01-02")
+		tracks = append(tracks, track)
+	}
+
+	return tracks, rows.Err()
+}
+
+// CountTracksByGenre returns the number of tracks per album genre; genres without tracks are absent
+func (r *TrackRepository) CountTracksByGenre(ctx context.Context) (map[string]int, error) {
+	query := `
+		SELECT a.genre, COUNT(*)
+		FROM tracks t
+		JOIN albums a ON t.album_id = a.id
+		WHERE ` + publishedTrack + `
+		GROUP BY a.genre
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tracks by genre: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var genre string
+		var count int
+		if err := rows.Scan(&genre, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan genre count: %w", err)
+		}
+		counts[genre] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// ListArtists returns distinct artist names with their track counts, ordered by name
+func (r *TrackRepository) ListArtists(ctx context.Context) ([]models.Artist, error) {
+	query := `
+		SELECT COALESCE(t.artist, a.artist) AS artist_name, COUNT(*) AS tracks_count
+		FROM tracks t
+		JOIN albums a ON t.album_id = a.id
+		WHERE ` + publishedTrack + `
+		GROUP BY artist_name
+		ORDER BY LOWER(COALESCE(t.artist, a.artist)), artist_name
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artists: %w", err)
+	}
+	defer rows.Close()
+
+	artists := []models.Artist{}
+	for rows.Next() {
+		var artist models.Artist
+		if err := rows.Scan(&artist.Name, &artist.TracksCount); err != nil {
+			return nil, fmt.Errorf("failed to scan artist: %w", err)
+		}
+		artists = append(artists, artist)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating artists: %w", err)
+	}
+
+	return artists, nil
+}
+
+// ParseTrackID validates and parses a track ID from string
+func ParseTrackID(id string) (uuid.UUID, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid track ID format: %w", err)
+	}
+	return parsedID, nil
+}
+
+// Legacy compatibility methods - to be removed after migration
+
+// ListTracks - deprecated, use ListTracksWithAlbumInfo
+func (r *TrackRepository) ListTracks(ctx context.Context, limit, offset int) ([]models.Track, error) {
+	return nil, fmt.Errorf("deprecated method - use ListTracksWithAlbumInfo")
+}
+
+// ListTracksWithLikeStatus - deprecated, use ListTracksWithAlbumInfo
+func (r *TrackRepository) ListTracksWithLikeStatus(ctx context.Context, limit, offset int, userID int) ([]models.Track, error) {
+	return nil, fmt.Errorf("deprecated method - use ListTracksWithAlbumInfo")
+}
+
+// GetUserTracksWithLikeStatus - deprecated, use GetTracksByUserID
+func (r *TrackRepository) GetUserTracksWithLikeStatus(ctx context.Context, userID int) ([]models.Track, error) {
+	return nil, fmt.Errorf("deprecated method - use GetTracksByUserID")
+}
+
+// GetTrackWithLikeStatus - deprecated, use GetTrackWithAlbumInfo
+func (r *TrackRepository) GetTrackWithLikeStatus(ctx context.Context, trackID string, userID int) (*models.Track, error) {
+	return nil, fmt.Errorf("deprecated method - use GetTrackWithAlbumInfo")
+}
+
+// GetTracksWithZeroDuration returns tracks that have duration_seconds = 0
+func (r *TrackRepository) GetTracksWithZeroDuration(ctx context.Context) ([]models.Track, error) {
+	query := `
+		SELECT id, user_id, album_id, title, artist, duration_seconds, audio_file_key, 
+			   plays_count, likes_count, created_at
+		FROM tracks 
+		WHERE duration_seconds = 0 OR duration_seconds IS NULL
+		ORDER BY created_at DESC
+	`
+	
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracks with zero duration: %w", err)
+	}
+	defer rows.Close()
+
+	var tracks []models.Track
+	for rows.Next() {
+		var track models.Track
+		err := rows.Scan(
+			&track.ID,
+			&track.UserID,
+			&track.AlbumID,
+			&track.Title,
+			&track.Artist,
+			&track.DurationSeconds,
+			&track.AudioFileKey,
+			&track.PlaysCount,
+			&track.LikesCount,
+			&track.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan track: %w", err)
+		}
+		tracks = append(tracks, track)
+	}
+
+	return tracks, nil
+}
+
+// GetTracksMissingAudioInfo returns tracks with a zero duration or an unknown bitrate or format
+func (r *TrackRepository) GetTracksMissingAudioInfo(ctx context.Context) ([]models.Track, error) {
+	query := `
+		SELECT id, audio_file_key, duration_seconds
+		FROM tracks
+		WHERE duration_seconds = 0 OR duration_seconds IS NULL OR bitrate IS NULL OR format IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracks missing audio info: %w", err)
+	}
+	defer rows.Close()
+
+	var tracks []models.Track
+	for rows.Next() {
+		var track models.Track
+		if err := rows.Scan(&track.ID, &track.AudioFileKey, &track.DurationSeconds); err != nil {
+			return nil, fmt.Errorf("failed to scan track: %w", err)
+		}
+		tracks = append(tracks, track)
+	}
+
+	return tracks, rows.Err()
+}
+
+// ListObjectKeys returns the ID and storage keys (audio, renditions and own
+// cover) of every track, drafts included
+func (r *TrackRepository) ListObjectKeys(ctx context.Context) ([]models.Track, error) {
+	query := `
+		SELECT id, audio_file_key, cover_image_key, renditions
+		FROM tracks
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list track object keys: %w", err)
+	}
+	defer rows.Close()
+
+	var tracks []models.Track
+	for rows.Next() {
+		var track models.Track
+		if err := rows.Scan(&track.ID, &track.AudioFileKey, &track.CoverImageKey, &track.Renditions); err != nil {
+			return nil, fmt.Errorf("failed to scan track: %w", err)
+		}
+		tracks = append(tracks, track)
+	}
+
+	return tracks, rows.Err()
+}
+
+// SearchFacetCounts counts the tracks SearchTracks matches for query by facet
+// (genre or artist), returning the limit most frequent values
+func (r *TrackRepository) SearchFacetCounts(ctx context.Context, query, facet string, limit int) (map[string]int, error) {
+	sqlQuery, err := facetQuery(trackFacetColumns, facet, "tracks t JOIN albums a ON t.album_id = a.id", trackSearchMatch)
+	if err != nil {
+		return nil, err
+	}
+	contains, _ := searchPatterns(query)
+	return scanFacetCounts(r.db.Pool.Query(ctx, sqlQuery, contains, limit))
+}
+
+// UpdateTrackAudioInfo stores the measured duration, bitrate and format of a track
+func (r *TrackRepository) UpdateTrackAudioInfo(ctx context.Context, trackID string, durationSeconds int, bitrate *int, format *string) error {
+	query := `UPDATE tracks SET duration_seconds = $2, bitrate = $3, format = $4 WHERE id = $1`
+	result, err := r.db.Pool.Exec(ctx, query, trackID, durationSeconds, bitrate, format)
+	if err != nil {
+		return fmt.Errorf("failed to update track audio info: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTrackNotFound
+	}
+	return nil
+}
+
+// UpdateTrackDuration updates the duration_seconds field for a track
+func (r *TrackRepository) UpdateTrackDuration(ctx context.Context, trackID string, durationSeconds int) error {
+	query := "UPDATE tracks SET duration_seconds = $2 WHERE id = $1"
+	result, err := r.db.Pool.Exec(ctx, query, trackID, durationSeconds)
+	if err != nil {
+		return fmt.Errorf("failed to update track duration: %w", err)
+	}
 	
 	if result.RowsAffected() == 0 {
 		return fmt.Errorf("track not found: %s", trackID)
@@ -582,3 +1424,46 @@ func (r *TrackRepository) UpdateTrackDuration(ctx context.Context, trackID strin
 	
 	return nil
 }
+
+// UpsertLyrics stores the lyrics of a track, replacing any existing ones
+func (r *TrackRepository) UpsertLyrics(ctx context.Context, lyrics *models.Lyrics) error {
+	query := `
+		INSERT INTO track_lyrics (track_id, content, is_synced, language)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (track_id) DO UPDATE
+		SET content = EXCLUDED.content,
+		    is_synced = EXCLUDED.is_synced,
+		    language = EXCLUDED.language,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at
+	`
+	err := r.db.Pool.QueryRow(ctx, query, lyrics.TrackID, lyrics.Content, lyrics.IsSynced, lyrics.Language).Scan(&lyrics.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save lyrics: %w", err)
+	}
+	return nil
+}
+
+// GetLyrics returns the lyrics of a track or ErrLyricsNotFound
+func (r *TrackRepository) GetLyrics(ctx context.Context, trackID string) (*models.Lyrics, error) {
+	query := `
+		SELECT track_id, content, is_synced, language, updated_at
+		FROM track_lyrics
+		WHERE track_id = $1
+	`
+	var lyrics models.Lyrics
+	err := r.db.Pool.QueryRow(ctx, query, trackID).Scan(
+		&lyrics.TrackID,
+		&lyrics.Content,
+		&lyrics.IsSynced,
+		&lyrics.Language,
+		&lyrics.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrLyricsNotFound
+		}
+		return nil, fmt.Errorf("failed to get lyrics: %w", err)
+	}
+	return &lyrics, nil
+}
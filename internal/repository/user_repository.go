@@ -2,12 +2,15 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"koteyye_music_be/internal/models"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type UserRepository struct {
@@ -21,24 +24,24 @@ func NewUserRepository(db *DB) *UserRepository {
 // CreateUser creates a new user in the database
 func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (email, name, avatar_key, password_hash, provider, external_id, role)
+		INSERT INTO users (email, name, avatar_key, password_hash, provider, role, email_verified)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, email, name, avatar_key, password_hash, provider, external_id, role, 
+		RETURNING id, email, name, avatar_key, password_hash, provider, role, email_verified,
 		          last_track_id, last_position, volume_preference, created_at, last_login_at
 	`
 
 	// Use NULL for nil pointers (guest users)
-	var email, name, avatarKey, passwordHash, provider, externalID interface{} = user.Email, user.Name, user.AvatarKey, user.PasswordHash, user.Provider, user.ExternalID
+	var email, name, avatarKey, passwordHash, provider interface{} = user.Email, user.Name, user.AvatarKey, user.PasswordHash, user.Provider
 
-	err := r.db.Pool.QueryRow(ctx, query, email, name, avatarKey, passwordHash, provider, externalID, user.Role).Scan(
+	err := r.db.Pool.QueryRow(ctx, query, email, name, avatarKey, passwordHash, provider, user.Role, user.EmailVerified).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Name,
 		&user.AvatarKey,
 		&user.PasswordHash,
 		&user.Provider,
-		&user.ExternalID,
 		&user.Role,
+		&user.EmailVerified,
 		&user.LastTrackID,
 		&user.LastPosition,
 		&user.VolumePreference,
@@ -56,7 +59,7 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) erro
 // GetUserByEmail retrieves a user by email
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, email, name, avatar_key, password_hash, provider, external_id, role, created_at, last_login_at
+		SELECT id, email, name, avatar_key, password_hash, provider, role, email_verified, created_at, last_login_at
 		FROM users
 		WHERE email = $1
 	`
@@ -69,15 +72,15 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 		&user.AvatarKey,
 		&user.PasswordHash,
 		&user.Provider,
-		&user.ExternalID,
 		&user.Role,
+		&user.EmailVerified,
 		&user.CreatedAt,
 		&user.LastLoginAt,
 	)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
@@ -88,7 +91,7 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 // GetUserByID retrieves a user by ID
 func (r *UserRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
 	query := `
-		SELECT id, email, name, avatar_key, password_hash, provider, external_id, role, 
+		SELECT id, email, name, avatar_key, password_hash, provider, role, email_verified,
 		       last_track_id, last_position, volume_preference, created_at, last_login_at
 		FROM users
 		WHERE id = $1
@@ -102,8 +105,8 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id int) (*models.User,
 		&user.AvatarKey,
 		&user.PasswordHash,
 		&user.Provider,
-		&user.ExternalID,
 		&user.Role,
+		&user.EmailVerified,
 		&user.LastTrackID,
 		&user.LastPosition,
 		&user.VolumePreference,
@@ -113,7 +116,7 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id int) (*models.User,
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
@@ -121,50 +124,78 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id int) (*models.User,
 	return &user, nil
 }
 
-// CreateOAuthUser creates a new user via OAuth
-func (r *UserRepository) CreateOAuthUser(ctx context.Context, user *models.User) error {
+// oauthAccountExternalIDKey is the constraint that lets a provider account be linked to one user only
+const oauthAccountExternalIDKey = "user_oauth_accounts_provider_external_id_key"
+
+// CreateOAuthUser creates a user signed up via the OAuth provider user.Provider
+// together with its linked provider account, in one statement
+func (r *UserRepository) CreateOAuthUser(ctx context.Context, user *models.User, externalID string) error {
 	query := `
-		INSERT INTO users (email, password_hash, provider, external_id)
-		VALUES ($1, NULL, $2, $3)
-		RETURNING id, created_at, role
+		WITH created AS (
+			INSERT INTO users (email, name, avatar_key, password_hash, provider, role, email_verified)
+			VALUES ($1, $2, $3, NULL, $4, $5, $6)
+			RETURNING id, email, name, avatar_key, password_hash, provider, role, email_verified,
+			          last_track_id, last_position, volume_preference, created_at, last_login_at
+		), linked AS (
+			INSERT INTO user_oauth_accounts (user_id, provider, external_id)
+			SELECT id, $4, $7 FROM created
+		)
+		SELECT * FROM created
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, user.Email, user.Provider, user.ExternalID).Scan(
+	err := r.db.Pool.QueryRow(ctx, query, user.Email, user.Name, user.AvatarKey, user.Provider, user.Role, user.EmailVerified, externalID).Scan(
 		&user.ID,
-		&user.CreatedAt,
+		&user.Email,
+		&user.Name,
+		&user.AvatarKey,
+		&user.PasswordHash,
+		&user.Provider,
 		&user.Role,
+		&user.EmailVerified,
+		&user.LastTrackID,
+		&user.LastPosition,
+		&user.VolumePreference,
+		&user.CreatedAt,
+		&user.LastLoginAt,
 	)
 
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == oauthAccountExternalIDKey {
+			return ErrOAuthAccountLinked
+		}
 		return fmt.Errorf("failed to create OAuth user: %w", err)
 	}
 
 	return nil
 }
 
-// GetUserByProviderAndExternalID retrieves a user by provider and external ID
+// GetUserByProviderAndExternalID retrieves the user a provider account is linked to
 func (r *UserRepository) GetUserByProviderAndExternalID(ctx context.Context, provider, externalID string) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, provider, external_id, role, created_at, last_login_at
-			FROM users
-			WHERE provider = $1 AND external_id = $2
-		`
+		SELECT u.id, u.email, u.name, u.avatar_key, u.password_hash, u.provider, u.role, u.email_verified, u.created_at, u.last_login_at
+		FROM user_oauth_accounts o
+		JOIN users u ON u.id = o.user_id
+		WHERE o.provider = $1 AND o.external_id = $2
+	`
 
 	var user models.User
 	err := r.db.Pool.QueryRow(ctx, query, provider, externalID).Scan(
 		&user.ID,
 		&user.Email,
+		&user.Name,
+		&user.AvatarKey,
 		&user.PasswordHash,
 		&user.Provider,
-		&user.ExternalID,
 		&user.Role,
+		&user.EmailVerified,
 		&user.CreatedAt,
 		&user.LastLoginAt,
 	)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by provider and external ID: %w", err)
 	}
@@ -176,20 +207,19 @@ func (r *UserRepository) GetUserByProviderAndExternalID(ctx context.Context, pro
 func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
 	query := `
 		UPDATE users
-		SET email = $1, name = $2, avatar_key = $3, provider = $4, external_id = $5, role = $6
-		WHERE id = $7
-		RETURNING id, email, name, avatar_key, password_hash, provider, external_id, role, created_at, last_login_at
+		SET email = $1, name = $2, avatar_key = $3, provider = $4, role = $5, email_verified = $7
+		WHERE id = $6
+		RETURNING id, email, name, avatar_key, password_hash, provider, role, created_at, last_login_at
 	`
 
 	var updatedUser models.User
-	err := r.db.Pool.QueryRow(ctx, query, user.Email, user.Name, user.AvatarKey, user.Provider, user.ExternalID, user.Role, user.ID).Scan(
+	err := r.db.Pool.QueryRow(ctx, query, user.Email, user.Name, user.AvatarKey, user.Provider, user.Role, user.ID, user.EmailVerified).Scan(
 		&updatedUser.ID,
 		&updatedUser.Email,
 		&updatedUser.Name,
 		&updatedUser.AvatarKey,
 		&updatedUser.PasswordHash,
 		&updatedUser.Provider,
-		&updatedUser.ExternalID,
 		&updatedUser.Role,
 		&updatedUser.CreatedAt,
 		&updatedUser.LastLoginAt,
@@ -197,7 +227,7 @@ func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) erro
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return fmt.Errorf("user not found")
+			return ErrUserNotFound
 		}
 		return fmt.Errorf("failed to update user: %w", err)
 	}
@@ -219,47 +249,94 @@ func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID int, lastLo
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
 }
 
-// LinkOAuthAccount links an OAuth account to an existing user
+// LinkOAuthAccount links an OAuth provider account to an existing user,
+// replacing the account of that provider the user had linked before.
+// An account already linked to another user yields ErrOAuthAccountLinked.
 func (r *UserRepository) LinkOAuthAccount(ctx context.Context, userID int, provider, externalID string) error {
 	query := `
-		UPDATE users
-		SET provider = $1, external_id = $2
-		WHERE id = $3
+		INSERT INTO user_oauth_accounts (user_id, provider, external_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, provider) DO UPDATE
+		SET external_id = EXCLUDED.external_id, created_at = CURRENT_TIMESTAMP
 	`
 
-	result, err := r.db.Pool.Exec(ctx, query, provider, externalID, userID)
-	if err != nil {
+	if _, err := r.db.Pool.Exec(ctx, query, userID, provider, externalID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch {
+			case pgErr.Code == "23505" && pgErr.ConstraintName == oauthAccountExternalIDKey:
+				return ErrOAuthAccountLinked
+			case pgErr.Code == "23503":
+				return ErrUserNotFound
+			}
+		}
 		return fmt.Errorf("failed to link OAuth account: %w", err)
 	}
 
+	return nil
+}
+
+// GetUserStats returns how many tracks a user liked and uploaded and how many
+// times their uploads were played; a user without uploads has 0 plays
+func (r *UserRepository) GetUserStats(ctx context.Context, userID int) (*models.UserStats, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM track_likes WHERE user_id = $1),
+			COUNT(t.id),
+			COALESCE(SUM(t.plays_count), 0)
+		FROM tracks t
+		WHERE t.user_id = $1
+	`
+
+	var stats models.UserStats
+	err := r.db.Pool.QueryRow(ctx, query, userID).Scan(
+		&stats.LikedTracksCount,
+		&stats.TotalUploadedTracks,
+		&stats.TotalPlaysOnMyTracks,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// MarkEmailVerified marks a user's email as verified
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID int) error {
+	result, err := r.db.Pool.Exec(ctx, `UPDATE users SET email_verified = true WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
 }
 
-// UpdateUserProfile updates user's name and avatar_key
-func (r *UserRepository) UpdateUserProfile(ctx context.Context, userID int, name, avatarURL *string) error {
+// UpdateUserProfile updates user's name and avatar_key; fields that aren't Set are left unchanged
+func (r *UserRepository) UpdateUserProfile(ctx context.Context, userID int, name, avatarKey models.OptionalString) error {
 	query := `
 		UPDATE users 
-		SET name = $2, avatar_key = $3
+		SET name = CASE WHEN $2 THEN $3 ELSE name END,
+		    avatar_key = CASE WHEN $4 THEN $5 ELSE avatar_key END
 		WHERE id = $1
 	`
 
-	result, err := r.db.Pool.Exec(ctx, query, userID, name, avatarURL)
+	result, err := r.db.Pool.Exec(ctx, query, userID, name.Set, name.Value, avatarKey.Set, avatarKey.Value)
 	if err != nil {
 		return fmt.Errorf("failed to update user profile: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
@@ -279,7 +356,7 @@ func (r *UserRepository) UpdatePlayerState(ctx context.Context, userID int, trac
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
@@ -289,7 +366,7 @@ func (r *UserRepository) UpdatePlayerState(ctx context.Context, userID int, trac
 func (r *UserRepository) GetUserWithLastTrack(ctx context.Context, id int) (*models.User, error) {
 	query := `
 		SELECT 
-			u.id, u.email, u.name, u.avatar_key, u.password_hash, u.provider, u.external_id, u.role,
+			u.id, u.email, u.name, u.avatar_key, u.password_hash, u.provider, u.role, u.email_verified,
 			u.last_track_id, u.last_position, u.volume_preference, u.created_at, u.last_login_at,
 			t.id as track_id, t.title, t.artist, t.album_id, t.duration_seconds, t.audio_file_key,
 			t.plays_count, t.likes_count, t.created_at as track_created_at
@@ -311,8 +388,8 @@ func (r *UserRepository) GetUserWithLastTrack(ctx context.Context, id int) (*mod
 		&user.AvatarKey,
 		&user.PasswordHash,
 		&user.Provider,
-		&user.ExternalID,
 		&user.Role,
+		&user.EmailVerified,
 		&user.LastTrackID,
 		&user.LastPosition,
 		&user.VolumePreference,
@@ -331,7 +408,7 @@ func (r *UserRepository) GetUserWithLastTrack(ctx context.Context, id int) (*mod
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user with last track: %w", err)
 	}
@@ -361,18 +438,231 @@ func (r *UserRepository) GetUserWithLastTrack(ctx context.Context, id int) (*mod
 	return &user, nil
 }
 
-// TrackExists checks if a track exists by ID
-func (r *UserRepository) TrackExists(ctx context.Context, trackID string) (bool, error) {
-	query := `SELECT 1 FROM tracks WHERE id = $1`
+// ListUsers returns a page of users, newest first, optionally filtered by
+// role, and the total number of matching users. Only columns safe to show
+// admins are selected.
+func (r *UserRepository) ListUsers(ctx context.Context, limit, offset int, roleFilter string) ([]models.AdminUser, int, error) {
+	var total int
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM users WHERE ($1 = '' OR role = $1)
+	`, roleFilter).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := `
+		SELECT id, email, name, avatar_key, provider, role, last_login_at, created_at
+		FROM users
+		WHERE ($1 = '' OR role = $1)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, roleFilter, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.AdminUser{}
+	for rows.Next() {
+		var user models.AdminUser
+		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.AvatarKey, &user.Provider, &user.Role, &user.LastLoginAt, &user.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// UpdateUserRole sets a user's role and returns the updated user. Admin rows
+// are locked while the admins are counted, so two concurrent demotions can't
+// both pass the check and leave the service without an admin.
+func (r *UserRepository) UpdateUserRole(ctx context.Context, userID int, role string) (*models.AdminUser, error) {
+	if !slices.Contains(models.UserRoles, role) {
+		return nil, fmt.Errorf("%w %q", ErrInvalidRole, role)
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	admins, err := lockAdmins(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if admins[userID] && len(admins) == 1 && role != models.RoleAdmin {
+		return nil, ErrLastAdmin
+	}
+
+	var user models.AdminUser
+	err = tx.QueryRow(ctx, `
+		UPDATE users SET role = $2
+		WHERE id = $1
+		RETURNING id, email, name, avatar_key, provider, role, last_login_at, created_at
+	`, userID, role).Scan(&user.ID, &user.Email, &user.Name, &user.AvatarKey, &user.Provider, &user.Role, &user.LastLoginAt, &user.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &user, nil
+}
+
+// lockAdmins locks the rows of all admins until tx ends and returns their IDs
+func lockAdmins(ctx context.Context, tx pgx.Tx) (map[int]bool, error) {
+	rows, err := tx.Query(ctx, `SELECT id FROM users WHERE role = $1 FOR UPDATE`, models.RoleAdmin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock admins: %w", err)
+	}
+	defer rows.Close()
+
+	admins := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan admin id: %w", err)
+		}
+		admins[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating admins: %w", err)
+	}
+	return admins, nil
+}
+
+// DeleteUser removes a user together with their likes and uploaded tracks in
+// one transaction and returns the storage objects that belonged to them.
+// Deleting the only admin fails with ErrLastAdmin.
+func (r *UserRepository) DeleteUser(ctx context.Context, userID int) (*models.UserFiles, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	admins, err := lockAdmins(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if admins[userID] && len(admins) == 1 {
+		return nil, ErrLastAdmin
+	}
+
+	var files models.UserFiles
+	err = tx.QueryRow(ctx, `SELECT avatar_key FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&files.AvatarKey)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `SELECT audio_file_key, cover_image_key, renditions FROM tracks WHERE user_id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tracks: %w", err)
+	}
+	for rows.Next() {
+		var audioKey string
+		var coverKey *string
+		var renditions map[string]string
+		if err := rows.Scan(&audioKey, &coverKey, &renditions); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan user track: %w", err)
+		}
+		files.AudioKeys = append(files.AudioKeys, audioKey)
+		for _, key := range renditions {
+			files.AudioKeys = append(files.AudioKeys, key)
+		}
+		if coverKey != nil {
+			files.CoverKeys = append(files.CoverKeys, *coverKey)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user tracks: %w", err)
+	}
+
+	// likes_count is maintained by hand, so take the user's likes back out of it
+	_, err = tx.Exec(ctx, `
+		UPDATE tracks SET likes_count = GREATEST(likes_count - 1, 0)
+		WHERE id IN (SELECT track_id FROM track_likes WHERE user_id = $1)
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update likes count: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM track_likes WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete likes: %w", err)
+	}
+
+	// last_track_id has no foreign key, so other users' player state must be cleared by hand
+	_, err = tx.Exec(ctx, `
+		UPDATE users SET last_track_id = NULL, last_position = 0
+		WHERE last_track_id IN (SELECT id FROM tracks WHERE user_id = $1)
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset player state: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM tracks WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete tracks: %w", err)
+	}
+
+	// Refresh tokens and play history go with the user via ON DELETE CASCADE
+	if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &files, nil
+}
+
+// TrackDuration returns the stored duration of a track in seconds, or
+// ErrTrackNotFound when the track doesn't exist
+func (r *UserRepository) TrackDuration(ctx context.Context, trackID string) (int, error) {
+	query := `SELECT COALESCE(duration_seconds, 0) FROM tracks WHERE id = $1`
 
-	var exists int
-	err := r.db.Pool.QueryRow(ctx, query, trackID).Scan(&exists)
+	var duration int
+	err := r.db.Pool.QueryRow(ctx, query, trackID).Scan(&duration)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return false, nil
+			return 0, ErrTrackNotFound
 		}
-		return false, fmt.Errorf("failed to check if track exists: %w", err)
+		return 0, fmt.Errorf("failed to get track duration: %w", err)
+	}
+
+	return duration, nil
+}
+
+// DeleteStaleGuests removes guest users inactive for longer than olderThan
+// that have no liked or uploaded tracks. Related rows are removed by ON DELETE CASCADE.
+func (r *UserRepository) DeleteStaleGuests(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := `
+		DELETE FROM users u
+		WHERE u.role = 'guest'
+		  AND COALESCE(u.last_login_at, u.created_at) < $1
+		  AND NOT EXISTS (SELECT 1 FROM track_likes tl WHERE tl.user_id = u.id)
+		  AND NOT EXISTS (SELECT 1 FROM tracks t WHERE t.user_id = u.id)
+	`
+
+	result, err := r.db.Pool.Exec(ctx, query, time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale guests: %w", err)
 	}
 
-	return true, nil
+	return result.RowsAffected(), nil
 }
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/repository"
+)
+
+type ActivityService struct {
+	activityRepo *repository.ActivityRepository
+	logger       *slog.Logger
+}
+
+func NewActivityService(activityRepo *repository.ActivityRepository, log *slog.Logger) *ActivityService {
+	return &ActivityService{
+		activityRepo: activityRepo,
+		logger:       log,
+	}
+}
+
+// ListActivity returns a page of the admin activity feed with the total number of matching entries
+func (s *ActivityService) ListActivity(ctx context.Context, page, limit int, filter models.ActivityFilter) ([]models.Activity, int, error) {
+	offset := (page - 1) * limit
+
+	activities, err := s.activityRepo.List(ctx, limit, offset, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list activity: %w", err)
+	}
+
+	total, err := s.activityRepo.Count(ctx, filter)
+	if err != nil {
+		s.logger.Error("Failed to count activity", "error", err)
+		total = len(activities) // Fallback to page length if count fails
+	}
+
+	return activities, total, nil
+}
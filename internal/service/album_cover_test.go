@@ -0,0 +1,74 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math/rand"
+	"mime/multipart"
+	"testing"
+)
+
+// memFile is an in-memory multipart.File
+type memFile struct{ *bytes.Reader }
+
+func (memFile) Close() error { return nil }
+
+func TestPrepareCoverKeepsSmallerOriginal(t *testing.T) {
+	s := &AlbumService{coverFormat: "webp", coverQuality: 90}
+
+	flat := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	noise := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	rng := rand.New(rand.NewSource(1))
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(rng.Intn(256))
+	}
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			flat.Set(x, y, color.RGBA{R: 200, G: 40, B: 90, A: 255})
+		}
+	}
+
+	var pngData, jpegData bytes.Buffer
+	// An uncompressed PNG of one color shrinks a lot as WebP; noise as JPEG doesn't
+	if err := (&png.Encoder{CompressionLevel: png.NoCompression}).Encode(&pngData, flat); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	if err := jpeg.Encode(&jpegData, noise, &jpeg.Options{Quality: 50}); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+
+	tests := []struct {
+		name, filename, contentType string
+		data                        []byte
+		wantExt                     string
+	}{
+		{"transcodes when smaller", "cover.png", "image/png", pngData.Bytes(), ".webp"},
+		{"keeps original when not smaller", "cover.jpg", "image/jpeg", jpegData.Bytes(), ".jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := &multipart.FileHeader{Filename: tt.filename, Size: int64(len(tt.data))}
+			r, size, ext, err := s.prepareCover(memFile{bytes.NewReader(tt.data)}, header, tt.contentType)
+			if err != nil {
+				t.Fatalf("prepareCover: %v", err)
+			}
+			if ext != tt.wantExt {
+				t.Errorf("ext = %q, want %q", ext, tt.wantExt)
+			}
+			stored, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("read cover: %v", err)
+			}
+			if int64(len(stored)) != size || size > int64(len(tt.data)) {
+				t.Errorf("stored %d bytes, size %d, original %d", len(stored), size, len(tt.data))
+			}
+			if tt.wantExt != ".webp" && !bytes.Equal(stored, tt.data) {
+				t.Error("original cover was not stored unchanged")
+			}
+		})
+	}
+}
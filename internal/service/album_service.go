@@ -1,11 +1,23 @@
 package service
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
+	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,57 +26,140 @@ import (
 	"koteyye_music_be/internal/models"
 	"koteyye_music_be/internal/repository"
 	"koteyye_music_be/pkg/audio"
+	"koteyye_music_be/pkg/filetype"
+	imagePkg "koteyye_music_be/pkg/image"
 	minioPkg "koteyye_music_be/pkg/minio"
 )
 
+// ErrAlbumNotFound is returned when an album does not exist
+var ErrAlbumNotFound = repository.ErrAlbumNotFound
+
+// ErrAlbumExists is returned when creating an album that matches an existing one
+var ErrAlbumExists = errors.New("album with this title, artist and release date already exists")
+
+// ErrDuplicateTrack is returned when the uploaded audio is already in the album
+var ErrDuplicateTrack = repository.ErrDuplicateTrack
+
+// ErrInvalidIdempotencyKey is returned for an Idempotency-Key longer than maxIdempotencyKeyLength
+var ErrInvalidIdempotencyKey = errors.New("idempotency key must be at most 255 characters")
+
+// ErrIdempotencyKeyInProgress is returned when an upload with the same key hasn't finished yet
+var ErrIdempotencyKeyInProgress = errors.New("an upload with this idempotency key is still in progress")
+
+// ErrIdempotencyKeyReused is returned when a key is sent again for a different album
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used for another album")
+
+// maxIdempotencyKeyLength matches the upload_idempotency_keys column size
+const maxIdempotencyKeyLength = 255
+
+// ErrInvalidGenre is returned for a genre that doesn't map to one of models.AllowedGenres
+var ErrInvalidGenre = errors.New("invalid genre")
+
+// invalidGenreError describes an unknown genre together with the canonical genres
+func invalidGenreError(genre string) error {
+	return fmt.Errorf("%w %q. Allowed genres: %s", ErrInvalidGenre, genre, strings.Join(models.AllowedGenres, ", "))
+}
+
 type AlbumService struct {
 	albumRepo *repository.AlbumRepository
 	trackRepo *repository.TrackRepository
-	minioSvc  *minioPkg.Service
+	// idempotencyKeys remembers retried track uploads
+	idempotencyKeys *repository.IdempotencyKeyRepository
+	minioSvc        *minioPkg.Service
+	// thumbnails caches resized covers that must be dropped when a cover changes
+	thumbnails *ThumbnailService
+	// tracks transcodes non-MP3 uploads
+	tracks *TrackService
+	logger *slog.Logger
+	// coverFormat is "webp" to transcode uploaded covers, anything else stores them as-is
+	coverFormat  string
+	coverQuality int
+	// publicBaseURL prefixes the stream links of exported playlists
+	publicBaseURL string
+	// idempotencyTTL is how long a processed upload key is replayed
+	idempotencyTTL time.Duration
+	// uploads lets shutdown wait for album and track uploads
+	uploads *uploadTracker
 }
 
-func NewAlbumService(albumRepo *repository.AlbumRepository, trackRepo *repository.TrackRepository, minioSvc *minioPkg.Service) *AlbumService {
+func NewAlbumService(albumRepo *repository.AlbumRepository, trackRepo *repository.TrackRepository, idempotencyKeys *repository.IdempotencyKeyRepository, minioSvc *minioPkg.Service, thumbnails *ThumbnailService, tracks *TrackService, coverFormat string, coverQuality int, publicBaseURL string, idempotencyTTL time.Duration, log *slog.Logger) *AlbumService {
 	return &AlbumService{
-		albumRepo: albumRepo,
-		trackRepo: trackRepo,
-		minioSvc:  minioSvc,
+		albumRepo:       albumRepo,
+		trackRepo:       trackRepo,
+		idempotencyKeys: idempotencyKeys,
+		idempotencyTTL:  idempotencyTTL,
+		minioSvc:        minioSvc,
+		thumbnails:      thumbnails,
+		tracks:          tracks,
+		logger:          log,
+		coverFormat:     coverFormat,
+		coverQuality:    coverQuality,
+		publicBaseURL:   publicBaseURL,
+		uploads:         newUploadTracker(minioSvc, log),
 	}
 }
 
+// Close rejects new uploads and waits for running ones until ctx is done;
+// objects of uploads still running then are deleted
+func (s *AlbumService) Close(ctx context.Context) error {
+	return s.uploads.Close(ctx)
+}
+
 func (s *AlbumService) CreateAlbum(ctx context.Context, req *models.AlbumCreate, coverFile multipart.File, coverHeader *multipart.FileHeader) (*models.AlbumResponse, error) {
-	// Validate genre
-	if !models.IsValidGenre(req.Genre) {
-		return nil, fmt.Errorf("invalid genre: %s. Allowed genres: %v", req.Genre, models.AllowedGenres)
+	// Validate and normalize genre
+	normalizedGenre, ok := models.NormalizeGenre(req.Genre)
+	if !ok {
+		return nil, invalidGenreError(req.Genre)
 	}
 
-	// Normalize genre to lowercase
-	normalizedGenre := strings.ToLower(req.Genre)
+	// Parse release date
+	releaseDate, err := time.Parse("2006-01-02", req.ReleaseDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release date format. Use YYYY-MM-DD: %w", err)
+	}
+
+	// A retried request must not create the album twice
+	if !req.Force {
+		existing, err := s.albumRepo.FindByTitleArtist(ctx, req.Title, req.Artist, releaseDate)
+		if err == nil {
+			return nil, fmt.Errorf("%w (id %s)", ErrAlbumExists, existing.ID)
+		}
+		if !errors.Is(err, repository.ErrAlbumNotFound) {
+			return nil, err
+		}
+	}
 
 	// Validate file type
 	if !isValidImageFile(coverHeader.Filename) {
 		return nil, fmt.Errorf("invalid cover image format. Allowed: jpg, jpeg, png")
 	}
+	contentType, err := filetype.Verify(coverFile, filetype.CategoryImage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cover image: %w", err)
+	}
+
+	coverData, coverSize, coverExt, err := s.prepareCover(coverFile, coverHeader, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	upload, ctx, err := s.uploads.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer upload.end()
 
 	// Generate album ID and cover path
 	albumID := uuid.New().String()
-	coverExt := filepath.Ext(coverHeader.Filename)
-	if coverExt == "" {
-		coverExt = ".jpg"
-	}
 	coverKey := fmt.Sprintf("albums/%s/cover%s", albumID, coverExt)
+	upload.writes(s.minioSvc.Buckets().Image, fmt.Sprintf("albums/%s/", albumID))
 
 	// Upload cover to MinIO
-	_, err := s.minioSvc.UploadFile(ctx, "music-files", coverKey, coverFile, coverHeader.Size)
+	_, err = s.minioSvc.UploadFile(ctx, s.minioSvc.Buckets().Image, coverKey, coverData, coverSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload cover image: %w", err)
 	}
 
-	// Parse release date
-	releaseDate, err := time.Parse("2006-01-02", req.ReleaseDate)
-	if err != nil {
-		return nil, fmt.Errorf("invalid release date format. Use YYYY-MM-DD: %w", err)
-	}
-
 	// Create album record
 	album := &models.Album{
 		ID:            albumID,
@@ -80,30 +175,40 @@ func (s *AlbumService) CreateAlbum(ctx context.Context, req *models.AlbumCreate,
 	err = s.albumRepo.Create(ctx, album)
 	if err != nil {
 		// Cleanup uploaded cover on database error
-		s.minioSvc.DeleteFile(ctx, "music-files", coverKey)
+		s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Image, coverKey)
 		return nil, fmt.Errorf("failed to create album: %w", err)
 	}
+	upload.commit()
 
 	// Generate BE endpoint URL for cover
 	coverURL := fmt.Sprintf("/albums/%s/cover", albumID)
 	year := releaseDate.Year()
 
 	return &models.AlbumResponse{
-		ID:          albumID,
-		Title:       req.Title,
-		Artist:      req.Artist,
-		ReleaseDate: releaseDate.Format("2006-01-02"),
-		Genre:       normalizedGenre,
-		CoverURL:    coverURL,
-		Year:        year,
-		CreatedAt:   album.CreatedAt,
+		ID:                   albumID,
+		Title:                req.Title,
+		Artist:               req.Artist,
+		ReleaseDate:          releaseDate.Format("2006-01-02"),
+		Genre:                normalizedGenre,
+		CoverURL:             coverURL,
+		Year:                 year,
+		TrackCount:           album.TrackCount,
+		TotalDurationSeconds: album.TotalDurationSeconds,
+		IsFeatured:           album.IsFeatured,
+		FeaturedOrder:        album.FeaturedOrder,
+		Status:               models.StatusDraft, // New albums stay hidden until published
+		CreatedAt:            album.CreatedAt,
 	}, nil
 }
 
-func (s *AlbumService) GetAlbumByID(ctx context.Context, id string) (*models.AlbumResponse, error) {
+// GetAlbumByID returns an album; without includeDrafts a draft album is reported as ErrAlbumNotFound
+func (s *AlbumService) GetAlbumByID(ctx context.Context, id string, includeDrafts bool) (*models.AlbumResponse, error) {
 	album, err := s.albumRepo.GetByID(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("album not found: %w", err)
+		return nil, fmt.Errorf("failed to get album: %w", err)
+	}
+	if !includeDrafts && album.Status != models.StatusPublished {
+		return nil, fmt.Errorf("album %s is a draft: %w", id, ErrAlbumNotFound)
 	}
 
 	// Generate BE endpoint URL for cover
@@ -114,14 +219,19 @@ func (s *AlbumService) GetAlbumByID(ctx context.Context, id string) (*models.Alb
 	year := album.ReleaseDate.Year()
 
 	return &models.AlbumResponse{
-		ID:          album.ID,
-		Title:       album.Title,
-		Artist:      album.Artist,
-		ReleaseDate: releaseDateStr,
-		Genre:       album.Genre,
-		CoverURL:    coverURL,
-		Year:        year,
-		CreatedAt:   album.CreatedAt,
+		ID:                   album.ID,
+		Title:                album.Title,
+		Artist:               album.Artist,
+		ReleaseDate:          releaseDateStr,
+		Genre:                album.Genre,
+		CoverURL:             coverURL,
+		Year:                 year,
+		TrackCount:           album.TrackCount,
+		TotalDurationSeconds: album.TotalDurationSeconds,
+		IsFeatured:           album.IsFeatured,
+		FeaturedOrder:        album.FeaturedOrder,
+		Status:               album.Status,
+		CreatedAt:            album.CreatedAt,
 	}, nil
 }
 
@@ -130,35 +240,131 @@ func (s *AlbumService) GetAlbumRaw(ctx context.Context, id string) (*models.Albu
 	return s.albumRepo.GetByID(ctx, id)
 }
 
-func (s *AlbumService) GetAllAlbums(ctx context.Context, limit, offset int, genreFilter string) ([]models.AlbumResponse, error) {
-	albums, err := s.albumRepo.GetAll(ctx, limit, offset, genreFilter)
+// GetAllAlbums returns a page of albums matching the filter and the total number of matches
+func (s *AlbumService) GetAllAlbums(ctx context.Context, limit, offset int, filter models.AlbumFilter) ([]models.AlbumResponse, int, error) {
+	albums, err := s.albumRepo.GetAll(ctx, limit, offset, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get albums: %w", err)
+	}
+
+	total, err := s.albumRepo.CountAll(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count albums: %w", err)
+	}
+
+	return toAlbumResponses(albums), total, nil
+}
+
+// GetRecentlyPlayedAlbums returns a page of albums the user recently played tracks from,
+// most recent first, and the total number of such albums
+func (s *AlbumService) GetRecentlyPlayedAlbums(ctx context.Context, userID, limit, offset int) ([]models.AlbumResponse, int, error) {
+	albums, err := s.albumRepo.GetRecentlyPlayed(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get recently played albums: %w", err)
+	}
+
+	total, err := s.albumRepo.CountRecentlyPlayed(ctx, userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count recently played albums: %w", err)
+	}
+
+	return toAlbumResponses(albums), total, nil
+}
+
+// GetFeaturedAlbums returns up to limit featured albums in display order
+func (s *AlbumService) GetFeaturedAlbums(ctx context.Context, limit int) ([]models.AlbumResponse, error) {
+	albums, err := s.albumRepo.GetFeatured(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get featured albums: %w", err)
+	}
+	return toAlbumResponses(albums), nil
+}
+
+// GetSimilarAlbums returns up to limit albums by the same artist or in the same
+// genre as albumID, or ErrAlbumNotFound if the album doesn't exist or is a draft
+func (s *AlbumService) GetSimilarAlbums(ctx context.Context, albumID string, limit int) ([]models.AlbumResponse, error) {
+	if _, err := s.GetAlbumByID(ctx, albumID, false); err != nil {
+		return nil, err
+	}
+
+	albums, err := s.albumRepo.GetSimilarAlbums(ctx, albumID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get similar albums: %w", err)
+	}
+	return toAlbumResponses(albums), nil
+}
+
+// ErrArtistNotFound is returned for an artist with no albums or tracks
+var ErrArtistNotFound = errors.New("artist not found")
+
+// GetAlbumsByArtist returns a page of the artist's albums, the total number of
+// them and the number of tracks attributed to the artist
+func (s *AlbumService) GetAlbumsByArtist(ctx context.Context, artist string, limit, offset int) ([]models.AlbumResponse, int, int, error) {
+	albums, total, err := s.albumRepo.GetAlbumsByArtist(ctx, artist, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get albums: %w", err)
+		return nil, 0, 0, fmt.Errorf("failed to get artist albums: %w", err)
+	}
+
+	tracksCount, err := s.trackRepo.CountTracks(ctx, models.TrackFilter{Artist: artist})
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to count artist tracks: %w", err)
+	}
+
+	if total == 0 && tracksCount == 0 {
+		return nil, 0, 0, ErrArtistNotFound
+	}
+
+	return toAlbumResponses(albums), total, tracksCount, nil
+}
+
+// ErrInvalidFeaturedOrder is returned for a featured position below 1
+var ErrInvalidFeaturedOrder = errors.New("featured order must be at least 1")
+
+// SetAlbumFeatured adds an album to or removes it from the featured section and returns the updated album
+func (s *AlbumService) SetAlbumFeatured(ctx context.Context, albumID string, req *models.AlbumFeaturedRequest) (*models.AlbumResponse, error) {
+	if req.Order != nil && *req.Order < 1 {
+		return nil, ErrInvalidFeaturedOrder
 	}
 
-	var responses []models.AlbumResponse
+	if err := s.albumRepo.SetFeatured(ctx, albumID, req.Featured, req.Order); err != nil {
+		return nil, fmt.Errorf("failed to update album: %w", err)
+	}
+
+	s.logger.Info("Album featured flag updated", "album_id", albumID, "featured", req.Featured)
+	return s.GetAlbumByID(ctx, albumID, true)
+}
+
+// toAlbumResponses converts albums to list responses with cover URLs
+func toAlbumResponses(albums []models.Album) []models.AlbumResponse {
+	responses := make([]models.AlbumResponse, 0, len(albums))
 	for _, album := range albums {
 		coverURL := fmt.Sprintf("/api/albums/%s/cover", album.ID)
 		releaseDateStr := album.ReleaseDate.Format("2006-01-02")
 		year := album.ReleaseDate.Year()
 
 		responses = append(responses, models.AlbumResponse{
-			ID:          album.ID,
-			Title:       album.Title,
-			Artist:      album.Artist,
-			ReleaseDate: releaseDateStr,
-			Genre:       album.Genre,
-			CoverURL:    coverURL,
-			Year:        year,
-			CreatedAt:   album.CreatedAt,
+			ID:                   album.ID,
+			Title:                album.Title,
+			Artist:               album.Artist,
+			ReleaseDate:          releaseDateStr,
+			Genre:                album.Genre,
+			CoverURL:             coverURL,
+			Year:                 year,
+			TrackCount:           album.TrackCount,
+			TotalDurationSeconds: album.TotalDurationSeconds,
+			IsFeatured:           album.IsFeatured,
+			FeaturedOrder:        album.FeaturedOrder,
+			Status:               album.Status,
+			CreatedAt:            album.CreatedAt,
 		})
 	}
-
-	return responses, nil
+	return responses
 }
 
-func (s *AlbumService) GetAlbumWithTracks(ctx context.Context, albumID string) (*models.AlbumDetail, error) {
-	albumDetail, err := s.albumRepo.GetAlbumWithTracks(ctx, albumID)
+// GetAlbumWithTracks returns album details with tracks; userID 0 means anonymous.
+// Drafts are only shown with includeDrafts.
+func (s *AlbumService) GetAlbumWithTracks(ctx context.Context, albumID string, userID int, includeDrafts bool) (*models.AlbumDetail, error) {
+	albumDetail, err := s.albumRepo.GetAlbumWithTracks(ctx, albumID, userID, includeDrafts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get album with tracks: %w", err)
 	}
@@ -177,11 +383,59 @@ func (s *AlbumService) GetAlbumWithTracks(ctx context.Context, albumID string) (
 	return albumDetail, nil
 }
 
+// ErrTrackOrderMismatch is returned when a new track order doesn't list exactly the album's tracks
+var ErrTrackOrderMismatch = repository.ErrTrackOrderMismatch
+
+// ReorderTracks numbers the album's tracks in the order of trackIDs and returns the updated album
+func (s *AlbumService) ReorderTracks(ctx context.Context, albumID string, trackIDs []string) (*models.AlbumDetail, error) {
+	if _, err := s.albumRepo.GetByID(ctx, albumID); err != nil {
+		return nil, fmt.Errorf("failed to get album: %w", err)
+	}
+
+	seen := make(map[string]bool, len(trackIDs))
+	order := make([]models.TrackOrder, 0, len(trackIDs))
+	for i, rawID := range trackIDs {
+		parsed, err := uuid.Parse(rawID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid track ID %q", ErrTrackOrderMismatch, rawID)
+		}
+		id := parsed.String()
+		if seen[id] {
+			return nil, fmt.Errorf("%w: track %s is listed twice", ErrTrackOrderMismatch, id)
+		}
+		seen[id] = true
+		order = append(order, models.TrackOrder{TrackID: id, Number: i + 1})
+	}
+
+	if err := s.albumRepo.UpdateTrackOrder(ctx, albumID, order); err != nil {
+		if errors.Is(err, ErrTrackOrderMismatch) {
+			// The message explains what is wrong with the list, keep it as is
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to reorder tracks: %w", err)
+	}
+
+	s.logger.Info("Album tracks reordered", "album_id", albumID, "tracks", len(order))
+	return s.GetAlbumWithTracks(ctx, albumID, 0, true)
+}
+
+// PublishAlbum makes a draft album and its draft tracks public and returns the
+// album. Tracks can be drafted again one by one afterwards.
+func (s *AlbumService) PublishAlbum(ctx context.Context, albumID string) (*models.AlbumDetail, error) {
+	published, err := s.albumRepo.Publish(ctx, albumID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish album: %w", err)
+	}
+
+	s.logger.Info("Album published", "album_id", albumID, "tracks_published", published)
+	return s.GetAlbumWithTracks(ctx, albumID, 0, true)
+}
+
 func (s *AlbumService) DeleteAlbum(ctx context.Context, albumID string) error {
 	// Verify album exists before deletion
 	_, err := s.albumRepo.GetByID(ctx, albumID)
 	if err != nil {
-		return fmt.Errorf("album not found: %w", err)
+		return fmt.Errorf("failed to get album: %w", err)
 	}
 
 	// Delete album from database (this will cascade delete tracks)
@@ -190,42 +444,211 @@ func (s *AlbumService) DeleteAlbum(ctx context.Context, albumID string) error {
 		return fmt.Errorf("failed to delete album: %w", err)
 	}
 
-	// Delete album folder from MinIO (includes cover and all tracks)
+	// Delete album folder from MinIO (cover and tracks) in every bucket it spans
 	folderPath := fmt.Sprintf("albums/%s/", albumID)
-	err = s.minioSvc.DeleteFolder(ctx, "music-files", folderPath)
+	buckets := s.minioSvc.Buckets()
+	for _, bucket := range slices.Compact([]string{buckets.Image, buckets.Audio}) {
+		err = s.minioSvc.DeleteFolder(ctx, bucket, folderPath)
+		if err != nil {
+			// Log error but don't fail - database deletion already succeeded
+			// In production, this should be handled by a cleanup job
+			s.logger.Warn("Failed to delete album folder from storage", "album_id", albumID, "bucket", bucket, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateAlbumCover replaces the cover image of an existing album.
+// The previous object is removed only after the database points to the new key,
+// so a failed update never leaves the album without a cover.
+func (s *AlbumService) UpdateAlbumCover(ctx context.Context, albumID string, coverFile multipart.File, coverHeader *multipart.FileHeader) (*models.AlbumResponse, error) {
+	// Verify album exists
+	album, err := s.albumRepo.GetByID(ctx, albumID)
 	if err != nil {
-		// Log error but don't fail - database deletion already succeeded
-		// In production, this should be handled by a cleanup job
-		fmt.Printf("Warning: failed to delete album folder from storage: %v\n", err)
+		return nil, fmt.Errorf("failed to get album: %w", err)
+	}
+
+	// Validate file type
+	if !isValidImageFile(coverHeader.Filename) {
+		return nil, fmt.Errorf("invalid cover image format. Allowed: jpg, jpeg, png")
+	}
+	contentType, err := filetype.Verify(coverFile, filetype.CategoryImage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cover image: %w", err)
+	}
+
+	coverData, coverSize, coverExt, err := s.prepareCover(coverFile, coverHeader, contentType)
+	if err != nil {
+		return nil, err
+	}
+	coverKey := fmt.Sprintf("albums/%s/cover%s", albumID, coverExt)
+
+	// Upload new cover to MinIO
+	if _, err := s.minioSvc.UploadFile(ctx, s.minioSvc.Buckets().Image, coverKey, coverData, coverSize); err != nil {
+		return nil, fmt.Errorf("failed to upload cover image: %w", err)
+	}
+
+	oldKey, err := s.albumRepo.UpdateCoverKey(ctx, albumID, coverKey)
+	if err != nil {
+		// Cleanup uploaded cover on database error, unless it overwrote the current one
+		if coverKey != album.CoverImageKey {
+			s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Image, coverKey)
+		}
+		return nil, fmt.Errorf("failed to update album cover: %w", err)
+	}
+
+	// Remove the previous cover only when it lived under a different key
+	if oldKey != "" && oldKey != coverKey {
+		if err := s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Image, oldKey); err != nil {
+			s.logger.Warn("Failed to delete old album cover", "album_id", albumID, "key", oldKey, "error", err)
+		}
+	}
+
+	// Resized variants were made from the old cover
+	s.thumbnails.DeleteCoverThumbnails(ctx, album.CoverImageKey)
+
+	return s.GetAlbumByID(ctx, albumID, true)
+}
+
+// ExportAlbum writes a zip archive with the audio files of albumDetail's
+// tracks to w. Each object is streamed from MinIO straight into the archive,
+// so the whole album is never held in memory. Tracks whose audio is missing
+// are skipped.
+func (s *AlbumService) ExportAlbum(ctx context.Context, albumDetail *models.AlbumDetail, w io.Writer) error {
+	albumID := albumDetail.Album.ID
+	zipWriter := zip.NewWriter(w)
+	usedNames := make(map[string]int)
+
+	for i, track := range albumDetail.Tracks {
+		if _, err := s.minioSvc.GetObjectInfo(ctx, s.minioSvc.Buckets().Audio, track.AudioFileKey); err != nil {
+			s.logger.Warn("Skipping track with missing audio in album export",
+				"album_id", albumID, "track_id", track.ID, "audio_key", track.AudioFileKey, "error", err)
+			continue
+		}
+
+		object, err := s.minioSvc.GetObject(ctx, s.minioSvc.Buckets().Audio, track.AudioFileKey)
+		if err != nil {
+			s.logger.Warn("Skipping track that failed to open in album export",
+				"album_id", albumID, "track_id", track.ID, "error", err)
+			continue
+		}
+
+		name := exportEntryName(i+1, track.Title, filepath.Ext(track.AudioFileKey), usedNames)
+		entry, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Store, // Audio is already compressed
+			Modified: track.CreatedAt,
+		})
+		if err != nil {
+			object.Close()
+			return fmt.Errorf("failed to create zip entry: %w", err)
+		}
+
+		_, err = io.Copy(entry, object)
+		object.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write track %s to zip: %w", track.ID, err)
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finalize zip: %w", err)
 	}
 
 	return nil
 }
 
+// ExportAlbumM3U returns the album and an extended M3U playlist of its tracks
+// with absolute stream URLs
+func (s *AlbumService) ExportAlbumM3U(ctx context.Context, albumID string) (*models.AlbumResponse, []byte, error) {
+	albumDetail, err := s.albumRepo.GetAlbumWithTracks(ctx, albumID, 0, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get album: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&buf, "#PLAYLIST:%s\n", m3uText(albumDetail.Album.Artist+" - "+albumDetail.Album.Title))
+	for _, track := range albumDetail.Tracks {
+		fmt.Fprintf(&buf, "#EXTINF:%d,%s\n", track.DurationSeconds, m3uText(track.ArtistName+" - "+track.Title))
+		fmt.Fprintf(&buf, "%s/api/tracks/%s/stream\n", s.publicBaseURL, track.ID)
+	}
+
+	return &albumDetail.Album, buf.Bytes(), nil
+}
+
+// m3uText flattens line breaks, which would end an M3U directive early
+func m3uText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// exportEntryName builds a unique "NN - Title.ext" name for a zip entry
+func exportEntryName(number int, title, ext string, usedNames map[string]int) string {
+	title = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if title == "" {
+		title = "Track"
+	}
+	if ext == "" {
+		ext = ".mp3"
+	}
+
+	name := fmt.Sprintf("%02d - %s%s", number, title, ext)
+	usedNames[name]++
+	if count := usedNames[name]; count > 1 {
+		name = fmt.Sprintf("%02d - %s (%d)%s", number, title, count, ext)
+	}
+	return name
+}
+
 // GetCoverImage returns the cover image object from MinIO
 func (s *AlbumService) GetCoverImage(ctx context.Context, coverKey string) (io.ReadCloser, error) {
-	return s.minioSvc.GetObject(ctx, coverKey)
+	return s.minioSvc.GetObject(ctx, s.minioSvc.Buckets().Image, coverKey)
 }
 
 // GetCoverImageInfo returns the cover image info from MinIO
 func (s *AlbumService) GetCoverImageInfo(ctx context.Context, coverKey string) (*minio.ObjectInfo, error) {
-	return s.minioSvc.GetObjectInfo(ctx, coverKey)
+	return s.minioSvc.GetObjectInfo(ctx, s.minioSvc.Buckets().Image, coverKey)
 }
 
-func (s *AlbumService) AddTrackToAlbum(ctx context.Context, albumID string, userID int, req *models.TrackCreate, audioFile multipart.File, audioHeader *multipart.FileHeader) (*models.TrackResponse, error) {
+// AddTrackToAlbum uploads a track into an album. coverFile is optional: when
+// given it becomes the track's own cover, otherwise the album cover is used.
+func (s *AlbumService) AddTrackToAlbum(ctx context.Context, albumID string, userID int, req *models.TrackCreate, audioFile multipart.File, audioHeader *multipart.FileHeader, coverFile multipart.File, coverHeader *multipart.FileHeader) (*models.TrackResponse, error) {
 	// Verify album exists
 	album, err := s.albumRepo.GetByID(ctx, albumID)
 	if err != nil {
-		return nil, fmt.Errorf("album not found: %w", err)
+		return nil, fmt.Errorf("failed to get album: %w", err)
 	}
 
+	return s.addTrack(ctx, album, userID, req, nil, audioFile, audioHeader.Filename, audioHeader.Size, coverFile, coverHeader)
+}
+
+// addTrack validates, uploads and stores one track of album. trackNumber
+// places the track in the album; nil appends it.
+func (s *AlbumService) addTrack(ctx context.Context, album *models.Album, userID int, req *models.TrackCreate, trackNumber *int, audioFile multipart.File, audioName string, audioSize int64, coverFile multipart.File, coverHeader *multipart.FileHeader) (*models.TrackResponse, error) {
+	albumID := album.ID
+
+	upload, ctx, err := s.uploads.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer upload.end()
+
 	// Validate audio file
-	if !isValidAudioFile(audioHeader.Filename) {
+	if !isValidAudioFile(audioName) {
 		return nil, fmt.Errorf("invalid audio format. Allowed: mp3, wav, m4a, flac")
 	}
+	if _, err := filetype.Verify(audioFile, filetype.CategoryAudio); err != nil {
+		return nil, fmt.Errorf("invalid audio file: %w", err)
+	}
 
 	// Extract metadata from audio file (duration, format, etc.)
-	metadata, err := audio.ExtractMetadata(audioFile)
+	metadata, err := audio.ExtractMetadata(audioFile, s.tracks.tempDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract audio metadata: %w", err)
 	}
@@ -235,15 +658,82 @@ func (s *AlbumService) AddTrackToAlbum(ctx context.Context, albumID string, user
 		return nil, fmt.Errorf("invalid audio format detected: %s", metadata.Format)
 	}
 
-	// Generate track ID and audio path
+	// Validate the optional track cover before anything is uploaded
+	var coverData io.Reader
+	var coverSize int64
+	var coverExt string
+	if coverFile != nil {
+		if !isValidImageFile(coverHeader.Filename) {
+			return nil, fmt.Errorf("invalid cover image format. Allowed: jpg, jpeg, png")
+		}
+		contentType, err := filetype.Verify(coverFile, filetype.CategoryImage)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cover image: %w", err)
+		}
+		coverData, coverSize, coverExt, err = s.prepareCover(coverFile, coverHeader, contentType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Generate track ID and audio path; renditions and the own cover share the ID prefix
 	trackID := uuid.New().String()
 	audioKey := fmt.Sprintf("albums/%s/%s.mp3", albumID, trackID)
+	upload.writes(s.minioSvc.Buckets().Audio, fmt.Sprintf("albums/%s/%s.", albumID, trackID))
+	upload.writes(s.minioSvc.Buckets().Image, fmt.Sprintf("albums/%s/covers/%s.", albumID, trackID))
+
+	// The hash covers the upload as received, so re-uploading the same
+	// source file is caught even though only the MP3 is stored
+	hasher := sha256.New()
+	audioData := io.TeeReader(audioFile, hasher)
+
+	// Audio is always stored as MP3 under an .mp3 key
+	if metadata.Format != "mp3" {
+		converted, err := s.transcodeToMP3(audioData, path.Ext(audioName), s.tracks.mp3Bitrate)
+		if err != nil {
+			return nil, err
+		}
+		defer converted.Close()
+		audioData, audioSize, metadata = converted.file, converted.size, converted.metadata
+	}
 
-	// Upload audio file to MinIO
-	_, err = s.minioSvc.UploadFile(ctx, "music-files", audioKey, audioFile, audioHeader.Size)
+	bitrate, format, err := audioQuality(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	// Upload audio file to MinIO; for MP3 uploads this is also where the hash is computed
+	_, err = s.minioSvc.UploadFile(ctx, s.minioSvc.Buckets().Audio, audioKey, audioData, audioSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload audio file: %w", err)
 	}
+	audioHash := hex.EncodeToString(hasher.Sum(nil))
+
+	// The hash is only known after the upload, so a duplicate costs one discarded object
+	duplicate, err := s.trackRepo.ExistsInAlbumByHash(ctx, albumID, audioHash)
+	if err != nil {
+		s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Audio, audioKey)
+		return nil, err
+	}
+	if duplicate {
+		s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Audio, audioKey)
+		return nil, ErrDuplicateTrack
+	}
+
+	// Smaller renditions are encoded from the upload rather than the stored MP3
+	renditions := s.uploadRenditions(ctx, albumID, trackID, audioFile, path.Ext(audioName), metadata.BitRate)
+
+	// Upload track cover next to the album's files so album deletion removes it
+	var trackCoverKey *string
+	if coverData != nil {
+		coverKey := fmt.Sprintf("albums/%s/covers/%s%s", albumID, trackID, coverExt)
+		if _, err := s.minioSvc.UploadFile(ctx, s.minioSvc.Buckets().Image, coverKey, coverData, coverSize); err != nil {
+			s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Audio, audioKey)
+			s.deleteRenditions(ctx, renditions)
+			return nil, fmt.Errorf("failed to upload cover image: %w", err)
+		}
+		trackCoverKey = &coverKey
+	}
 
 	// Create track record
 	track := &models.Track{
@@ -254,6 +744,12 @@ func (s *AlbumService) AddTrackToAlbum(ctx context.Context, albumID string, user
 		Artist:          req.Artist,
 		DurationSeconds: metadata.GetDurationSeconds(),
 		AudioFileKey:    audioKey,
+		CoverImageKey:   trackCoverKey,
+		TrackNumber:     trackNumber,
+		AudioSHA256:     audioHash,
+		Renditions:      renditions,
+		Bitrate:         bitrate,
+		Format:          format,
 		PlaysCount:      0,
 		LikesCount:      0,
 		CreatedAt:       time.Now(),
@@ -261,10 +757,23 @@ func (s *AlbumService) AddTrackToAlbum(ctx context.Context, albumID string, user
 
 	err = s.trackRepo.CreateTrack(ctx, track)
 	if err != nil {
-		// Cleanup uploaded audio on database error
-		s.minioSvc.DeleteFile(ctx, "music-files", audioKey)
+		// Cleanup uploaded files on database error
+		s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Audio, audioKey)
+		s.deleteRenditions(ctx, renditions)
+		if trackCoverKey != nil {
+			s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Image, *trackCoverKey)
+		}
+		if errors.Is(err, ErrDuplicateTrack) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to create track: %w", err)
 	}
+	upload.commit()
+
+	coverKey := album.CoverImageKey
+	if trackCoverKey != nil {
+		coverKey = *trackCoverKey
+	}
 
 	// Generate BE endpoint URLs
 	coverURL := fmt.Sprintf("/api/tracks/%s/cover", trackID)
@@ -283,18 +792,325 @@ func (s *AlbumService) AddTrackToAlbum(ctx context.Context, albumID string, user
 		AlbumID:         albumID,
 		AlbumTitle:      album.Title,
 		CoverURL:        coverURL,
+		ImageKey:        &coverKey,
+		CoverImageKey:   coverKey,
 		AudioURL:        audioURL,
 		AudioFileKey:    audioKey,
 		ReleaseDate:     album.ReleaseDate.Format("2006-01-02"),
 		Genre:           album.Genre,
 		DurationSeconds: metadata.GetDurationSeconds(),
+		Bitrate:         bitrate,
+		Format:          format,
 		PlaysCount:      0,
 		LikesCount:      0,
 		IsLiked:         false,
+		Status:          models.StatusDraft, // New tracks stay hidden until published
 		CreatedAt:       track.CreatedAt,
 	}, nil
 }
 
+// BeginIdempotentUpload reserves the Idempotency-Key of a track upload. If an earlier
+// upload with the key already created a track, that track is returned and the upload
+// must be skipped. Otherwise the caller owns the key and must finish it with
+// CompleteIdempotentUpload or ReleaseIdempotentUpload.
+func (s *AlbumService) BeginIdempotentUpload(ctx context.Context, userID int, key, albumID string) (*models.TrackResponse, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, ErrInvalidIdempotencyKey
+	}
+	// A malformed ID can't name an existing album, and the column is a UUID
+	if _, err := uuid.Parse(albumID); err != nil {
+		return nil, fmt.Errorf("invalid album ID format: %w", ErrAlbumNotFound)
+	}
+
+	existing, err := s.idempotencyKeys.Reserve(ctx, userID, key, albumID, time.Now().Add(s.idempotencyTTL))
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+	if existing.AlbumID != albumID {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.TrackID == nil {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	track, err := s.trackRepo.GetTrackWithAlbumInfo(ctx, *existing.TrackID, userID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get track: %w", err)
+	}
+
+	// Same URLs as the original AddTrackToAlbum response
+	track.CoverURL = fmt.Sprintf("/api/tracks/%s/cover", track.ID)
+	track.AudioURL = fmt.Sprintf("/api/tracks/%s/stream", track.ID)
+
+	return track, nil
+}
+
+// CompleteIdempotentUpload stores the track created under a reserved key
+func (s *AlbumService) CompleteIdempotentUpload(ctx context.Context, userID int, key, trackID string) error {
+	return s.idempotencyKeys.Complete(ctx, userID, key, trackID)
+}
+
+// ReleaseIdempotentUpload frees the key of a failed upload so it can be retried
+func (s *AlbumService) ReleaseIdempotentUpload(ctx context.Context, userID int, key string) error {
+	return s.idempotencyKeys.Release(ctx, userID, key)
+}
+
+// convertedAudio is an upload transcoded to MP3 in a temp file
+type convertedAudio struct {
+	file     *os.File
+	size     int64
+	metadata *audio.Metadata
+}
+
+// Close closes and removes the temp file
+func (c *convertedAudio) Close() {
+	c.file.Close()
+	os.Remove(c.file.Name())
+}
+
+// uploadRenditions encodes the configured renditions below sourceBitrate from
+// audioFile and stores them next to the track's audio as
+// albums/{album}/{track}.{quality}.mp3. Renditions are optional, so one that
+// fails is logged and left out; the track then streams in high quality.
+func (s *AlbumService) uploadRenditions(ctx context.Context, albumID, trackID string, audioFile io.ReadSeeker, ext string, sourceBitrate int) map[string]string {
+	renditions := make(map[string]string)
+	for quality, kbps := range s.tracks.renditionsBelow(sourceBitrate) {
+		if _, err := audioFile.Seek(0, io.SeekStart); err != nil {
+			s.logger.Error("Failed to rewind audio for rendition", "track_id", trackID, "quality", quality, "error", err)
+			break
+		}
+
+		converted, err := s.transcodeToMP3(audioFile, ext, kbps)
+		if err != nil {
+			s.logger.Error("Failed to encode audio rendition", "track_id", trackID, "quality", quality, "error", err)
+			continue
+		}
+
+		key := fmt.Sprintf("albums/%s/%s.%s.mp3", albumID, trackID, quality)
+		_, err = s.minioSvc.UploadFile(ctx, s.minioSvc.Buckets().Audio, key, converted.file, converted.size)
+		converted.Close()
+		if err != nil {
+			s.logger.Error("Failed to upload audio rendition", "track_id", trackID, "quality", quality, "error", err)
+			continue
+		}
+		renditions[quality] = key
+	}
+	return renditions
+}
+
+// deleteRenditions removes rendition objects of a track that wasn't saved
+func (s *AlbumService) deleteRenditions(ctx context.Context, renditions map[string]string) {
+	for _, key := range renditions {
+		s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Audio, key)
+	}
+}
+
+// transcodeToMP3 saves src to a temp file with extension ext and converts it
+// to MP3 at bitrate kbps. The caller must Close the result; on error or panic
+// nothing is left on disk.
+func (s *AlbumService) transcodeToMP3(src io.Reader, ext string, bitrate int) (converted *convertedAudio, err error) {
+	input, err := os.CreateTemp(s.tracks.tempDir, "upload-*"+ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(input.Name())
+	defer input.Close()
+
+	if _, err := io.Copy(input, src); err != nil {
+		return nil, fmt.Errorf("failed to save audio file: %w", err)
+	}
+	if err := input.Close(); err != nil {
+		return nil, fmt.Errorf("failed to save audio file: %w", err)
+	}
+
+	// Reserve a name for ffmpeg to write to; it outlives this call only when returned
+	output, err := os.CreateTemp(s.tracks.tempDir, "upload-*.mp3")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	outputPath := output.Name()
+	output.Close()
+	defer func() {
+		if converted == nil {
+			os.Remove(outputPath)
+		}
+	}()
+
+	metadata, err := s.tracks.convertAudioToMP3(input.Name(), outputPath, bitrate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert audio to MP3: %w", err)
+	}
+
+	file, err := os.Open(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open converted audio: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat converted audio: %w", err)
+	}
+
+	return &convertedAudio{file: file, size: info.Size(), metadata: metadata}, nil
+}
+
+// ErrInvalidArchive is returned when a bulk upload is not a readable zip archive
+var ErrInvalidArchive = errors.New("invalid zip archive")
+
+// trackNumberPrefix matches a leading track number in a file name such as
+// "01 - Title", "01. Title", "01 Title" or "1_Title". A separator is
+// required, so a title like "1999" is not read as track 199.
+var trackNumberPrefix = regexp.MustCompile(`^(\d{1,3})(?:\s*[-._)]\s*|\s+)(.+)$`)
+
+// bulkEntry is an audio file found in a bulk upload archive
+type bulkEntry struct {
+	file   *zip.File
+	name   string
+	title  string
+	number *int
+}
+
+// BulkAddTracks adds every audio file of a zip archive to the album. Entries
+// are extracted one at a time to a temp file, so the archive is never
+// unpacked at once. A leading number in the file name ("01 - Title.mp3")
+// becomes the track number and the rest the title; files without one are
+// appended after the numbered ones. A failing entry is reported and doesn't
+// stop the others.
+func (s *AlbumService) BulkAddTracks(ctx context.Context, albumID string, userID int, artist *string, archive io.ReaderAt, archiveSize, maxAudioSize int64) (*models.BulkTrackUploadResponse, error) {
+	album, err := s.albumRepo.GetByID(ctx, albumID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get album: %w", err)
+	}
+
+	zr, err := zip.NewReader(archive, archiveSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	var entries []bulkEntry
+	for _, f := range zr.File {
+		name := path.Base(f.Name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(name, ".") || !isValidAudioFile(name) {
+			continue
+		}
+
+		entry := bulkEntry{file: f, name: name, title: strings.TrimSuffix(name, path.Ext(name))}
+		if m := trackNumberPrefix.FindStringSubmatch(entry.title); m != nil && strings.TrimSpace(m[2]) != "" {
+			number, _ := strconv.Atoi(m[1])
+			entry.number = &number
+			entry.title = strings.TrimSpace(m[2])
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: no audio files found", ErrInvalidArchive)
+	}
+	if len(entries) > models.MaxBulkTracks {
+		return nil, fmt.Errorf("%w: at most %d audio files allowed, got %d", ErrInvalidArchive, models.MaxBulkTracks, len(entries))
+	}
+
+	// Numbered files first in number order, so unnumbered ones are appended after them
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].number, entries[j].number
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a < *b
+	})
+
+	result := &models.BulkTrackUploadResponse{
+		Succeeded: []models.BulkTrackResult{},
+		Failed:    []models.BulkTrackFailure{},
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		req := &models.TrackCreate{AlbumID: albumID, Title: entry.title, Artist: artist}
+		track, err := s.addArchivedTrack(ctx, album, userID, req, entry, maxAudioSize)
+		if err != nil {
+			s.logger.Warn("Failed to add track from archive", "album_id", albumID, "filename", entry.file.Name, "error", err)
+			result.Failed = append(result.Failed, models.BulkTrackFailure{Filename: entry.file.Name, Error: err.Error()})
+			continue
+		}
+
+		result.Succeeded = append(result.Succeeded, models.BulkTrackResult{
+			Filename:    entry.file.Name,
+			TrackID:     track.ID,
+			Title:       track.Title,
+			TrackNumber: entry.number,
+		})
+	}
+
+	s.logger.Info("Bulk track upload finished", "album_id", albumID, "succeeded", len(result.Succeeded), "failed", len(result.Failed))
+	return result, nil
+}
+
+// addArchivedTrack extracts one archive entry to a temp file and adds it to the album
+func (s *AlbumService) addArchivedTrack(ctx context.Context, album *models.Album, userID int, req *models.TrackCreate, entry bulkEntry, maxAudioSize int64) (*models.TrackResponse, error) {
+	if entry.file.UncompressedSize64 > uint64(maxAudioSize) {
+		return nil, fmt.Errorf("audio file is larger than %d bytes", maxAudioSize)
+	}
+
+	src, err := entry.file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive entry: %w", err)
+	}
+	defer src.Close()
+
+	tempFile, err := os.CreateTemp(s.tracks.tempDir, "bulk-*"+path.Ext(entry.name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	// The declared size can lie, so never extract more than the limit
+	size, err := io.Copy(tempFile, io.LimitReader(src, maxAudioSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract archive entry: %w", err)
+	}
+	if size > maxAudioSize {
+		return nil, fmt.Errorf("audio file is larger than %d bytes", maxAudioSize)
+	}
+	if _, err := tempFile.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind temp file: %w", err)
+	}
+
+	return s.addTrack(ctx, album, userID, req, entry.number, tempFile, entry.name, size, nil, nil)
+}
+
+// prepareCover returns the cover content, size and key extension to store.
+// Covers are transcoded to WebP when configured, otherwise passed through as uploaded.
+// The WebP encoder is lossless, so photos often come out larger than their
+// JPEG; the upload is kept as is then.
+func (s *AlbumService) prepareCover(coverFile multipart.File, coverHeader *multipart.FileHeader, contentType string) (io.Reader, int64, string, error) {
+	if s.coverFormat == "webp" && contentType != imagePkg.WebPContentType {
+		data, err := imagePkg.ConvertToWebP(coverFile, s.coverQuality)
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("invalid cover image: %w", err)
+		}
+		if int64(len(data)) < coverHeader.Size {
+			return bytes.NewReader(data), int64(len(data)), ".webp", nil
+		}
+		if _, err := coverFile.Seek(0, io.SeekStart); err != nil {
+			return nil, 0, "", fmt.Errorf("failed to rewind cover: %w", err)
+		}
+	}
+
+	coverExt := strings.ToLower(filepath.Ext(coverHeader.Filename))
+	if coverExt == "" {
+		coverExt = ".jpg"
+	}
+	return coverFile, coverHeader.Size, coverExt, nil
+}
+
 func isValidImageFile(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
 	validExts := []string{".jpg", ".jpeg", ".png"}
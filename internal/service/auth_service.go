@@ -2,12 +2,18 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
 	"koteyye_music_be/internal/models"
 	"koteyye_music_be/internal/repository"
+	"koteyye_music_be/pkg/mailer"
 
 	"log/slog"
 
@@ -15,10 +21,44 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	// ErrUserExists is returned when registering an email that is already taken
+	ErrUserExists = errors.New("user with this email already exists")
+	// ErrInvalidCredentials is returned when login email or password do not match
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired or already used
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrInvalidVerificationToken is returned when an email verification token is unknown, expired or already used
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	// ErrEmailNotVerified is returned when an unverified local user logs in while verification is required
+	ErrEmailNotVerified = errors.New("email address is not verified")
+)
+
+// refreshTokenBytes is the amount of randomness in an opaque refresh or verification token
+const refreshTokenBytes = 32
+
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	jwtSecret string
-	logger    *slog.Logger
+	userRepo       *repository.UserRepository
+	refreshRepo    *repository.RefreshTokenRepository
+	jwtSecret      string
+	accessTokenTTL time.Duration
+	// issuer and audience are set on issued tokens and required on validated
+	// ones; empty disables the claim so older tokens keep working
+	issuer          string
+	audience        string
+	refreshTokenTTL time.Duration
+	// verifications stores email verification tokens sent through mailer
+	verifications *repository.EmailVerificationRepository
+	mailer        mailer.Mailer
+	// verificationTTL is how long a verification link stays valid
+	verificationTTL time.Duration
+	// requireVerification blocks login of unverified local users
+	requireVerification bool
+	// passwordPolicy is what passwords of new local accounts must satisfy
+	passwordPolicy PasswordPolicy
+	// publicBaseURL prefixes the verification link
+	publicBaseURL string
+	logger        *slog.Logger
 }
 
 type Claims struct {
@@ -28,21 +68,36 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func NewAuthService(userRepo *repository.UserRepository, jwtSecret string, log *slog.Logger) *AuthService {
+func NewAuthService(userRepo *repository.UserRepository, refreshRepo *repository.RefreshTokenRepository, verifications *repository.EmailVerificationRepository, mailer mailer.Mailer, jwtSecret string, accessTokenTTL time.Duration, issuer, audience string, refreshTokenTTL, verificationTTL time.Duration, requireVerification bool, passwordPolicy PasswordPolicy, publicBaseURL string, log *slog.Logger) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
-		logger:    log,
+		userRepo:            userRepo,
+		refreshRepo:         refreshRepo,
+		verifications:       verifications,
+		mailer:              mailer,
+		jwtSecret:           jwtSecret,
+		accessTokenTTL:      accessTokenTTL,
+		issuer:              issuer,
+		audience:            audience,
+		refreshTokenTTL:     refreshTokenTTL,
+		verificationTTL:     verificationTTL,
+		requireVerification: requireVerification,
+		passwordPolicy:      passwordPolicy,
+		publicBaseURL:       publicBaseURL,
+		logger:              log,
 	}
 }
 
 // Register creates a new user
 func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest) (*models.AuthResponse, error) {
+	if err := s.ValidatePassword(req.Password); err != nil {
+		return nil, err
+	}
+
 	// Check if user already exists
 	_, err := s.userRepo.GetUserByEmail(ctx, req.Email)
 	if err == nil {
 		// User found, email already exists
-		return nil, errors.New("user with this email already exists")
+		return nil, fmt.Errorf("failed to register user: %w", ErrUserExists)
 	}
 	// User not found - this is expected for registration, continue
 
@@ -69,6 +124,17 @@ func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest)
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	// The account exists either way; a lost email can be sent again
+	if err := s.sendVerification(ctx, user); err != nil {
+		s.logger.Error("Failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
+	// Tokens would let an unverified user in without logging in
+	if s.requireVerification {
+		s.logger.Info("User registered, awaiting email verification", "user_id", user.ID)
+		return &models.AuthResponse{User: *user}, nil
+	}
+
 	// Generate token
 	token, err := s.GenerateToken(user)
 	if err != nil {
@@ -76,11 +142,18 @@ func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest)
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
+	refreshToken, err := s.issueRefreshToken(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("Failed to issue refresh token", "user_id", user.ID, "error", err)
+		return nil, err
+	}
+
 	s.logger.Info("User registered successfully", "user_id", user.ID, "email", user.Email)
 
 	return &models.AuthResponse{
-		Token: token,
-		User:  *user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         *user,
 	}, nil
 }
 
@@ -90,7 +163,7 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 	user, err := s.userRepo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		s.logger.Warn("Login attempt with non-existent email", "email", req.Email)
-		return nil, errors.New("invalid email or password")
+		return nil, fmt.Errorf("failed to login: %w", ErrInvalidCredentials)
 	}
 
 	// Check password
@@ -99,7 +172,10 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(*user.PasswordHash), []byte(req.Password)); err != nil {
 		s.logger.Warn("Invalid password attempt", "user_id", user.ID, "email", user.Email)
-		return nil, errors.New("invalid email or password")
+		return nil, fmt.Errorf("failed to login: %w", ErrInvalidCredentials)
+	}
+	if s.requireVerification && !user.EmailVerified {
+		return nil, fmt.Errorf("failed to login: %w", ErrEmailNotVerified)
 	}
 
 	// Generate token
@@ -123,17 +199,24 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 		userWithLastTrack = user
 	}
 
+	refreshToken, err := s.issueRefreshToken(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("Failed to issue refresh token", "user_id", user.ID, "error", err)
+		return nil, err
+	}
+
 	s.logger.Info("User logged in successfully", "user_id", user.ID, "email", user.Email)
 
 	return &models.AuthResponse{
-		Token: token,
-		User:  *userWithLastTrack,
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         *userWithLastTrack,
 	}, nil
 }
 
 // GenerateToken generates a JWT token for a user
 func (s *AuthService) GenerateToken(user *models.User) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+	now := time.Now()
 
 	email := ""
 	if user.Email != nil {
@@ -144,11 +227,15 @@ func (s *AuthService) GenerateToken(user *models.User) (string, error) {
 		Email:  email,
 		Role:   user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    s.issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(s.jwtSecret))
@@ -161,22 +248,52 @@ func (s *AuthService) GenerateToken(user *models.User) (string, error) {
 
 // ValidateToken validates a JWT token and returns the user ID and role
 func (s *AuthService) ValidateToken(tokenString string) (int, string, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return 0, "", err
+	}
+	return claims.UserID, claims.Role, nil
+}
+
+// TokenExpiry validates a JWT token and returns its expiration time
+func (s *AuthService) TokenExpiry(tokenString string) (time.Time, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if claims.ExpiresAt == nil {
+		return time.Time{}, errors.New("token has no expiration")
+	}
+	return claims.ExpiresAt.Time, nil
+}
+
+// parseToken verifies a JWT token's signature and validity and returns its
+// claims. Issuer and audience are only checked when configured.
+func (s *AuthService) parseToken(tokenString string) (*Claims, error) {
+	opts := []jwt.ParserOption{jwt.WithExpirationRequired()}
+	if s.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.issuer))
+	}
+	if s.audience != "" {
+		opts = append(opts, jwt.WithAudience(s.audience))
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(s.jwtSecret), nil
-	})
+	}, opts...)
 
 	if err != nil {
-		return 0, "", fmt.Errorf("failed to parse token: %w", err)
+		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims.UserID, claims.Role, nil
+		return claims, nil
 	}
 
-	return 0, "", errors.New("invalid token")
+	return nil, errors.New("invalid token")
 }
 
 // GuestLogin creates a new guest user and returns a token
@@ -186,7 +303,6 @@ func (s *AuthService) GuestLogin(ctx context.Context) (*models.GuestResponse, er
 		Email:        nil, // NULL for guests
 		PasswordHash: nil, // NULL for guests
 		Provider:     nil, // NULL for guests
-		ExternalID:   nil, // NULL for guests
 		Role:         "guest",
 	}
 
@@ -209,14 +325,150 @@ func (s *AuthService) GuestLogin(ctx context.Context) (*models.GuestResponse, er
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
+	refreshToken, err := s.issueRefreshToken(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("Failed to issue refresh token", "user_id", user.ID, "error", err)
+		return nil, err
+	}
+
 	s.logger.Info("Guest user created and logged in", "user_id", user.ID)
 
 	return &models.GuestResponse{
-		Token: token,
-		User:  *user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         *user,
 	}, nil
 }
 
+// Refresh exchanges a refresh token for a new access token.
+// The refresh token is rotated: the presented one is revoked and a new one is returned.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.TokenResponse, error) {
+	newRefreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := s.refreshRepo.Rotate(ctx, hashRefreshToken(refreshToken), hashRefreshToken(newRefreshToken), time.Now().Add(s.refreshTokenTTL))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil, fmt.Errorf("failed to refresh token: %w", ErrInvalidRefreshToken)
+		}
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	// Reload the user so role changes are reflected in the new access token
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user for refresh: %w", err)
+	}
+
+	token, err := s.GenerateToken(user)
+	if err != nil {
+		s.logger.Error("Failed to generate token", "error", err)
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	s.logger.Info("Access token refreshed", "user_id", user.ID)
+
+	return &models.TokenResponse{
+		Token:        token,
+		RefreshToken: newRefreshToken,
+	}, nil
+}
+
+// Logout revokes a refresh token
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	if err := s.refreshRepo.Delete(ctx, hashRefreshToken(refreshToken)); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return fmt.Errorf("failed to logout: %w", ErrInvalidRefreshToken)
+		}
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// issueRefreshToken creates and stores a new refresh token for a user
+func (s *AuthService) issueRefreshToken(ctx context.Context, userID int) (string, error) {
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.refreshRepo.Create(ctx, userID, hashRefreshToken(refreshToken), time.Now().Add(s.refreshTokenTTL)); err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return refreshToken, nil
+}
+
+// generateRefreshToken returns a random URL-safe opaque token
+func generateRefreshToken() (string, error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashRefreshToken returns the hex SHA-256 of a refresh token as stored in the database
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyEmail marks the email of the token's user verified. Tokens are single-use.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.verifications.Verify(ctx, hashRefreshToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrVerificationTokenNotFound) {
+			return fmt.Errorf("failed to verify email: %w", ErrInvalidVerificationToken)
+		}
+		return err
+	}
+
+	s.logger.Info("Email verified", "user_id", userID)
+	return nil
+}
+
+// ResendVerification sends a new verification link to an unverified local
+// user, invalidating earlier links. Unknown, OAuth and already verified
+// emails are ignored so the response doesn't reveal which accounts exist.
+func (s *AuthService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.PasswordHash == nil || user.EmailVerified {
+		return nil
+	}
+
+	return s.sendVerification(ctx, user)
+}
+
+// sendVerification stores a new verification token for user and mails the link
+func (s *AuthService) sendVerification(ctx context.Context, user *models.User) error {
+	token, err := generateRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.verifications.Replace(ctx, user.ID, hashRefreshToken(token), time.Now().Add(s.verificationTTL)); err != nil {
+		return err
+	}
+
+	link := s.publicBaseURL + "/api/auth/verify?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Confirm your email address by opening this link:\n\n%s\n\nThe link expires in %s.", link, s.verificationTTL)
+	if err := s.mailer.Send(ctx, *user.Email, "Confirm your email", body); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	return nil
+}
+
 // PromoteGuestToUser promotes a guest user to a registered user via OAuth
 func (s *AuthService) PromoteGuestToUser(ctx context.Context, guestID int, userInfo *models.OAuthUserInfo) (*models.AuthResponse, error) {
 	// Update guest user with email and provider
@@ -236,13 +488,14 @@ func (s *AuthService) PromoteGuestToUser(ctx context.Context, guestID int, userI
 
 	// Update user fields to become a regular user
 	updatedUser := &models.User{
-		ID:         user.ID,
-		Email:      &userInfo.Email,
-		Name:       name,
-		AvatarKey:  avatarURL,
-		Provider:   &userInfo.Provider,
-		ExternalID: &userInfo.ExternalID,
-		Role:       "user", // Promote from guest to user
+		ID:        user.ID,
+		Email:     &userInfo.Email,
+		Name:      name,
+		AvatarKey: avatarURL,
+		Provider:  &userInfo.Provider,
+		Role:      "user", // Promote from guest to user
+		// The provider has verified the email
+		EmailVerified: true,
 	}
 
 	if err := s.userRepo.UpdateUser(ctx, updatedUser); err != nil {
@@ -250,6 +503,11 @@ func (s *AuthService) PromoteGuestToUser(ctx context.Context, guestID int, userI
 		return nil, fmt.Errorf("failed to promote guest: %w", err)
 	}
 
+	if err := s.userRepo.LinkOAuthAccount(ctx, guestID, userInfo.Provider, userInfo.ExternalID); err != nil {
+		s.logger.Error("Failed to link OAuth account to promoted guest", "guest_id", guestID, "provider", userInfo.Provider, "error", err)
+		return nil, fmt.Errorf("failed to link OAuth account: %w", err)
+	}
+
 	// Get updated user
 	user, err = s.userRepo.GetUserByID(ctx, guestID)
 	if err != nil {
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"koteyye_music_be/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestAuthService(issuer string, accessTokenTTL time.Duration) *AuthService {
+	return NewAuthService(nil, nil, nil, nil, "test-secret", accessTokenTTL, issuer, "", time.Hour, time.Hour, false, PasswordPolicy{}, "http://localhost", slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestValidateToken(t *testing.T) {
+	user := &models.User{ID: 42, Role: "user"}
+	tests := []struct {
+		name      string
+		issuedBy  *AuthService
+		checkedBy *AuthService
+		wantErr   error
+	}{
+		{"valid", newTestAuthService("koteyye", time.Hour), newTestAuthService("koteyye", time.Hour), nil},
+		{"expired", newTestAuthService("koteyye", -time.Minute), newTestAuthService("koteyye", time.Hour), jwt.ErrTokenExpired},
+		{"wrong issuer", newTestAuthService("someone-else", time.Hour), newTestAuthService("koteyye", time.Hour), jwt.ErrTokenInvalidIssuer},
+		{"issuer not checked when unset", newTestAuthService("someone-else", time.Hour), newTestAuthService("", time.Hour), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.issuedBy.GenerateToken(user)
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+
+			userID, role, err := tt.checkedBy.ValidateToken(token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ValidateToken error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateToken: %v", err)
+			}
+			if userID != user.ID || role != user.Role {
+				t.Errorf("got user %d with role %q, want %d with %q", userID, role, user.ID, user.Role)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/repository"
+)
+
+// genreStatsTTL is how long genre counts are served from memory
+const genreStatsTTL = time.Minute
+
+type GenreService struct {
+	albumRepo *repository.AlbumRepository
+	trackRepo *repository.TrackRepository
+	logger    *slog.Logger
+
+	mu        sync.Mutex
+	cached    []models.GenreStats
+	expiresAt time.Time
+}
+
+func NewGenreService(albumRepo *repository.AlbumRepository, trackRepo *repository.TrackRepository, log *slog.Logger) *GenreService {
+	return &GenreService{
+		albumRepo: albumRepo,
+		trackRepo: trackRepo,
+		logger:    log,
+	}
+}
+
+// ListGenres returns every allowed genre, in models.AllowedGenres order, with its album
+// and track counts. Genres without content are included with zero counts.
+// Results are cached for genreStatsTTL.
+func (s *GenreService) ListGenres(ctx context.Context) ([]models.GenreStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Now().Before(s.expiresAt) {
+		return s.cached, nil
+	}
+
+	albumCounts, err := s.albumRepo.CountAlbumsByGenre(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count albums: %w", err)
+	}
+	trackCounts, err := s.trackRepo.CountTracksByGenre(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tracks: %w", err)
+	}
+
+	stats := make([]models.GenreStats, 0, len(models.AllowedGenres))
+	for _, genre := range models.AllowedGenres {
+		stats = append(stats, models.GenreStats{
+			Genre:       genre,
+			AlbumsCount: albumCounts[genre],
+			TracksCount: trackCounts[genre],
+		})
+	}
+
+	s.cached = stats
+	s.expiresAt = time.Now().Add(genreStatsTTL)
+	return stats, nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"koteyye_music_be/pkg/filetype"
+)
+
+func TestFetchObjectRejectsInternalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer srv.Close()
+
+	s := NewImportService(nil, nil, 1<<20, 1<<20, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	_, err := s.fetchObject(context.Background(), "bucket", srv.URL, filetype.CategoryImage, 1<<20, func(string) string { return "key" })
+	if !errors.Is(err, errForbiddenFetchAddress) {
+		t.Fatalf("err = %v, want errForbiddenFetchAddress", err)
+	}
+}
+
+func TestCheckFetchAddress(t *testing.T) {
+	for address, allowed := range map[string]bool{
+		"127.0.0.1:80":          false,
+		"10.1.2.3:443":          false,
+		"192.168.0.10:80":       false,
+		"169.254.169.254:80":    false,
+		"[::1]:80":              false,
+		"[::ffff:127.0.0.1]:80": false,
+		"[fe80::1]:80":          false,
+		"0.0.0.0:80":            false,
+		"93.184.216.34:443":     true,
+	} {
+		if err := checkFetchAddress(address); (err == nil) != allowed {
+			t.Errorf("checkFetchAddress(%q) = %v, want allowed %v", address, err, allowed)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/repository"
+	"koteyye_music_be/pkg/filetype"
+	minioPkg "koteyye_music_be/pkg/minio"
+)
+
+// importFetchTimeout bounds a single cover or audio download
+const importFetchTimeout = 2 * time.Minute
+
+// errForbiddenFetchAddress is returned when a manifest URL resolves to an internal address
+var errForbiddenFetchAddress = errors.New("URL must not point to a loopback, private or link-local address")
+
+// errInvalidTracks marks an album whose tracks failed validation; details are in the track results
+var errInvalidTracks = errors.New("one or more tracks are invalid")
+
+// ImportService creates albums and tracks in bulk from an import manifest
+type ImportService struct {
+	albumRepo    *repository.AlbumRepository
+	minioSvc     *minioPkg.Service
+	httpClient   *http.Client
+	maxCoverSize int64
+	maxAudioSize int64
+	logger       *slog.Logger
+}
+
+func NewImportService(albumRepo *repository.AlbumRepository, minioSvc *minioPkg.Service, maxCoverSize, maxAudioSize int64, log *slog.Logger) *ImportService {
+	return &ImportService{
+		albumRepo:    albumRepo,
+		minioSvc:     minioSvc,
+		httpClient:   newFetchClient(),
+		maxCoverSize: maxCoverSize,
+		maxAudioSize: maxAudioSize,
+		logger:       log,
+	}
+}
+
+// Import processes manifest albums one by one. Each album with its tracks is
+// created in a single transaction; albums whose ID already exists are skipped,
+// so a partially failed import can be re-run with the same manifest.
+func (s *ImportService) Import(ctx context.Context, userID int, manifest *models.ImportManifest) *models.ImportResponse {
+	response := &models.ImportResponse{
+		Albums: make([]models.ImportAlbumResult, 0, len(manifest.Albums)),
+	}
+
+	for i := range manifest.Albums {
+		result := s.importAlbum(ctx, userID, &manifest.Albums[i])
+		switch result.Status {
+		case models.ImportStatusImported:
+			response.Imported++
+		case models.ImportStatusSkipped:
+			response.Skipped++
+		default:
+			response.Failed++
+		}
+		response.Albums = append(response.Albums, result)
+	}
+
+	s.logger.Info("Catalog import finished",
+		"imported", response.Imported, "skipped", response.Skipped, "failed", response.Failed)
+	return response
+}
+
+// importAlbum validates and stores one manifest album, never returning an error:
+// failures are reported in the result
+func (s *ImportService) importAlbum(ctx context.Context, userID int, item *models.ImportAlbum) models.ImportAlbumResult {
+	result := models.ImportAlbumResult{
+		ID:     item.ID,
+		Title:  item.Title,
+		Tracks: make([]models.ImportTrackResult, len(item.Tracks)),
+	}
+	for i, track := range item.Tracks {
+		result.Tracks[i] = models.ImportTrackResult{ID: track.ID, Title: track.Title}
+	}
+
+	fail := func(err error) models.ImportAlbumResult {
+		result.Status = models.ImportStatusFailed
+		result.Error = err.Error()
+		for i := range result.Tracks {
+			if result.Tracks[i].Status == "" {
+				result.Tracks[i].Status = models.ImportStatusFailed
+			}
+		}
+		return result
+	}
+	setTrackStatus := func(status string) {
+		for i := range result.Tracks {
+			result.Tracks[i].Status = status
+		}
+	}
+
+	if _, err := uuid.Parse(item.ID); err != nil {
+		return fail(fmt.Errorf("album id must be a UUID"))
+	}
+
+	exists, err := s.albumRepo.Exists(ctx, item.ID)
+	if err != nil {
+		s.logger.Error("Failed to check imported album", "album_id", item.ID, "error", err)
+		return fail(fmt.Errorf("failed to check whether album exists"))
+	}
+	if exists {
+		result.Status = models.ImportStatusSkipped
+		setTrackStatus(models.ImportStatusSkipped)
+		return result
+	}
+
+	album, err := s.validateAlbum(ctx, item)
+	if err != nil {
+		return fail(err)
+	}
+
+	tracks := make([]models.Track, len(item.Tracks))
+	invalid := false
+	seenIDs := make(map[string]bool, len(item.Tracks))
+	for i := range item.Tracks {
+		track, err := s.validateTrack(ctx, item.ID, &item.Tracks[i])
+		if err == nil && seenIDs[track.ID] {
+			err = fmt.Errorf("duplicate track id %s", track.ID)
+		}
+		if err != nil {
+			result.Tracks[i].Status = models.ImportStatusFailed
+			result.Tracks[i].Error = err.Error()
+			invalid = true
+			continue
+		}
+		seenIDs[track.ID] = true
+		track.UserID = userID
+		track.AlbumID = item.ID
+		tracks[i] = *track
+		result.Tracks[i].ID = track.ID
+	}
+	if invalid {
+		return fail(errInvalidTracks)
+	}
+
+	// Download remote files only once everything is known to be valid
+	buckets := s.minioSvc.Buckets()
+	type fetchedObject struct{ bucket, key string }
+	var fetched []fetchedObject
+	cleanup := func() {
+		for _, object := range fetched {
+			if err := s.minioSvc.DeleteFile(ctx, object.bucket, object.key); err != nil {
+				s.logger.Warn("Failed to delete imported object", "bucket", object.bucket, "key", object.key, "error", err)
+			}
+		}
+	}
+
+	if item.CoverURL != "" {
+		key, err := s.fetchObject(ctx, buckets.Image, item.CoverURL, filetype.CategoryImage, s.maxCoverSize, func(contentType string) string {
+			return fmt.Sprintf("albums/%s/cover%s", item.ID, imageExtension(contentType))
+		})
+		if err != nil {
+			return fail(fmt.Errorf("failed to fetch cover: %w", err))
+		}
+		fetched = append(fetched, fetchedObject{buckets.Image, key})
+		album.CoverImageKey = key
+	}
+
+	for i := range item.Tracks {
+		if item.Tracks[i].AudioURL == "" {
+			continue
+		}
+		trackID := tracks[i].ID
+		ext := audioExtension(item.Tracks[i].AudioURL)
+		key, err := s.fetchObject(ctx, buckets.Audio, item.Tracks[i].AudioURL, filetype.CategoryAudio, s.maxAudioSize, func(string) string {
+			return fmt.Sprintf("albums/%s/%s%s", item.ID, trackID, ext)
+		})
+		if err != nil {
+			cleanup()
+			result.Tracks[i].Status = models.ImportStatusFailed
+			result.Tracks[i].Error = fmt.Sprintf("failed to fetch audio: %v", err)
+			return fail(errInvalidTracks)
+		}
+		fetched = append(fetched, fetchedObject{buckets.Audio, key})
+		tracks[i].AudioFileKey = key
+	}
+
+	if err := s.albumRepo.CreateWithTracks(ctx, album, tracks); err != nil {
+		cleanup()
+		s.logger.Error("Failed to import album", "album_id", item.ID, "error", err)
+		return fail(fmt.Errorf("failed to store album: %w", err))
+	}
+
+	result.Status = models.ImportStatusImported
+	setTrackStatus(models.ImportStatusImported)
+	s.logger.Info("Album imported", "album_id", item.ID, "tracks", len(tracks))
+	return result
+}
+
+// validateAlbum checks manifest album fields and an existing cover key
+func (s *ImportService) validateAlbum(ctx context.Context, item *models.ImportAlbum) (*models.Album, error) {
+	title := strings.TrimSpace(item.Title)
+	artist := strings.TrimSpace(item.Artist)
+	if title == "" || artist == "" {
+		return nil, fmt.Errorf("title and artist are required")
+	}
+
+	genre, ok := models.NormalizeGenre(item.Genre)
+	if !ok {
+		return nil, invalidGenreError(item.Genre)
+	}
+
+	releaseDate, err := time.Parse("2006-01-02", item.ReleaseDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release date %q. Use YYYY-MM-DD", item.ReleaseDate)
+	}
+
+	if (item.CoverKey == "") == (item.CoverURL == "") {
+		return nil, fmt.Errorf("exactly one of cover_key and cover_url is required")
+	}
+	if item.CoverKey != "" {
+		ext := strings.ToLower(path.Ext(item.CoverKey))
+		if !isValidImageFile(item.CoverKey) && ext != ".webp" {
+			return nil, fmt.Errorf("cover_key must be a jpg, png or webp image")
+		}
+		if err := s.checkObjectKey(ctx, s.minioSvc.Buckets().Image, item.ID, item.CoverKey); err != nil {
+			return nil, fmt.Errorf("invalid cover_key: %w", err)
+		}
+	} else if err := validateFetchURL(item.CoverURL); err != nil {
+		return nil, fmt.Errorf("invalid cover_url: %w", err)
+	}
+
+	now := time.Now()
+	return &models.Album{
+		ID:            item.ID,
+		Title:         title,
+		Artist:        artist,
+		ReleaseDate:   releaseDate,
+		Genre:         genre,
+		CoverImageKey: item.CoverKey,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}, nil
+}
+
+// validateTrack checks manifest track fields and an existing audio key
+func (s *ImportService) validateTrack(ctx context.Context, albumID string, item *models.ImportTrack) (*models.Track, error) {
+	trackID := item.ID
+	if trackID == "" {
+		trackID = uuid.New().String()
+	} else if _, err := uuid.Parse(trackID); err != nil {
+		return nil, fmt.Errorf("track id must be a UUID")
+	}
+
+	title := strings.TrimSpace(item.Title)
+	if title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	if item.DurationSeconds <= 0 {
+		return nil, fmt.Errorf("duration_seconds must be positive")
+	}
+
+	if (item.AudioKey == "") == (item.AudioURL == "") {
+		return nil, fmt.Errorf("exactly one of audio_key and audio_url is required")
+	}
+	if item.AudioKey != "" {
+		if !isValidAudioFile(item.AudioKey) {
+			return nil, fmt.Errorf("audio_key must be an mp3, wav, m4a or flac file")
+		}
+		if err := s.checkObjectKey(ctx, s.minioSvc.Buckets().Audio, albumID, item.AudioKey); err != nil {
+			return nil, fmt.Errorf("invalid audio_key: %w", err)
+		}
+	} else if err := validateFetchURL(item.AudioURL); err != nil {
+		return nil, fmt.Errorf("invalid audio_url: %w", err)
+	}
+
+	var artist *string
+	if item.Artist != nil && strings.TrimSpace(*item.Artist) != "" {
+		trimmed := strings.TrimSpace(*item.Artist)
+		artist = &trimmed
+	}
+
+	return &models.Track{
+		ID:              trackID,
+		Title:           title,
+		Artist:          artist,
+		DurationSeconds: item.DurationSeconds,
+		AudioFileKey:    item.AudioKey,
+		CreatedAt:       time.Now(),
+	}, nil
+}
+
+// checkObjectKey requires key to live in the album's folder, so deleting the
+// album also removes it, and to exist in the given bucket
+func (s *ImportService) checkObjectKey(ctx context.Context, bucket, albumID, key string) error {
+	prefix := fmt.Sprintf("albums/%s/", albumID)
+	if !strings.HasPrefix(key, prefix) || strings.Contains(key, "..") {
+		return fmt.Errorf("key must start with %s", prefix)
+	}
+	if _, err := s.minioSvc.GetObjectInfo(ctx, bucket, key); err != nil {
+		return fmt.Errorf("object %s not found in storage", key)
+	}
+	return nil
+}
+
+// fetchObject downloads rawURL, checks its content category and size and
+// stores it in bucket under the key returned by keyFor for the detected content type
+func (s *ImportService) fetchObject(ctx context.Context, bucket, rawURL string, category filetype.Category, maxSize int64, keyFor func(contentType string) string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return "", fmt.Errorf("file exceeds %d bytes", maxSize)
+	}
+
+	contentType, err := filetype.Verify(bytes.NewReader(data), category)
+	if err != nil {
+		return "", err
+	}
+
+	key := keyFor(contentType)
+	if _, err := s.minioSvc.UploadFile(ctx, bucket, key, bytes.NewReader(data), int64(len(data))); err != nil {
+		return "", fmt.Errorf("failed to store file: %w", err)
+	}
+	return key, nil
+}
+
+// newFetchClient returns the client for manifest URLs. Every connection it
+// makes, including redirects, is checked after DNS resolution, so a manifest
+// can't reach the internal network or cloud metadata, not even through a
+// redirect or a host name that later resolves elsewhere.
+func newFetchClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkFetchAddress(address)
+		},
+	}
+	return &http.Client{
+		Timeout: importFetchTimeout,
+		Transport: &http.Transport{
+			// No proxy: the check must see the address of the actual server
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: time.Minute,
+		},
+	}
+}
+
+// checkFetchAddress rejects the ip:port a fetch is about to connect to unless it is a public address
+func checkFetchAddress(address string) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("unexpected dial address %q: %w", address, err)
+	}
+	ip := addrPort.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return errForbiddenFetchAddress
+	}
+	return nil
+}
+
+// validateFetchURL accepts absolute http and https URLs
+func validateFetchURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL: %w", err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("URL must be absolute http or https")
+	}
+	return nil
+}
+
+// imageExtension returns the cover key extension for a detected image type
+func imageExtension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ".jpg"
+	}
+}
+
+// audioExtension takes the extension from the URL path when it is a supported
+// audio format and falls back to .mp3 like regular uploads
+func audioExtension(rawURL string) string {
+	if parsed, err := url.Parse(rawURL); err == nil && isValidAudioFile(parsed.Path) {
+		return strings.ToLower(path.Ext(parsed.Path))
+	}
+	return ".mp3"
+}
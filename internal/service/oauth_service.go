@@ -2,11 +2,18 @@ package service
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"koteyye_music_be/internal/models"
@@ -17,6 +24,49 @@ import (
 	"golang.org/x/oauth2/yandex"
 )
 
+const (
+	// OAuthStateTTL is how long a user has to complete the provider's login page
+	OAuthStateTTL   = 10 * time.Minute
+	oauthNonceBytes = 32
+)
+
+// ErrInvalidOAuthState is returned when a callback's state wasn't issued by
+// this server, has expired or doesn't belong to the browser completing it
+var ErrInvalidOAuthState = errors.New("invalid or expired OAuth state")
+
+// ErrOAuthAccountLinked is returned when the provider account being linked
+// already belongs to another user
+var ErrOAuthAccountLinked = repository.ErrOAuthAccountLinked
+
+// ErrGuestCannotLinkOAuth is returned when a guest tries to link a provider
+// account; guests sign in with the provider instead to keep their history
+var ErrGuestCannotLinkOAuth = errors.New("guests cannot link OAuth accounts, sign in with the provider instead")
+
+// oauthState is what the state parameter carries to the callback. It is
+// signed rather than stored, so pending logins take no server memory and any
+// instance can complete them. Nonce is also kept in a cookie of the browser
+// that started the flow; a callback from another browser doesn't match it.
+type oauthState struct {
+	Provider    string `json:"p"`
+	GuestUserID int    `json:"g,omitempty"`
+	// LinkUserID is the signed-in user the provider account is linked to;
+	// 0 for a login
+	LinkUserID int    `json:"l,omitempty"`
+	Nonce      string `json:"n"`
+	ExpiresAt  int64  `json:"e"`
+}
+
+// OAuthCallbackResult is the outcome of an OAuth callback
+type OAuthCallbackResult struct {
+	// Token is a new JWT for User; empty when an account was linked, since
+	// the user is already signed in
+	Token string
+	User  *models.User
+	// Linked reports that the callback linked the provider account to a
+	// signed-in user instead of signing in
+	Linked bool
+}
+
 type OAuthService struct {
 	userRepo     *repository.UserRepository
 	authService  *AuthService
@@ -24,6 +74,8 @@ type OAuthService struct {
 	googleConfig *oauth2.Config
 	yandexConfig *oauth2.Config
 	frontendURL  string
+	// stateKey signs the state parameter
+	stateKey []byte
 }
 
 // Ensure AuthService methods can be called on pointer
@@ -57,13 +109,17 @@ func NewOAuthService(
 	googleClientID, googleClientSecret, googleRedirectURL string,
 	yandexClientID, yandexClientSecret, yandexRedirectURL string,
 	frontendURL string,
+	stateSecret string,
 	logger *slog.Logger,
 ) *OAuthService {
+	// A key of its own, so a signed state can never pass for a JWT signature
+	stateKey := sha256.Sum256([]byte("oauth-state\x00" + stateSecret))
 	return &OAuthService{
 		userRepo:    userRepo,
 		authService: authService,
 		logger:      logger,
 		frontendURL: frontendURL,
+		stateKey:    stateKey[:],
 		googleConfig: &oauth2.Config{
 			ClientID:     googleClientID,
 			ClientSecret: googleClientSecret,
@@ -86,52 +142,70 @@ func NewOAuthService(
 
 // GetAuthURL generates OAuth authorization URL for the specified provider
 // guestUserID is optional - if provided, guest will be promoted to user on OAuth login
-func (s *OAuthService) GetAuthURL(provider string, guestUserID *int) (string, error) {
+// The returned browser nonce must be stored in the browser, see HandleCallback.
+func (s *OAuthService) GetAuthURL(provider string, guestUserID *int) (authURL, browserNonce string, err error) {
+	var guestID int
+	if guestUserID != nil {
+		guestID = *guestUserID
+	}
+	return s.authCodeURL(oauthState{Provider: provider, GuestUserID: guestID})
+}
+
+// GetLinkURL generates the OAuth authorization URL that links an account of
+// the provider to the signed-in user userID once its callback arrives.
+// The returned browser nonce must be stored in the browser, see HandleCallback.
+func (s *OAuthService) GetLinkURL(provider string, userID int) (authURL, browserNonce string, err error) {
+	return s.authCodeURL(oauthState{Provider: provider, LinkUserID: userID})
+}
+
+// authCodeURL issues a state for pending and returns the provider's
+// authorization URL carrying it together with the state's nonce
+func (s *OAuthService) authCodeURL(pending oauthState) (string, string, error) {
 	var config *oauth2.Config
 	var providerName string
 
-	switch provider {
+	switch pending.Provider {
 	case "google":
 		config = s.googleConfig
 		providerName = "Google"
 		if config.ClientID == "" || config.ClientSecret == "" {
-			return "", fmt.Errorf("Google OAuth credentials not configured. Please set GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET environment variables")
+			return "", "", fmt.Errorf("Google OAuth credentials not configured. Please set GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET environment variables")
 		}
 	case "yandex":
 		config = s.yandexConfig
 		providerName = "Yandex"
 		if config.ClientID == "" || config.ClientSecret == "" {
-			return "", fmt.Errorf("Yandex OAuth credentials not configured. Please set YANDEX_CLIENT_ID and YANDEX_CLIENT_SECRET environment variables")
+			return "", "", fmt.Errorf("Yandex OAuth credentials not configured. Please set YANDEX_CLIENT_ID and YANDEX_CLIENT_SECRET environment variables")
 		}
 	default:
-		return "", fmt.Errorf("unsupported OAuth provider: %s", provider)
+		return "", "", fmt.Errorf("unsupported OAuth provider: %s", pending.Provider)
 	}
 
-	// Generate state with guest user ID for guest promotion
-	// Format: "provider:guestUserID" or just "provider" if no guest
-	var state string
-	if guestUserID != nil {
-		state = fmt.Sprintf("%s:guest:%d", provider, *guestUserID)
-	} else {
-		state = fmt.Sprintf("%s:guest:0", provider)
+	state, err := s.issueState(&pending)
+	if err != nil {
+		return "", "", err
 	}
 
 	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline)
 
 	s.logger.Info("Generated OAuth URL",
 		"provider", providerName,
-		"guest_user_id", guestUserID,
+		"guest_user_id", pending.GuestUserID,
+		"link_user_id", pending.LinkUserID,
 		"redirect_url", config.RedirectURL,
-		"client_id", config.ClientID,
-		"url", authURL)
+		"client_id", config.ClientID)
 
-	return authURL, nil
+	return authURL, pending.Nonce, nil
 }
 
 // HandleCallback processes OAuth callback from provider
 // Returns JWT token and user info for redirect to frontend
-// Supports guest promotion: if state contains guest ID, guest will be promoted
-func (s *OAuthService) HandleCallback(ctx context.Context, provider, code, state string) (string, *models.User, error) {
+// The state must be one issued by GetAuthURL or GetLinkURL for the same
+// provider, and browserNonce the nonce returned with it as kept by the
+// browser, otherwise ErrInvalidOAuthState is returned. A guest recorded with
+// the state is promoted; a state from GetLinkURL links the provider account
+// to its user instead of signing in.
+func (s *OAuthService) HandleCallback(ctx context.Context, provider, code, state, browserNonce string) (*OAuthCallbackResult, error) {
 	var config *oauth2.Config
 	var providerName string
 	var userInfo *models.OAuthUserInfo
@@ -144,43 +218,57 @@ func (s *OAuthService) HandleCallback(ctx context.Context, provider, code, state
 		config = s.yandexConfig
 		providerName = "Yandex"
 	default:
-		return "", nil, fmt.Errorf("unsupported OAuth provider: %s", provider)
+		return nil, fmt.Errorf("unsupported OAuth provider: %s", provider)
+	}
+
+	// Check the state before spending the code, so forged callbacks go nowhere
+	pending, err := s.verifyState(provider, state, browserNonce)
+	if err != nil {
+		s.logger.Warn("Rejected OAuth callback", "provider", providerName, "error", err)
+		return nil, err
+	}
+	guestUserID := pending.GuestUserID
+	if guestUserID > 0 {
+		s.logger.Info("OAuth login with guest promotion", "provider", providerName, "guest_user_id", guestUserID)
 	}
 
 	// Exchange code for access token
 	token, err := config.Exchange(ctx, code)
 	if err != nil {
 		s.logger.Error("Failed to exchange code for token", "provider", providerName, "error", err)
-		return "", nil, fmt.Errorf("failed to exchange code for token: %w", err)
+		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
 	}
 
 	// Get user info from provider
 	userInfo, err = s.getUserInfo(ctx, provider, token)
 	if err != nil {
 		s.logger.Error("Failed to get user info from provider", "provider", providerName, "error", err)
-		return "", nil, fmt.Errorf("failed to get user info: %w", err)
+		return nil, fmt.Errorf("failed to get user info: %w", err)
 	}
 
 	userInfo.Provider = provider
 
-	// Parse state to extract guest user ID
-	guestUserID := s.parseStateForGuestID(state)
-	if guestUserID > 0 {
-		s.logger.Info("OAuth login with guest promotion", "provider", providerName, "guest_user_id", guestUserID)
+	if pending.LinkUserID > 0 {
+		user, err := s.linkAccount(ctx, pending.LinkUserID, userInfo)
+		if err != nil {
+			s.logger.Error("Failed to link OAuth account", "provider", providerName, "user_id", pending.LinkUserID, "error", err)
+			return nil, fmt.Errorf("failed to link OAuth account: %w", err)
+		}
+		return &OAuthCallbackResult{User: user, Linked: true}, nil
 	}
 
 	// Find or create user (with guest promotion support)
 	user, err := s.findOrCreateUser(ctx, userInfo, guestUserID)
 	if err != nil {
 		s.logger.Error("Failed to find or create user", "provider", providerName, "error", err)
-		return "", nil, fmt.Errorf("failed to find or create user: %w", err)
+		return nil, fmt.Errorf("failed to find or create user: %w", err)
 	}
 
 	// Generate JWT token
 	jwtToken, err := s.authService.GenerateToken(user)
 	if err != nil {
 		s.logger.Error("Failed to generate JWT token", "user_id", user.ID, "error", err)
-		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	s.logger.Info("User authenticated via OAuth",
@@ -189,54 +277,61 @@ func (s *OAuthService) HandleCallback(ctx context.Context, provider, code, state
 		"email", user.Email,
 		"was_guest", guestUserID > 0)
 
-	return jwtToken, user, nil
+	return &OAuthCallbackResult{Token: jwtToken, User: user}, nil
 }
 
-// parseStateForGuestID extracts guest user ID from OAuth state parameter
-// State format: "provider:guest:userID" or "provider:guest:0"
-// Returns guest user ID if valid (>0), otherwise 0
-func (s *OAuthService) parseStateForGuestID(state string) int {
-	if state == "" {
-		return 0
+// issueState fills in a fresh nonce and expiry and returns pending signed as
+// "payload.signature", both base64url
+func (s *OAuthService) issueState(pending *oauthState) (string, error) {
+	nonce := make([]byte, oauthNonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate OAuth state: %w", err)
 	}
+	pending.Nonce = base64.RawURLEncoding.EncodeToString(nonce)
+	pending.ExpiresAt = time.Now().Add(OAuthStateTTL).Unix()
 
-	// Simple parsing: extract last part after "guest:"
-	// Expected format: "google:guest:123" or "yandex:guest:456"
-	guestPrefix := "guest:"
-	idx := len(state) - len(guestPrefix)
-	if idx < 0 {
-		idx = 0
-	} else {
-		idx = len(state) - idx
+	payload, err := json.Marshal(pending)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode OAuth state: %w", err)
 	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.signState(encoded)), nil
+}
 
-	// Find guest: in state
-	guestIdx := findSubstring(state, guestPrefix)
-	if guestIdx == -1 {
-		return 0
+// verifyState checks the signature of a state issued by issueState and returns
+// what it carries. Forged, expired or other-provider states, and states whose
+// nonce isn't browserNonce, yield ErrInvalidOAuthState.
+func (s *OAuthService) verifyState(provider, state, browserNonce string) (oauthState, error) {
+	encoded, sig, ok := strings.Cut(state, ".")
+	if !ok {
+		return oauthState{}, ErrInvalidOAuthState
+	}
+	gotSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, s.signState(encoded)) {
+		return oauthState{}, ErrInvalidOAuthState
 	}
 
-	// Extract guest ID after "guest:"
-	guestIDStr := state[guestIdx+len(guestPrefix):]
-
-	// Parse guest ID
-	var guestID int
-	if _, err := fmt.Sscanf(guestIDStr, "%d", &guestID); err != nil {
-		s.logger.Warn("Failed to parse guest ID from state", "state", state, "error", err)
-		return 0
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return oauthState{}, ErrInvalidOAuthState
+	}
+	var pending oauthState
+	if err := json.Unmarshal(payload, &pending); err != nil {
+		return oauthState{}, ErrInvalidOAuthState
 	}
 
-	return guestID
+	if pending.Provider != provider || time.Now().Unix() > pending.ExpiresAt ||
+		subtle.ConstantTimeCompare([]byte(pending.Nonce), []byte(browserNonce)) != 1 {
+		return oauthState{}, ErrInvalidOAuthState
+	}
+	return pending, nil
 }
 
-// findSubstring finds the last occurrence of substring in string
-func findSubstring(s, substr string) int {
-	for i := len(s) - len(substr); i >= 0; i-- {
-		if s[i:i+len(substr)] == substr {
-			return i
-		}
-	}
-	return -1
+// signState returns the HMAC-SHA256 of an encoded state payload
+func (s *OAuthService) signState(encoded string) []byte {
+	mac := hmac.New(sha256.New, s.stateKey)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
 }
 
 // GetFrontendURL returns the frontend URL for OAuth redirect
@@ -350,33 +445,54 @@ func (s *OAuthService) parseYandexUserInfo(body io.Reader) (*models.OAuthUserInf
 	}, nil
 }
 
-// findOrCreateUser finds existing user by email or creates a new one
+// findOrCreateUser finds the user the provider account is linked to, an
+// existing user by email, or creates a new one
 // Supports guest promotion: if guestUserID > 0, guest will be promoted instead of creating new user
 func (s *OAuthService) findOrCreateUser(ctx context.Context, userInfo *models.OAuthUserInfo, guestUserID int) (*models.User, error) {
-	// First, try to find user by email
-	user, err := s.userRepo.GetUserByEmail(ctx, userInfo.Email)
+	// A linked provider account signs its user in whatever the email is now
+	user, err := s.userRepo.GetUserByProviderAndExternalID(ctx, userInfo.Provider, userInfo.ExternalID)
+	if err == nil {
+		s.logger.Info("User found by linked OAuth account, logging in", "provider", userInfo.Provider, "existing_user_id", user.ID)
+		if err := s.userRepo.UpdateLastLogin(ctx, user.ID, time.Now()); err != nil {
+			s.logger.Warn("Failed to update last login time", "user_id", user.ID, "error", err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+
+	// Then, try to find user by email
+	user, err = s.userRepo.GetUserByEmail(ctx, userInfo.Email)
 	if err == nil && user != nil {
 		// SCENARIO A: User already exists by email
 		// Just login them - guest history is lost (expected behavior)
 		s.logger.Info("User found by email, logging in", "email", userInfo.Email, "existing_user_id", user.ID)
 
-		// If user was registered locally, we can link OAuth account
-		if user.Provider != nil && *user.Provider == "local" {
-			user.ExternalID = &userInfo.ExternalID
-			user.Provider = &userInfo.Provider
-
-			if err := s.userRepo.LinkOAuthAccount(ctx, user.ID, *user.Provider, *user.ExternalID); err != nil {
+		// A user registered locally, or via this provider but without the
+		// account linked, gets it linked. Users created via another provider
+		// link this one from their profile.
+		if user.Provider != nil && (*user.Provider == "local" || *user.Provider == userInfo.Provider) {
+			if err := s.userRepo.LinkOAuthAccount(ctx, user.ID, userInfo.Provider, userInfo.ExternalID); err != nil {
 				s.logger.Warn("Failed to link OAuth account to existing user",
 					"user_id", user.ID,
 					"provider", userInfo.Provider,
 					"error", err)
-				// Continue anyway, just update user object
 			} else {
 				s.logger.Info("Linked OAuth account to existing user",
 					"user_id", user.ID,
 					"provider", userInfo.Provider)
 			}
-		} else if user.Provider != nil && *user.Provider != userInfo.Provider {
+
+			// The provider has verified the shared email
+			if !user.EmailVerified {
+				if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+					s.logger.Warn("Failed to mark email verified", "user_id", user.ID, "error", err)
+				} else {
+					user.EmailVerified = true
+				}
+			}
+		} else if user.Provider != nil {
 			// User exists but with different provider
 			// This is a conflict situation - user with this email already exists via another OAuth provider
 			return nil, fmt.Errorf("user already exists with provider: %s", *user.Provider)
@@ -420,15 +536,16 @@ func (s *OAuthService) createOAuthUser(ctx context.Context, userInfo *models.OAu
 	_ = userInfo.AvatarURL
 
 	user := &models.User{
-		Email:      &userInfo.Email,
-		Name:       name,
-		AvatarKey:  nil, // TODO: Download and store OAuth avatars to MinIO
-		Provider:   &userInfo.Provider,
-		ExternalID: &userInfo.ExternalID,
-		Role:       "user",
+		Email:     &userInfo.Email,
+		Name:      name,
+		AvatarKey: nil, // TODO: Download and store OAuth avatars to MinIO
+		Provider:  &userInfo.Provider,
+		Role:      "user",
+		// The provider has verified the email
+		EmailVerified: true,
 	}
 
-	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+	if err := s.userRepo.CreateOAuthUser(ctx, user, userInfo.ExternalID); err != nil {
 		return nil, err
 	}
 
@@ -439,3 +556,34 @@ func (s *OAuthService) createOAuthUser(ctx context.Context, userInfo *models.OAu
 
 	return user, nil
 }
+
+// linkAccount links the provider account in userInfo to the signed-in user userID
+func (s *OAuthService) linkAccount(ctx context.Context, userID int, userInfo *models.OAuthUserInfo) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// The role may have changed since the link was started
+	if user.Role == "guest" {
+		return nil, ErrGuestCannotLinkOAuth
+	}
+
+	if err := s.userRepo.LinkOAuthAccount(ctx, user.ID, userInfo.Provider, userInfo.ExternalID); err != nil {
+		return nil, err
+	}
+
+	// The provider has verified its email; it verifies the user's when they match
+	if !user.EmailVerified && user.Email != nil && strings.EqualFold(*user.Email, userInfo.Email) {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			s.logger.Warn("Failed to mark email verified", "user_id", user.ID, "error", err)
+		} else {
+			user.EmailVerified = true
+		}
+	}
+
+	s.logger.Info("Linked OAuth account",
+		"user_id", user.ID,
+		"provider", userInfo.Provider)
+
+	return user, nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestOAuthStateIsBoundToBrowser(t *testing.T) {
+	s := NewOAuthService(nil, nil, "", "", "", "", "", "", "", "secret", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	guestID := 7
+	pending := oauthState{Provider: "google", GuestUserID: guestID}
+	state, err := s.issueState(&pending)
+	if err != nil {
+		t.Fatalf("issueState: %v", err)
+	}
+	nonce := pending.Nonce
+
+	got, err := s.verifyState("google", state, nonce)
+	if err != nil {
+		t.Fatalf("verifyState: %v", err)
+	}
+	if got.GuestUserID != guestID {
+		t.Errorf("GuestUserID = %d, want %d", got.GuestUserID, guestID)
+	}
+
+	other := NewOAuthService(nil, nil, "", "", "", "", "", "", "", "other", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for name, check := range map[string]func() error{
+		"other browser":  func() error { _, err := s.verifyState("google", state, "attacker-nonce"); return err },
+		"no cookie":      func() error { _, err := s.verifyState("google", state, ""); return err },
+		"other provider": func() error { _, err := s.verifyState("yandex", state, nonce); return err },
+		"tampered":       func() error { _, err := s.verifyState("google", "x"+state, nonce); return err },
+		"other key":      func() error { _, err := other.verifyState("google", state, nonce); return err },
+	} {
+		if err := check(); !errors.Is(err, ErrInvalidOAuthState) {
+			t.Errorf("%s: err = %v, want ErrInvalidOAuthState", name, err)
+		}
+	}
+}
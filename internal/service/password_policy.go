@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// ErrWeakPassword is returned for a password that doesn't meet the password policy
+var ErrWeakPassword = errors.New("password is too weak")
+
+// maxPasswordBytes is the longest password bcrypt hashes; it ignores the rest
+const maxPasswordBytes = 72
+
+// PasswordPolicy is what a new password must satisfy
+type PasswordPolicy struct {
+	MinLength     int
+	RequireLetter bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// Validate checks pw against the policy and returns ErrWeakPassword wrapped
+// with the first rule it breaks
+func (p PasswordPolicy) Validate(pw string) error {
+	if len([]rune(pw)) < p.MinLength {
+		return fmt.Errorf("%w: needs at least %d characters", ErrWeakPassword, p.MinLength)
+	}
+	if len(pw) > maxPasswordBytes {
+		return fmt.Errorf("%w: must be at most %d bytes", ErrWeakPassword, maxPasswordBytes)
+	}
+
+	var letter, upper, digit, symbol bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsUpper(r):
+			letter, upper = true, true
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	switch {
+	case p.RequireLetter && !letter:
+		return fmt.Errorf("%w: needs a letter", ErrWeakPassword)
+	case p.RequireUpper && !upper:
+		return fmt.Errorf("%w: needs an uppercase letter", ErrWeakPassword)
+	case p.RequireDigit && !digit:
+		return fmt.Errorf("%w: needs a digit", ErrWeakPassword)
+	case p.RequireSymbol && !symbol:
+		return fmt.Errorf("%w: needs a symbol", ErrWeakPassword)
+	}
+	return nil
+}
+
+// ValidatePassword checks a new password against the configured policy
+func (s *AuthService) ValidatePassword(pw string) error {
+	return s.passwordPolicy.Validate(pw)
+}
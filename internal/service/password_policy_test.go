@@ -0,0 +1,48 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	strict := PasswordPolicy{MinLength: 8, RequireLetter: true, RequireUpper: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name    string
+		policy  PasswordPolicy
+		pw      string
+		wantErr string
+	}{
+		{name: "valid", policy: strict, pw: "Secret-42"},
+		{name: "too short", policy: strict, pw: "Se-4", wantErr: "at least 8 characters"},
+		{name: "length counts characters", policy: PasswordPolicy{MinLength: 8}, pw: "пароль12"},
+		{name: "too long", policy: strict, pw: "Secret-42" + strings.Repeat("a", maxPasswordBytes), wantErr: "at most 72 bytes"},
+		{name: "exactly max bytes", policy: strict, pw: "Secret-42" + strings.Repeat("a", maxPasswordBytes-9)},
+		{name: "no letter", policy: strict, pw: "12345678-", wantErr: "needs a letter"},
+		{name: "no uppercase", policy: strict, pw: "secret-42", wantErr: "needs an uppercase letter"},
+		{name: "no digit", policy: strict, pw: "Secret-xy", wantErr: "needs a digit"},
+		{name: "no symbol", policy: strict, pw: "Secret42", wantErr: "needs a symbol"},
+		{name: "space counts as symbol", policy: strict, pw: "Secret 42"},
+		{name: "rules off", policy: PasswordPolicy{MinLength: 8}, pw: "abcdefgh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate(tt.pw)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate(%q) = %v, want nil", tt.pw, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrWeakPassword) {
+				t.Fatalf("Validate(%q) = %v, want ErrWeakPassword", tt.pw, err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate(%q) = %q, want it to mention %q", tt.pw, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"koteyye_music_be/internal/models"
+)
+
+// PermissionsFor maps a role to the capabilities it grants. Middleware and
+// handlers must check these flags instead of comparing role strings so the
+// API and the frontend share one definition of who may do what.
+// Unknown roles get no capabilities.
+func PermissionsFor(role string) models.Permissions {
+	p := models.Permissions{Role: role}
+
+	switch role {
+	case models.RoleAdmin:
+		p.CanUpload = true
+		p.CanManageAlbums = true
+		p.CanManageUsers = true
+	case models.RoleGuest:
+		p.IsGuest = true
+	}
+
+	return p
+}
+
+// GetUserPermissions returns the permissions of the user's current role as stored in the database
+func (s *UserService) GetUserPermissions(ctx context.Context, userID int) (models.Permissions, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.Permissions{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return PermissionsFor(user.Role), nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/repository"
+)
+
+// playFlushTimeout bounds a single write of buffered plays
+const playFlushTimeout = 10 * time.Second
+
+// playKey identifies a listener's plays of one track for debouncing
+type playKey struct {
+	listener string
+	trackID  string
+}
+
+// PlayTracker debounces play reports and writes them to the database in batches.
+// A listener (user or, for anonymous requests, client IP) counts at most one
+// play per track per debounce interval; counted plays are buffered and flushed
+// every flush interval by Run.
+type PlayTracker struct {
+	trackRepo *repository.TrackRepository
+	// debounce is how long repeated plays of a track by the same listener are ignored
+	debounce time.Duration
+	// flushInterval is how often buffered plays are written; 0 writes every play immediately
+	flushInterval time.Duration
+	// onFlush is called for each track whose plays were written
+	onFlush func(ctx context.Context, trackID string)
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	lastPlay map[playKey]time.Time
+	pending  []models.Play
+}
+
+func NewPlayTracker(trackRepo *repository.TrackRepository, debounce, flushInterval time.Duration, log *slog.Logger) *PlayTracker {
+	return &PlayTracker{
+		trackRepo:     trackRepo,
+		debounce:      debounce,
+		flushInterval: flushInterval,
+		logger:        log,
+		lastPlay:      make(map[playKey]time.Time),
+	}
+}
+
+// UserListener returns the debounce key of an authenticated user
+func UserListener(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// IPListener returns the debounce key of an anonymous client
+func IPListener(ip string) string {
+	return "ip:" + ip
+}
+
+// Record counts a play of trackID unless the listener already played it within
+// the debounce interval. userID is 0 for anonymous plays, which only count
+// toward the track's plays_count. It reports whether the play was counted.
+func (p *PlayTracker) Record(ctx context.Context, listener string, userID int, trackID string) (bool, error) {
+	now := time.Now()
+	key := playKey{listener: listener, trackID: trackID}
+	play := models.Play{TrackID: trackID, UserID: userID, PlayedAt: now}
+
+	p.mu.Lock()
+	if last, ok := p.lastPlay[key]; ok && now.Sub(last) < p.debounce {
+		p.mu.Unlock()
+		return false, nil
+	}
+	if p.debounce > 0 {
+		p.lastPlay[key] = now
+	}
+	if p.flushInterval > 0 {
+		p.pending = append(p.pending, play)
+		p.mu.Unlock()
+		return true, nil
+	}
+	p.mu.Unlock()
+
+	// Without a flush interval every counted play is written right away
+	if err := p.write(ctx, []models.Play{play}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Run flushes buffered plays every flush interval until ctx is done, then
+// flushes once more so no counted play is lost on shutdown. When plays are
+// written immediately it only expires debounce entries.
+func (p *PlayTracker) Run(ctx context.Context) {
+	interval := p.flushInterval
+	if interval <= 0 {
+		interval = p.debounce
+	}
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.Flush(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			p.Flush(ctx)
+		}
+	}
+}
+
+// Flush writes buffered plays and forgets debounce entries that have expired.
+// Plays that fail to write are put back to be retried on the next flush.
+func (p *PlayTracker) Flush(ctx context.Context) {
+	now := time.Now()
+
+	p.mu.Lock()
+	plays := p.pending
+	p.pending = nil
+	for key, last := range p.lastPlay {
+		if now.Sub(last) >= p.debounce {
+			delete(p.lastPlay, key)
+		}
+	}
+	p.mu.Unlock()
+
+	if len(plays) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, playFlushTimeout)
+	defer cancel()
+
+	if err := p.write(ctx, plays); err != nil {
+		p.logger.Error("Failed to flush plays, will retry", "plays", len(plays), "error", err)
+		p.mu.Lock()
+		p.pending = append(plays, p.pending...)
+		p.mu.Unlock()
+		return
+	}
+	p.logger.Debug("Plays flushed", "plays", len(plays))
+}
+
+// write stores plays and notifies onFlush of each affected track
+func (p *PlayTracker) write(ctx context.Context, plays []models.Play) error {
+	if err := p.trackRepo.RecordPlays(ctx, plays); err != nil {
+		return fmt.Errorf("failed to record plays: %w", err)
+	}
+
+	if p.onFlush != nil {
+		seen := make(map[string]bool, len(plays))
+		for _, play := range plays {
+			if !seen[play.TrackID] {
+				seen[play.TrackID] = true
+				p.onFlush(ctx, play.TrackID)
+			}
+		}
+	}
+	return nil
+}
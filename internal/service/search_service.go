@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/repository"
+)
+
+type SearchService struct {
+	trackRepo *repository.TrackRepository
+	albumRepo *repository.AlbumRepository
+	logger    *slog.Logger
+}
+
+func NewSearchService(trackRepo *repository.TrackRepository, albumRepo *repository.AlbumRepository, log *slog.Logger) *SearchService {
+	return &SearchService{
+		trackRepo: trackRepo,
+		albumRepo: albumRepo,
+		logger:    log,
+	}
+}
+
+// Search looks up tracks and albums matching query, up to limit of each kind.
+// searchType restricts the results to one kind; kinds that aren't searched come back empty.
+// With withFacets the response also counts all matches by genre and artist.
+func (s *SearchService) Search(ctx context.Context, query, searchType string, limit, userID int, withFacets bool) (*models.SearchResponse, error) {
+	response := &models.SearchResponse{
+		Tracks: []models.TrackResponse{},
+		Albums: []models.AlbumResponse{},
+	}
+
+	if searchType != models.SearchTypeAlbums {
+		tracks, err := s.trackRepo.SearchTracks(ctx, query, limit, userID)
+		if err != nil {
+			s.logger.Error("Failed to search tracks", "query", query, "error", err)
+			return nil, fmt.Errorf("failed to search tracks: %w", err)
+		}
+		setTrackURLs(tracks)
+		if tracks != nil {
+			response.Tracks = tracks
+		}
+	}
+
+	if searchType != models.SearchTypeTracks {
+		albums, err := s.albumRepo.Search(ctx, query, limit)
+		if err != nil {
+			s.logger.Error("Failed to search albums", "query", query, "error", err)
+			return nil, fmt.Errorf("failed to search albums: %w", err)
+		}
+		response.Albums = toAlbumResponses(albums)
+	}
+
+	if withFacets {
+		genre, err := s.facet(ctx, query, searchType, models.SearchFacetGenre)
+		if err != nil {
+			return nil, err
+		}
+		artist, err := s.facet(ctx, query, searchType, models.SearchFacetArtist)
+		if err != nil {
+			return nil, err
+		}
+		response.Facets = &models.SearchFacets{Genre: genre, Artist: artist}
+	}
+
+	return response, nil
+}
+
+// facet counts the tracks and albums of the searched kinds matching query by
+// facet and keeps the MaxSearchFacetValues most frequent values. Each kind
+// contributes its own top values, so a value just outside both tops can be
+// left out even if its combined count would rank.
+func (s *SearchService) facet(ctx context.Context, query, searchType, facet string) (map[string]int, error) {
+	counts := make(map[string]int)
+	if searchType != models.SearchTypeAlbums {
+		tracks, err := s.trackRepo.SearchFacetCounts(ctx, query, facet, models.MaxSearchFacetValues)
+		if err != nil {
+			s.logger.Error("Failed to count track search facet", "query", query, "facet", facet, "error", err)
+			return nil, fmt.Errorf("failed to count search facet: %w", err)
+		}
+		for value, count := range tracks {
+			counts[value] += count
+		}
+	}
+	if searchType != models.SearchTypeTracks {
+		albums, err := s.albumRepo.SearchFacetCounts(ctx, query, facet, models.MaxSearchFacetValues)
+		if err != nil {
+			s.logger.Error("Failed to count album search facet", "query", query, "facet", facet, "error", err)
+			return nil, fmt.Errorf("failed to count search facet: %w", err)
+		}
+		for value, count := range albums {
+			counts[value] += count
+		}
+	}
+
+	if len(counts) <= models.MaxSearchFacetValues {
+		return counts, nil
+	}
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	top := make(map[string]int, models.MaxSearchFacetValues)
+	for _, value := range values[:models.MaxSearchFacetValues] {
+		top[value] = counts[value]
+	}
+	return top, nil
+}
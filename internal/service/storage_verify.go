@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"koteyye_music_be/internal/models"
+	minioPkg "koteyye_music_be/pkg/minio"
+)
+
+// storageVerifyWorkers bounds the concurrent MinIO stat calls of VerifyStorage
+const storageVerifyWorkers = 8
+
+// storagePrefix is the prefix albums and their tracks are stored under
+const storagePrefix = "albums/"
+
+// VerifyStorage checks that every object tracks and albums refer to exists in
+// MinIO and lists objects under albums/ that nothing refers to. Cached cover
+// thumbnails of configured sizes count as referenced by their cover. It only
+// reports; nothing is deleted.
+func (s *TrackService) VerifyStorage(ctx context.Context) (*models.StorageReport, error) {
+	tracks, err := s.trackRepo.ListObjectKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	albums, err := s.albumRepo.ListCoverKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := s.minioSvc.Buckets()
+	var refs []models.MissingStorageObject
+	ref := func(kind, ownerID, bucket, key string) {
+		refs = append(refs, models.MissingStorageObject{
+			StorageObject: models.StorageObject{Bucket: bucket, Key: key},
+			Kind:          kind,
+			OwnerID:       ownerID,
+		})
+	}
+	for _, track := range tracks {
+		ref("track_audio", track.ID, buckets.Audio, track.AudioFileKey)
+		for _, key := range track.Renditions {
+			ref("track_rendition", track.ID, buckets.Audio, key)
+		}
+		if track.CoverImageKey != nil && *track.CoverImageKey != "" {
+			ref("track_cover", track.ID, buckets.Image, *track.CoverImageKey)
+		}
+	}
+	for _, album := range albums {
+		if album.CoverImageKey != "" {
+			ref("album_cover", album.ID, buckets.Image, album.CoverImageKey)
+		}
+	}
+
+	referenced := make(map[models.StorageObject]bool, len(refs))
+	for _, r := range refs {
+		referenced[r.StorageObject] = true
+		if r.Kind == "track_cover" || r.Kind == "album_cover" {
+			for _, size := range s.thumbnails.Sizes() {
+				referenced[models.StorageObject{Bucket: r.Bucket, Key: coverThumbnailKey(r.Key, size)}] = true
+			}
+		}
+	}
+
+	report := &models.StorageReport{
+		Checked:  len(refs),
+		Missing:  []models.MissingStorageObject{},
+		Orphaned: []models.StorageObject{},
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan models.MissingStorageObject)
+	for range storageVerifyWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				_, err := s.minioSvc.GetObjectInfo(ctx, r.Bucket, r.Key)
+				if err == nil {
+					continue
+				}
+
+				mu.Lock()
+				if errors.Is(err, minioPkg.ErrObjectNotFound) {
+					report.Missing = append(report.Missing, r)
+				} else {
+					s.logger.Error("Failed to check stored object", "kind", r.Kind, "owner_id", r.OwnerID, "key", r.Key, "error", err)
+					report.Failed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, r := range refs {
+		select {
+		case jobs <- r:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, bucket := range buckets.Unique() {
+		keys, err := s.minioSvc.ListObjectKeys(ctx, bucket, storagePrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list stored objects: %w", err)
+		}
+		for _, key := range keys {
+			object := models.StorageObject{Bucket: bucket, Key: key}
+			if !referenced[object] {
+				report.Orphaned = append(report.Orphaned, object)
+			}
+		}
+	}
+
+	// Workers finish in any order; sort so repeated runs are comparable
+	sort.Slice(report.Missing, func(i, j int) bool {
+		if report.Missing[i].Bucket != report.Missing[j].Bucket {
+			return report.Missing[i].Bucket < report.Missing[j].Bucket
+		}
+		return report.Missing[i].Key < report.Missing[j].Key
+	})
+
+	s.logger.Info("Storage verification finished", "checked", report.Checked, "missing", len(report.Missing), "orphaned", len(report.Orphaned), "failed", report.Failed)
+	return report, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	imagePkg "koteyye_music_be/pkg/image"
+	minioPkg "koteyye_music_be/pkg/minio"
+)
+
+// ThumbnailService generates resized album covers on first request and caches them in MinIO
+type ThumbnailService struct {
+	minioSvc *minioPkg.Service
+	widths   map[string]int
+	logger   *slog.Logger
+}
+
+func NewThumbnailService(minioSvc *minioPkg.Service, widths map[string]int, log *slog.Logger) *ThumbnailService {
+	return &ThumbnailService{
+		minioSvc: minioSvc,
+		widths:   widths,
+		logger:   log,
+	}
+}
+
+// Sizes returns the configured size names in alphabetical order
+func (s *ThumbnailService) Sizes() []string {
+	sizes := make([]string, 0, len(s.widths))
+	for name := range s.widths {
+		sizes = append(sizes, name)
+	}
+	sort.Strings(sizes)
+	return sizes
+}
+
+// HasSize reports whether size is a configured thumbnail size
+func (s *ThumbnailService) HasSize(size string) bool {
+	_, ok := s.widths[size]
+	return ok
+}
+
+// coverThumbnailKey returns the MinIO key of a cover thumbnail, stored next to
+// the cover: albums/{id}/cover.png becomes albums/{id}/cover_small.jpg
+func coverThumbnailKey(coverKey, size string) string {
+	return fmt.Sprintf("%s_%s.jpg", strings.TrimSuffix(coverKey, path.Ext(coverKey)), size)
+}
+
+// CoverThumbnail returns the key of the album or track cover resized to size,
+// generating and caching it from coverKey when it doesn't exist yet. albumID is
+// only used for logging. Callers should fall back to coverKey on error.
+func (s *ThumbnailService) CoverThumbnail(ctx context.Context, albumID, coverKey, size string) (string, error) {
+	width, ok := s.widths[size]
+	if !ok {
+		return "", fmt.Errorf("unknown thumbnail size %q", size)
+	}
+
+	key := coverThumbnailKey(coverKey, size)
+	if _, err := s.minioSvc.GetObjectInfo(ctx, s.minioSvc.Buckets().Image, key); err == nil {
+		return key, nil
+	}
+
+	original, err := s.minioSvc.GetObject(ctx, s.minioSvc.Buckets().Image, coverKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get original cover: %w", err)
+	}
+	defer original.Close()
+
+	data, err := imagePkg.Thumbnail(original, width)
+	if err != nil {
+		return "", fmt.Errorf("failed to resize cover: %w", err)
+	}
+
+	if _, err := s.minioSvc.UploadFile(ctx, s.minioSvc.Buckets().Image, key, bytes.NewReader(data), int64(len(data))); err != nil {
+		return "", fmt.Errorf("failed to store cover thumbnail: %w", err)
+	}
+
+	s.logger.Info("Cover thumbnail generated", "album_id", albumID, "cover_key", coverKey, "size", size, "width", width, "bytes", len(data))
+	return key, nil
+}
+
+// DeleteCoverThumbnails removes cached thumbnails of a cover so they are
+// regenerated from a new cover. Failures are only logged.
+func (s *ThumbnailService) DeleteCoverThumbnails(ctx context.Context, coverKey string) {
+	for size := range s.widths {
+		key := coverThumbnailKey(coverKey, size)
+		if err := s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Image, key); err != nil {
+			s.logger.Warn("Failed to delete cover thumbnail", "cover_key", coverKey, "key", key, "error", err)
+		}
+	}
+}
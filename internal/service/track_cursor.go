@@ -0,0 +1,44 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"koteyye_music_be/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned for a track listing cursor that can't be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// encodeTrackCursor returns the opaque cursor pointing after track
+func encodeTrackCursor(track models.TrackResponse) string {
+	raw := track.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + track.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeTrackCursor parses a cursor produced by encodeTrackCursor
+func decodeTrackCursor(cursor string) (*models.TrackCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &models.TrackCursor{CreatedAt: t.UTC(), ID: id}, nil
+}
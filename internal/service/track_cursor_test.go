@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"koteyye_music_be/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestTrackCursorRoundTripKeepsTiedPositions(t *testing.T) {
+	// Microsecond precision like Postgres timestamps; the tracks share a created_at
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 123456000, time.FixedZone("MSK", 3*60*60))
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		track := models.TrackResponse{ID: uuid.NewString(), CreatedAt: createdAt}
+
+		cursor := encodeTrackCursor(track)
+		if seen[cursor] {
+			t.Fatalf("tracks with a tied created_at share the cursor %q", cursor)
+		}
+		seen[cursor] = true
+
+		decoded, err := decodeTrackCursor(cursor)
+		if err != nil {
+			t.Fatalf("decode cursor: %v", err)
+		}
+		if !decoded.CreatedAt.Equal(createdAt) {
+			t.Errorf("created_at = %v, want %v", decoded.CreatedAt, createdAt)
+		}
+		if decoded.ID != track.ID {
+			t.Errorf("id = %q, want %q", decoded.ID, track.ID)
+		}
+	}
+}
+
+func TestDecodeTrackCursorRejectsGarbage(t *testing.T) {
+	for _, cursor := range []string{"!!!", "bm90LWEtY3Vyc29y", "MjAyNC0wMS0wMVQwMDowMDowMFp8bm90LWEtdXVpZA"} {
+		if _, err := decodeTrackCursor(cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeTrackCursor(%q) = %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
@@ -2,16 +2,23 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"mime/multipart"
 	"os"
-	"strconv"
+	"path"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"koteyye_music_be/internal/models"
+	"koteyye_music_be/internal/realtime"
 	"koteyye_music_be/internal/repository"
+	"koteyye_music_be/pkg/audio"
 	"koteyye_music_be/pkg/logger"
 	minioPkg "koteyye_music_be/pkg/minio"
 
@@ -24,17 +31,44 @@ type TrackService struct {
 	albumRepo *repository.AlbumRepository
 	Minio     *minioPkg.Client
 	minioSvc  *minioPkg.Service
-	logger    *slog.Logger
+	// thumbnails caches resized track covers that must go with the track
+	thumbnails *ThumbnailService
+	// live receives play and like count changes for WebSocket subscribers
+	live *realtime.Hub
+	// mp3Bitrate is the bitrate in kbps audio is transcoded to
+	mp3Bitrate int
+	// renditions maps extra stream qualities to their MP3 bitrate in kbps
+	renditions map[string]int
+	// streamURLExpiry is how long presigned audio URLs stay valid
+	streamURLExpiry time.Duration
+	// tempDir holds temp files of streaming, probing and transcoding
+	tempDir string
+	// uploads lets shutdown wait for track moves copying objects
+	uploads *uploadTracker
+	// plays debounces and batches play count increments
+	plays  *PlayTracker
+	logger *slog.Logger
 }
 
-func NewTrackService(trackRepo *repository.TrackRepository, albumRepo *repository.AlbumRepository, minio *minioPkg.Client, minioSvc *minioPkg.Service, log *slog.Logger) *TrackService {
-	return &TrackService{
-		trackRepo: trackRepo,
-		albumRepo: albumRepo,
-		Minio:     minio,
-		minioSvc:  minioSvc,
-		logger:    log,
+func NewTrackService(trackRepo *repository.TrackRepository, albumRepo *repository.AlbumRepository, minio *minioPkg.Client, minioSvc *minioPkg.Service, thumbnails *ThumbnailService, live *realtime.Hub, plays *PlayTracker, mp3Bitrate int, renditions map[string]int, streamURLExpiry time.Duration, tempDir string, log *slog.Logger) *TrackService {
+	s := &TrackService{
+		trackRepo:       trackRepo,
+		albumRepo:       albumRepo,
+		Minio:           minio,
+		minioSvc:        minioSvc,
+		thumbnails:      thumbnails,
+		live:            live,
+		mp3Bitrate:      mp3Bitrate,
+		renditions:      renditions,
+		streamURLExpiry: streamURLExpiry,
+		tempDir:         tempDir,
+		uploads:         newUploadTracker(minioSvc, log),
+		plays:           plays,
+		logger:          log,
 	}
+	// Live subscribers see play counts once buffered plays are written
+	plays.onFlush = s.publishStats
+	return s
 }
 
 // UploadTrack handles the complete track upload process (DEPRECATED - use albums)
@@ -44,9 +78,9 @@ func (s *TrackService) UploadTrack(ctx context.Context, userID int, title, artis
 
 // GetTrack retrieves a track by ID
 func (s *TrackService) GetTrack(ctx context.Context, id string) (*models.Track, error) {
-	// Validate and parse UUID
+	// A malformed ID can't name an existing track
 	if _, err := uuid.Parse(id); err != nil {
-		return nil, fmt.Errorf("invalid track ID format: %w", err)
+		return nil, fmt.Errorf("invalid track ID format: %w", repository.ErrTrackNotFound)
 	}
 
 	track, err := s.trackRepo.GetTrackByID(ctx, id)
@@ -58,6 +92,68 @@ func (s *TrackService) GetTrack(ctx context.Context, id string) (*models.Track,
 	return track, nil
 }
 
+// DownloadFilename returns the "Artist - Title.mp3" name a track's audio is
+// saved under, falling back to the album artist when the track has none
+func (s *TrackService) DownloadFilename(ctx context.Context, track *models.Track) (string, error) {
+	artist := ""
+	if track.Artist != nil {
+		artist = strings.TrimSpace(*track.Artist)
+	}
+	if artist == "" {
+		album, err := s.albumRepo.GetByID(ctx, track.AlbumID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get track album: %w", err)
+		}
+		artist = album.Artist
+	}
+
+	if artist == "" {
+		return track.Title + ".mp3", nil
+	}
+	return artist + " - " + track.Title + ".mp3", nil
+}
+
+// Close rejects new track moves and waits for running ones until ctx is done;
+// objects copied by moves still running then are deleted
+func (s *TrackService) Close(ctx context.Context) error {
+	return s.uploads.Close(ctx)
+}
+
+// TempDir returns the directory temp files of audio processing go in
+func (s *TrackService) TempDir() string {
+	return s.tempDir
+}
+
+// GetVisibleTrack retrieves a track like GetTrack but reports a track that
+// isn't public as ErrTrackNotFound unless includeDrafts is set
+func (s *TrackService) GetVisibleTrack(ctx context.Context, id string, includeDrafts bool) (*models.Track, error) {
+	track, err := s.GetTrack(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !includeDrafts && !track.Public {
+		return nil, fmt.Errorf("track %s is a draft: %w", id, repository.ErrTrackNotFound)
+	}
+	return track, nil
+}
+
+// GetStreamURL returns a presigned URL for the track's audio in the given
+// quality that expires after the configured time
+func (s *TrackService) GetStreamURL(ctx context.Context, trackID, quality string, includeDrafts bool) (*models.StreamURLResponse, error) {
+	track, err := s.GetVisibleTrack(ctx, trackID, includeDrafts)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.streamURLExpiry).UTC()
+	presigned, err := s.minioSvc.PresignedGetURL(ctx, s.minioSvc.Buckets().Audio, s.AudioKey(track, quality), s.streamURLExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StreamURLResponse{URL: presigned.String(), ExpiresAt: expiresAt}, nil
+}
+
 // ListTracks returns a paginated list of tracks (DEPRECATED - use ListTracksWithOptionalUser)
 func (s *TrackService) ListTracks(ctx context.Context, page, limit int) ([]models.Track, int, error) {
 	return nil, 0, fmt.Errorf("deprecated method - use ListTracksWithOptionalUser")
@@ -84,7 +180,7 @@ func (s *TrackService) ListTracksWithLikeStatus(ctx context.Context, page, limit
 	}
 
 	// Get total count of tracks
-	total, err := s.trackRepo.CountTracks(ctx)
+	total, err := s.trackRepo.CountTracks(ctx, models.TrackFilter{})
 	if err != nil {
 		s.logger.Error("Failed to count tracks", "error", err)
 		total = len(tracks) // Fallback to tracks length if count fails
@@ -93,9 +189,10 @@ func (s *TrackService) ListTracksWithLikeStatus(ctx context.Context, page, limit
 	return tracks, total, nil
 }
 
-// ListTracksWithOptionalUser returns a paginated list of tracks with album info and optional like status and genre filtering
-// If userID is 0, returns tracks without like status for unauthenticated users
-func (s *TrackService) ListTracksWithOptionalUser(ctx context.Context, page, limit int, userID int, genreFilter string) ([]models.TrackResponse, int, error) {
+// ListTracksWithOptionalUser returns a paginated list of tracks with album info and optional like status, genre and artist filtering
+// If userID is 0, returns tracks without like status for unauthenticated users.
+// A non-empty cursor takes precedence over page. The returned next cursor is empty on the last page.
+func (s *TrackService) ListTracksWithOptionalUser(ctx context.Context, page, limit int, userID int, filter models.TrackFilter, cursor string) ([]models.TrackResponse, int, string, error) {
 	if limit <= 0 {
 		limit = 20
 	}
@@ -108,10 +205,26 @@ func (s *TrackService) ListTracksWithOptionalUser(ctx context.Context, page, lim
 		offset = 0
 	}
 
-	tracks, err := s.trackRepo.ListTracksWithAlbumInfo(ctx, limit, offset, userID, genreFilter)
+	var after *models.TrackCursor
+	if cursor != "" {
+		decoded, err := decodeTrackCursor(cursor)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		after = decoded
+		offset = 0
+	}
+
+	tracks, err := s.trackRepo.ListTracksWithAlbumInfo(ctx, limit, offset, userID, filter, after)
 	if err != nil {
 		s.logger.Error("Failed to list tracks with album info", "error", err)
-		return nil, 0, fmt.Errorf("failed to list tracks: %w", err)
+		return nil, 0, "", fmt.Errorf("failed to list tracks: %w", err)
+	}
+
+	// A full page may have more tracks after it
+	nextCursor := ""
+	if len(tracks) == limit {
+		nextCursor = encodeTrackCursor(tracks[len(tracks)-1])
 	}
 
 	// Generate BE endpoint URLs for all tracks
@@ -126,14 +239,25 @@ func (s *TrackService) ListTracksWithOptionalUser(ctx context.Context, page, lim
 		}
 	}
 
-	// Get total count of tracks
-	total, err := s.trackRepo.CountTracks(ctx)
+	// Get total count of tracks matching the filter
+	total, err := s.trackRepo.CountTracks(ctx, filter)
 	if err != nil {
 		s.logger.Error("Failed to count tracks", "error", err)
 		total = len(tracks) // Fallback to tracks length if count fails
 	}
 
-	return tracks, total, nil
+	return tracks, total, nextCursor, nil
+}
+
+// ListArtists returns distinct artist names with track counts
+func (s *TrackService) ListArtists(ctx context.Context) ([]models.Artist, error) {
+	artists, err := s.trackRepo.ListArtists(ctx)
+	if err != nil {
+		s.logger.Error("Failed to list artists", "error", err)
+		return nil, fmt.Errorf("failed to list artists: %w", err)
+	}
+
+	return artists, nil
 }
 
 // GetUserTracks returns all tracks for a specific user (DEPRECATED - use GetUserTracksWithAlbumInfo)
@@ -141,12 +265,18 @@ func (s *TrackService) GetUserTracks(ctx context.Context, userID int) ([]models.
 	return nil, fmt.Errorf("deprecated method - use GetUserTracksWithAlbumInfo")
 }
 
-// GetUserTracksWithAlbumInfo returns all tracks for a specific user with album info
-func (s *TrackService) GetUserTracksWithAlbumInfo(ctx context.Context, userID int) ([]models.TrackResponse, error) {
-	tracks, err := s.trackRepo.GetTracksByUserID(ctx, userID)
+// GetUserTracksWithAlbumInfo returns a page of a user's tracks with album info and the user's track count
+func (s *TrackService) GetUserTracksWithAlbumInfo(ctx context.Context, userID int, page, limit int) ([]models.TrackResponse, int, error) {
+	offset := (page - 1) * limit
+	tracks, err := s.trackRepo.GetTracksByUserID(ctx, userID, limit, offset)
 	if err != nil {
 		s.logger.Error("Failed to get user tracks", "user_id", userID, "error", err)
-		return nil, fmt.Errorf("failed to get user tracks: %w", err)
+		return nil, 0, fmt.Errorf("failed to get user tracks: %w", err)
+	}
+
+	total, err := s.trackRepo.CountTracksByUserID(ctx, userID)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// Generate BE endpoint URLs for all tracks
@@ -161,7 +291,45 @@ func (s *TrackService) GetUserTracksWithAlbumInfo(ctx context.Context, userID in
 		}
 	}
 
-	return tracks, nil
+	return tracks, total, nil
+}
+
+// ErrInvalidPeriod is returned for an analytics period other than day, week or month
+var ErrInvalidPeriod = errors.New("invalid period")
+
+// analyticsPeriods maps analytics period names to their window length
+var analyticsPeriods = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// GetTopTracks returns up to limit tracks most played within the last day, week or month
+func (s *TrackService) GetTopTracks(ctx context.Context, period string, limit int) (*models.TopTracksResponse, error) {
+	window, ok := analyticsPeriods[period]
+	if !ok {
+		return nil, fmt.Errorf("%w %q: must be day, week or month", ErrInvalidPeriod, period)
+	}
+
+	since := time.Now().Add(-window)
+	tracks, err := s.trackRepo.GetTopTracks(ctx, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top tracks: %w", err)
+	}
+
+	for i := range tracks {
+		tracks[i].CoverURL = fmt.Sprintf("/tracks/%s/cover", tracks[i].ID)
+		tracks[i].AudioURL = fmt.Sprintf("/tracks/%s/stream", tracks[i].ID)
+		if tracks[i].CoverImageKey != "" {
+			tracks[i].ImageKey = &tracks[i].CoverImageKey
+		}
+	}
+
+	return &models.TopTracksResponse{
+		Period: period,
+		Since:  since,
+		Tracks: tracks,
+	}, nil
 }
 
 // DeleteTrack deletes a track by ID
@@ -179,12 +347,23 @@ func (s *TrackService) DeleteTrack(ctx context.Context, id string) error {
 	}
 
 	// Delete audio from MinIO
-	if err := s.minioSvc.DeleteFile(ctx, "music-files", track.AudioFileKey); err != nil {
+	if err := s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Audio, track.AudioFileKey); err != nil {
 		s.logger.Error("Failed to delete audio from MinIO", "track_id", id, "error", err)
 		// Continue even if MinIO deletion fails
 	}
+	for quality, key := range track.Renditions {
+		if err := s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Audio, key); err != nil {
+			s.logger.Error("Failed to delete audio rendition from MinIO", "track_id", id, "quality", quality, "error", err)
+		}
+	}
 
-	// Note: In new album architecture, cover images belong to albums, not tracks
+	// Delete the track's own cover; album covers stay with the album
+	if track.CoverImageKey != nil {
+		if err := s.minioSvc.DeleteFile(ctx, s.minioSvc.Buckets().Image, *track.CoverImageKey); err != nil {
+			s.logger.Error("Failed to delete track cover from MinIO", "track_id", id, "error", err)
+		}
+		s.thumbnails.DeleteCoverThumbnails(ctx, *track.CoverImageKey)
+	}
 
 	// Delete from database
 	if err := s.trackRepo.DeleteTrack(ctx, id); err != nil {
@@ -218,56 +397,134 @@ func (s *TrackService) saveUploadedFile(file *multipart.FileHeader, path string)
 	return nil
 }
 
-// convertAudioToMP3 converts audio file to MP3 using ffmpeg and returns duration in seconds
-func (s *TrackService) convertAudioToMP3(inputPath, outputPath string) (float64, error) {
-	s.logger.Info("Starting audio conversion", "input", inputPath, "output", outputPath)
+// convertAudioToMP3 converts audio file to MP3 at bitrate kbps using ffmpeg and returns
+// the metadata of the converted file, so duration and bitrate describe what is stored
+func (s *TrackService) convertAudioToMP3(inputPath, outputPath string, bitrate int) (*audio.Metadata, error) {
+	s.logger.Info("Starting audio conversion", "input", inputPath, "output", outputPath, "bitrate_kbps", bitrate)
 
-	// First, get duration
-	duration, err := s.getAudioDuration(inputPath)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get audio duration: %w", err)
-	}
-
-	s.logger.Info("Audio duration detected", "duration", duration)
-
-	// Convert to MP3 at 320kbps
 	args := []string{
 		"-i", inputPath,
+		"-vn", // Drop embedded cover art, it is stored separately
 		"-codec:a", "libmp3lame",
-		"-b:a", "320k",
+		"-b:a", fmt.Sprintf("%dk", bitrate),
 		"-y", // Overwrite output file if exists
 		outputPath,
 	}
 
 	s.logger.Info("Running ffmpeg conversion", "args", args)
 	if err := s.runFFmpeg(args); err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	s.logger.Info("Audio conversion completed successfully")
-	return duration, nil
+	metadata, err := audio.ExtractMetadataFromPath(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read converted audio: %w", err)
+	}
+
+	s.logger.Info("Audio conversion completed successfully", "duration", metadata.Duration)
+	return metadata, nil
 }
 
-// getAudioDuration extracts duration from audio file using ffprobe
-func (s *TrackService) getAudioDuration(filePath string) (float64, error) {
-	args := []string{
-		"-v", "error",
-		"-show_entries", "format=duration",
-		"-of", "default=noprint_wrappers=1:nokey=1",
-		filePath,
+// BackfillDurations probes the audio of every track stored with a zero
+// duration or without bitrate and format, and saves what ffprobe reports.
+// A track that can't be fixed is logged and counted as failed without
+// stopping the rest of the batch.
+func (s *TrackService) BackfillDurations(ctx context.Context) (*models.BackfillDurationsResponse, error) {
+	tracks, err := s.trackRepo.GetTracksMissingAudioInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracks missing audio info: %w", err)
 	}
 
-	output, err := s.runFFprobe(args)
+	result := &models.BackfillDurationsResponse{Checked: len(tracks)}
+	for _, track := range tracks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if err := s.backfillTrack(ctx, track); err != nil {
+			s.logger.Error("Failed to backfill track audio info", "track_id", track.ID, "audio_key", track.AudioFileKey, "error", err)
+			result.Failed++
+			continue
+		}
+		result.Fixed++
+	}
+
+	s.logger.Info("Audio info backfill finished", "checked", result.Checked, "fixed", result.Fixed, "failed", result.Failed)
+	return result, nil
+}
+
+// backfillTrack measures a stored track's audio and saves its duration, bitrate and format
+func (s *TrackService) backfillTrack(ctx context.Context, track models.Track) error {
+	metadata, err := s.probeStoredAudio(ctx, track.AudioFileKey)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	duration, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+	bitrate, format, err := audioQuality(metadata)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse duration: %w", err)
+		return err
+	}
+
+	duration := int(math.Round(metadata.Duration))
+	if duration < 1 {
+		return fmt.Errorf("audio is shorter than a second (%.3fs)", metadata.Duration)
 	}
 
-	return duration, nil
+	if err := s.trackRepo.UpdateTrackAudioInfo(ctx, track.ID, duration, bitrate, format); err != nil {
+		return err
+	}
+
+	s.logger.Info("Track audio info backfilled", "track_id", track.ID, "duration_seconds", duration, "bitrate", metadata.BitRate, "format", metadata.Format)
+	return nil
+}
+
+// probeStoredAudio downloads an audio object to a temp file and returns its
+// metadata as reported by ffprobe
+func (s *TrackService) probeStoredAudio(ctx context.Context, audioKey string) (*audio.Metadata, error) {
+	object, err := s.minioSvc.GetObject(ctx, s.minioSvc.Buckets().Audio, audioKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio object: %w", err)
+	}
+	defer object.Close()
+
+	tempFile, err := os.CreateTemp(s.tempDir, "backfill-*"+path.Ext(audioKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	if _, err := io.Copy(tempFile, object); err != nil {
+		return nil, fmt.Errorf("failed to download audio object: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	return audio.ExtractMetadataFromPath(tempFile.Name())
+}
+
+// audioQuality returns the bitrate and format of metadata as stored on a
+// track. A zero bitrate or empty format means ffprobe didn't report it and
+// is stored as unknown; a negative bitrate is rejected.
+func audioQuality(metadata *audio.Metadata) (*int, *string, error) {
+	if metadata.BitRate < 0 {
+		return nil, nil, fmt.Errorf("invalid audio format: negative bitrate %d", metadata.BitRate)
+	}
+
+	var bitrate *int
+	if metadata.BitRate > 0 {
+		value := metadata.BitRate
+		bitrate = &value
+	}
+
+	var format *string
+	if metadata.Format != "" {
+		value := metadata.Format
+		format = &value
+	}
+
+	return bitrate, format, nil
 }
 
 // runFFmpeg executes ffmpeg command
@@ -332,33 +589,158 @@ func (s *TrackService) ToggleLike(ctx context.Context, userID int, trackID strin
 	}
 
 	s.logger.Info("Like toggled", "user_id", userID, "track_id", trackID, "liked", isLiked)
+	s.publishStats(ctx, trackID)
 
 	return isLiked, likesCount, nil
 }
 
-// IncrementPlays increments the play count for a track
-func (s *TrackService) IncrementPlays(ctx context.Context, trackID string) error {
+// SetLike sets the like state of a track to the desired value
+// Returns (isLiked, likesCount, error)
+func (s *TrackService) SetLike(ctx context.Context, userID int, trackID string, liked bool) (bool, int, error) {
 	// Validate and parse UUID
 	if _, err := uuid.Parse(trackID); err != nil {
-		return fmt.Errorf("invalid track ID format: %w", err)
+		return false, 0, fmt.Errorf("invalid track ID format: %w", err)
 	}
 
-	if err := s.trackRepo.IncrementPlays(ctx, trackID); err != nil {
-		s.logger.Error("Failed to increment plays", "track_id", trackID, "error", err)
-		return fmt.Errorf("failed to increment plays: %w", err)
+	isLiked, likesCount, err := s.trackRepo.SetLike(ctx, userID, trackID, liked)
+	if err != nil {
+		s.logger.Error("Failed to set like", "user_id", userID, "track_id", trackID, "liked", liked, "error", err)
+		return false, 0, fmt.Errorf("failed to set like: %w", err)
 	}
 
-	return nil
+	s.logger.Info("Like set", "user_id", userID, "track_id", trackID, "liked", isLiked)
+	s.publishStats(ctx, trackID)
+
+	return isLiked, likesCount, nil
 }
 
-// GetTrackWithAlbumInfo retrieves a track by ID with album info and like status
-func (s *TrackService) GetTrackWithAlbumInfo(ctx context.Context, trackID string, userID int) (*models.TrackResponse, error) {
+// AddLike idempotently likes a track and returns its likes count
+func (s *TrackService) AddLike(ctx context.Context, userID int, trackID string) (int, error) {
+	if _, err := uuid.Parse(trackID); err != nil {
+		return 0, fmt.Errorf("invalid track ID format: %w", err)
+	}
+
+	likesCount, err := s.trackRepo.AddLike(ctx, userID, trackID)
+	if err != nil {
+		s.logger.Error("Failed to add like", "user_id", userID, "track_id", trackID, "error", err)
+		return 0, fmt.Errorf("failed to add like: %w", err)
+	}
+
+	s.logger.Info("Like added", "user_id", userID, "track_id", trackID)
+	s.publishStats(ctx, trackID)
+
+	return likesCount, nil
+}
+
+// RemoveLike idempotently removes the user's like and returns the track's likes count
+func (s *TrackService) RemoveLike(ctx context.Context, userID int, trackID string) (int, error) {
+	if _, err := uuid.Parse(trackID); err != nil {
+		return 0, fmt.Errorf("invalid track ID format: %w", err)
+	}
+
+	likesCount, err := s.trackRepo.RemoveLike(ctx, userID, trackID)
+	if err != nil {
+		s.logger.Error("Failed to remove like", "user_id", userID, "track_id", trackID, "error", err)
+		return 0, fmt.Errorf("failed to remove like: %w", err)
+	}
+
+	s.logger.Info("Like removed", "user_id", userID, "track_id", trackID)
+	s.publishStats(ctx, trackID)
+
+	return likesCount, nil
+}
+
+// IncrementPlays counts a play of a track by the listener and records it in the
+// user's history. Repeated plays within the debounce interval are ignored and
+// counted plays may be written later in a batch; userID is 0 for anonymous
+// listeners. It reports whether the play was counted.
+func (s *TrackService) IncrementPlays(ctx context.Context, trackID string, userID int, listener string) (bool, error) {
 	// Validate and parse UUID
 	if _, err := uuid.Parse(trackID); err != nil {
-		return nil, fmt.Errorf("invalid track ID format: %w", err)
+		return false, fmt.Errorf("invalid track ID format: %w", err)
 	}
 
-	track, err := s.trackRepo.GetTrackWithAlbumInfo(ctx, trackID, userID)
+	counted, err := s.plays.Record(ctx, listener, userID, trackID)
+	if err != nil {
+		s.logger.Error("Failed to increment plays", "track_id", trackID, "user_id", userID, "error", err)
+		return false, fmt.Errorf("failed to increment plays: %w", err)
+	}
+
+	return counted, nil
+}
+
+// SubscribeStats registers a live subscriber for the track's play and like counts
+func (s *TrackService) SubscribeStats(trackID string) (*realtime.Subscription, error) {
+	return s.live.Subscribe(trackID)
+}
+
+// publishStats pushes the track's current counts to live subscribers. Stats
+// are only loaded when someone listens; failures are logged, never returned,
+// so a counter update never fails because of them.
+func (s *TrackService) publishStats(ctx context.Context, trackID string) {
+	if !s.live.HasSubscribers(trackID) {
+		return
+	}
+
+	stats, err := s.trackRepo.GetTrackStats(ctx, trackID)
+	if err != nil {
+		s.logger.Warn("Failed to load track stats for live subscribers", "track_id", trackID, "error", err)
+		return
+	}
+	s.live.Publish(trackID, *stats)
+}
+
+// ListTracksByAlbum returns a page of an album's tracks in album order and the album's track count.
+// It fails with repository.ErrAlbumNotFound when the album does not exist, or
+// is a draft and includeDrafts isn't set; draft tracks are only listed with includeDrafts.
+func (s *TrackService) ListTracksByAlbum(ctx context.Context, albumID string, limit, offset int, userID int, includeDrafts bool) ([]models.TrackResponse, int, error) {
+	// Distinguish a missing album from an empty one
+	album, err := s.albumRepo.GetByID(ctx, albumID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get album: %w", err)
+	}
+	if !includeDrafts && album.Status != models.StatusPublished {
+		return nil, 0, fmt.Errorf("album %s is a draft: %w", albumID, repository.ErrAlbumNotFound)
+	}
+
+	tracks, err := s.trackRepo.ListTracksByAlbum(ctx, albumID, limit, offset, userID, includeDrafts)
+	if err != nil {
+		s.logger.Error("Failed to list album tracks", "album_id", albumID, "error", err)
+		return nil, 0, fmt.Errorf("failed to list album tracks: %w", err)
+	}
+
+	setTrackURLs(tracks)
+
+	total, err := s.trackRepo.CountTracksByAlbum(ctx, albumID, includeDrafts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return tracks, total, nil
+}
+
+// setTrackURLs fills in the BE endpoint URLs and image key of listed tracks
+func setTrackURLs(tracks []models.TrackResponse) {
+	for i := range tracks {
+		// Cover URL points to track cover endpoint (which gets it from album)
+		tracks[i].CoverURL = fmt.Sprintf("/tracks/%s/cover", tracks[i].ID)
+		// Audio URL points to track stream endpoint
+		tracks[i].AudioURL = fmt.Sprintf("/tracks/%s/stream", tracks[i].ID)
+		if tracks[i].CoverImageKey != "" {
+			tracks[i].ImageKey = &tracks[i].CoverImageKey
+		}
+	}
+}
+
+// GetTrackWithAlbumInfo retrieves a track by ID with album info and like status.
+// A track that isn't public is reported as not found unless includeDrafts is set.
+func (s *TrackService) GetTrackWithAlbumInfo(ctx context.Context, trackID string, userID int, includeDrafts bool) (*models.TrackResponse, error) {
+	// A malformed ID can't name an existing track
+	if _, err := uuid.Parse(trackID); err != nil {
+		return nil, fmt.Errorf("invalid track ID format: %w", repository.ErrTrackNotFound)
+	}
+
+	track, err := s.trackRepo.GetTrackWithAlbumInfo(ctx, trackID, userID, includeDrafts)
 	if err != nil {
 		s.logger.Error("Failed to get track with album info", "track_id", trackID, "error", err)
 		return nil, fmt.Errorf("failed to get track: %w", err)
@@ -370,17 +752,262 @@ func (s *TrackService) GetTrackWithAlbumInfo(ctx context.Context, trackID string
 	return track, nil
 }
 
-// GetUserLikedTrackIDs returns a list of track IDs liked by the user
+// ErrInvalidStatus is returned for a publication status other than draft or published
+var ErrInvalidStatus = errors.New("invalid status")
+
+// SetTrackStatus drafts or publishes a single track and returns it. A
+// published track is only public while its album is published too.
+func (s *TrackService) SetTrackStatus(ctx context.Context, trackID, status string) (*models.TrackResponse, error) {
+	if status != models.StatusDraft && status != models.StatusPublished {
+		return nil, fmt.Errorf("%w: status must be %s or %s", ErrInvalidStatus, models.StatusDraft, models.StatusPublished)
+	}
+	if _, err := s.GetTrack(ctx, trackID); err != nil {
+		return nil, err
+	}
+
+	if err := s.trackRepo.SetStatus(ctx, trackID, status); err != nil {
+		return nil, fmt.Errorf("failed to set track status: %w", err)
+	}
+
+	s.logger.Info("Track status changed", "track_id", trackID, "status", status)
+	return s.GetTrackWithAlbumInfo(ctx, trackID, 0, true)
+}
+
+// objectMove is a stored object that is copied to a new key when its track
+// changes album
+type objectMove struct {
+	bucket, from, to string
+}
+
+// MoveTrackToAlbum moves a track to another album, appending it to the end of
+// that album. Its objects under albums/{old}/ are copied under albums/{new}/
+// before the row is updated, and the originals are deleted only once the
+// update succeeded; if copying or the update fails the copies are removed and
+// the track stays where it was.
+func (s *TrackService) MoveTrackToAlbum(ctx context.Context, trackID, newAlbumID string) (*models.TrackResponse, error) {
+	upload, ctx, err := s.uploads.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer upload.end()
+
+	track, err := s.GetTrack(ctx, trackID)
+	if err != nil {
+		return nil, err
+	}
+	album, err := s.albumRepo.GetByID(ctx, newAlbumID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get destination album: %w", err)
+	}
+	if album.ID == track.AlbumID {
+		return s.GetTrackWithAlbumInfo(ctx, trackID, 0, true)
+	}
+
+	oldPrefix := fmt.Sprintf("albums/%s/", track.AlbumID)
+	newPrefix := fmt.Sprintf("albums/%s/", album.ID)
+	var moves []objectMove
+	// moveKey returns the key an object gets in the new album; keys outside
+	// the old album's folder stay as they are
+	moveKey := func(bucket, key string) string {
+		if !strings.HasPrefix(key, oldPrefix) {
+			return key
+		}
+		moved := newPrefix + strings.TrimPrefix(key, oldPrefix)
+		moves = append(moves, objectMove{bucket: bucket, from: key, to: moved})
+		return moved
+	}
+
+	oldCoverKey := track.CoverImageKey
+	track.AlbumID = album.ID
+	track.AudioFileKey = moveKey(s.minioSvc.Buckets().Audio, track.AudioFileKey)
+	renditions := make(map[string]string, len(track.Renditions))
+	for quality, key := range track.Renditions {
+		renditions[quality] = moveKey(s.minioSvc.Buckets().Audio, key)
+	}
+	track.Renditions = renditions
+	if track.CoverImageKey != nil {
+		coverKey := moveKey(s.minioSvc.Buckets().Image, *track.CoverImageKey)
+		track.CoverImageKey = &coverKey
+	}
+
+	for _, move := range moves {
+		upload.writes(move.bucket, move.to)
+	}
+	for i, move := range moves {
+		if err := s.minioSvc.CopyFile(ctx, move.bucket, move.from, move.to); err != nil {
+			s.deleteMovedObjects(ctx, moves[:i], func(m objectMove) string { return m.to })
+			return nil, fmt.Errorf("failed to move track object: %w", err)
+		}
+	}
+
+	if err := s.trackRepo.MoveToAlbum(ctx, track); err != nil {
+		s.deleteMovedObjects(ctx, moves, func(m objectMove) string { return m.to })
+		return nil, fmt.Errorf("failed to move track: %w", err)
+	}
+	upload.commit()
+
+	// The track now points at the copies; originals that fail to delete are only orphaned
+	s.deleteMovedObjects(ctx, moves, func(m objectMove) string { return m.from })
+	if oldCoverKey != nil && *oldCoverKey != *track.CoverImageKey {
+		s.thumbnails.DeleteCoverThumbnails(ctx, *oldCoverKey)
+	}
+
+	s.logger.Info("Track moved to album", "track_id", trackID, "album_id", album.ID)
+	return s.GetTrackWithAlbumInfo(ctx, trackID, 0, true)
+}
+
+// deleteMovedObjects removes one side of the given object moves, logging failures
+func (s *TrackService) deleteMovedObjects(ctx context.Context, moves []objectMove, key func(objectMove) string) {
+	for _, move := range moves {
+		if err := s.minioSvc.DeleteFile(ctx, move.bucket, key(move)); err != nil {
+			s.logger.Error("Failed to delete track object", "key", key(move), "error", err)
+		}
+	}
+}
+
+// GetUserLikedTracks returns the tracks liked by the user with the time of
+// each like, newest first. A limit of 0 returns all of them.
+func (s *TrackService) GetUserLikedTracks(ctx context.Context, userID, limit, offset int) ([]models.LikedTrack, error) {
+	liked, err := s.trackRepo.GetUserLikedTracks(ctx, userID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to get user liked tracks", "user_id", userID, "error", err)
+		return nil, fmt.Errorf("failed to get user liked tracks: %w", err)
+	}
+
+	return liked, nil
+}
+
+// GetUserLikedTrackIDs returns the IDs of all tracks liked by the user, newest like first
 func (s *TrackService) GetUserLikedTrackIDs(ctx context.Context, userID int) ([]string, error) {
-	trackIDs, err := s.trackRepo.GetUserLikedTrackIDs(ctx, userID)
+	liked, err := s.GetUserLikedTracks(ctx, userID, 0, 0)
 	if err != nil {
-		s.logger.Error("Failed to get user liked track IDs", "user_id", userID, "error", err)
-		return nil, fmt.Errorf("failed to get user liked track IDs: %w", err)
+		return nil, err
 	}
 
+	trackIDs := make([]string, len(liked))
+	for i, like := range liked {
+		trackIDs[i] = like.TrackID
+	}
 	return trackIDs, nil
 }
 
+// recentPlayWindow is how far back a user's plays exclude tracks from genre recommendations
+const recentPlayWindow = 7 * 24 * time.Hour
+
+// RecommendNext picks the track to play after trackID, trying in order: the next
+// track of the same album, a track of the same genre the user hasn't played
+// recently, and the most played track. It returns nil if there is no other track.
+func (s *TrackService) RecommendNext(ctx context.Context, trackID string, userID int) (*models.NextTrackResponse, error) {
+	// The seed track must exist; this also validates the ID
+	if _, err := s.GetTrack(ctx, trackID); err != nil {
+		return nil, err
+	}
+
+	tiers := []struct {
+		reason string
+		pick   func() (string, error)
+	}{
+		{models.NextTrackReasonAlbum, func() (string, error) {
+			return s.trackRepo.GetNextTrackInAlbum(ctx, trackID)
+		}},
+		{models.NextTrackReasonGenre, func() (string, error) {
+			return s.trackRepo.GetRandomTrackInGenre(ctx, trackID, userID, time.Now().Add(-recentPlayWindow))
+		}},
+		{models.NextTrackReasonPopular, func() (string, error) {
+			return s.trackRepo.GetMostPlayedTrack(ctx, trackID)
+		}},
+	}
+
+	for _, tier := range tiers {
+		nextID, err := tier.pick()
+		if err != nil {
+			s.logger.Error("Failed to recommend next track", "track_id", trackID, "reason", tier.reason, "error", err)
+			return nil, fmt.Errorf("failed to recommend next track: %w", err)
+		}
+		if nextID == "" {
+			continue
+		}
+
+		next, err := s.GetTrackWithAlbumInfo(ctx, nextID, userID, false)
+		if err != nil {
+			return nil, err
+		}
+		next.AudioURL = fmt.Sprintf("/tracks/%s/stream", next.ID)
+		return &models.NextTrackResponse{Reason: tier.reason, Track: *next}, nil
+	}
+
+	return nil, nil
+}
+
+// GetRadio returns up to limit random tracks of the genre that aren't in
+// excludeIDs. For an authenticated user tracks played within the last week are
+// only used when nothing else is left, so the radio never runs dry.
+func (s *TrackService) GetRadio(ctx context.Context, genre string, excludeIDs []string, userID int, limit int) ([]models.TrackResponse, error) {
+	if len(excludeIDs) > models.MaxRadioExclude {
+		return nil, fmt.Errorf("%w: at most %d excluded tracks allowed, got %d", ErrInvalidTrackIDs, models.MaxRadioExclude, len(excludeIDs))
+	}
+	for _, id := range excludeIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, fmt.Errorf("%w: %q is not a UUID", ErrInvalidTrackIDs, id)
+		}
+	}
+
+	tracks, err := s.trackRepo.GetRandomTracksByGenre(ctx, genre, excludeIDs, limit, userID, time.Now().Add(-recentPlayWindow))
+	if err != nil {
+		s.logger.Error("Failed to get radio tracks", "genre", genre, "error", err)
+		return nil, fmt.Errorf("failed to get radio tracks: %w", err)
+	}
+
+	for i := range tracks {
+		tracks[i].CoverURL = fmt.Sprintf("/tracks/%s/cover", tracks[i].ID)
+		tracks[i].AudioURL = fmt.Sprintf("/tracks/%s/stream", tracks[i].ID)
+		if tracks[i].CoverImageKey != "" {
+			tracks[i].ImageKey = &tracks[i].CoverImageKey
+		}
+	}
+
+	return tracks, nil
+}
+
+// ErrInvalidTrackIDs is returned for a like status check with too many or malformed track IDs
+var ErrInvalidTrackIDs = errors.New("invalid track IDs")
+
+// CheckLikes reports for each track whether the user liked it. IDs are keyed
+// in canonical lowercase form; unknown tracks are reported as not liked.
+func (s *TrackService) CheckLikes(ctx context.Context, userID int, trackIDs []string) (map[string]bool, error) {
+	if len(trackIDs) > models.MaxLikeCheckTracks {
+		return nil, fmt.Errorf("%w: at most %d allowed, got %d", ErrInvalidTrackIDs, models.MaxLikeCheckTracks, len(trackIDs))
+	}
+
+	likes := make(map[string]bool, len(trackIDs))
+	ids := make([]string, 0, len(trackIDs))
+	for _, trackID := range trackIDs {
+		parsed, err := uuid.Parse(trackID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a UUID", ErrInvalidTrackIDs, trackID)
+		}
+		id := parsed.String()
+		if _, seen := likes[id]; !seen {
+			likes[id] = false
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return likes, nil
+	}
+
+	liked, err := s.trackRepo.GetLikedTrackIDsAmong(ctx, userID, ids)
+	if err != nil {
+		s.logger.Error("Failed to check liked tracks", "user_id", userID, "error", err)
+		return nil, fmt.Errorf("failed to check liked tracks: %w", err)
+	}
+	for _, id := range liked {
+		likes[id] = true
+	}
+
+	return likes, nil
+}
+
 // GetTrackStats returns play and like counts for a track
 func (s *TrackService) GetTrackStats(ctx context.Context, trackID string) (*models.TrackStats, error) {
 	// Validate and parse UUID
@@ -397,22 +1024,142 @@ func (s *TrackService) GetTrackStats(ctx context.Context, trackID string) (*mode
 	return stats, nil
 }
 
+// GetTrackLikers returns a page of users who liked a track, without private fields
+func (s *TrackService) GetTrackLikers(ctx context.Context, trackID string, page, limit int) ([]models.TrackLiker, int, error) {
+	if _, err := uuid.Parse(trackID); err != nil {
+		return nil, 0, fmt.Errorf("invalid track ID format: %w", err)
+	}
+
+	offset := (page - 1) * limit
+	likers, total, err := s.trackRepo.GetTrackLikers(ctx, trackID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get track likers: %w", err)
+	}
+
+	for i := range likers {
+		likers[i].AvatarURL = avatarURLFromKey(likers[i].AvatarKey)
+	}
+
+	return likers, total, nil
+}
+
 // GetCoverImage returns the cover image object from MinIO for a track
 func (s *TrackService) GetCoverImage(ctx context.Context, coverKey string) (io.ReadCloser, error) {
-	return s.minioSvc.GetObject(ctx, coverKey)
+	return s.minioSvc.GetObject(ctx, s.minioSvc.Buckets().Image, coverKey)
 }
 
 // GetCoverImageInfo returns the cover image info from MinIO for a track  
 func (s *TrackService) GetCoverImageInfo(ctx context.Context, coverKey string) (*minio.ObjectInfo, error) {
-	return s.minioSvc.GetObjectInfo(ctx, coverKey)
+	return s.minioSvc.GetObjectInfo(ctx, s.minioSvc.Buckets().Image, coverKey)
+}
+
+// QualityHigh is the stream quality of the audio stored on upload, served by default
+const QualityHigh = "high"
+
+// Qualities returns the stream qualities clients may request, in alphabetical order
+func (s *TrackService) Qualities() []string {
+	qualities := []string{QualityHigh}
+	for name := range s.renditions {
+		qualities = append(qualities, name)
+	}
+	sort.Strings(qualities)
+	return qualities
+}
+
+// HasQuality reports whether quality is "high" or a configured rendition
+func (s *TrackService) HasQuality(quality string) bool {
+	_, ok := s.renditions[quality]
+	return ok || quality == QualityHigh
+}
+
+// AudioKey returns the key of track's audio in quality. Tracks without that
+// rendition, because it would not be smaller than the upload or was configured
+// later, get the high quality audio.
+func (s *TrackService) AudioKey(track *models.Track, quality string) string {
+	if key, ok := track.Renditions[quality]; ok {
+		return key
+	}
+	return track.AudioFileKey
+}
+
+// renditionsBelow returns the configured renditions worth generating for audio
+// stored at sourceBitrate bits per second: a rendition at or above the source
+// bitrate would be no smaller. An unknown (zero) source bitrate keeps them all.
+func (s *TrackService) renditionsBelow(sourceBitrate int) map[string]int {
+	renditions := make(map[string]int, len(s.renditions))
+	for name, kbps := range s.renditions {
+		if sourceBitrate > 0 && kbps*1000 >= sourceBitrate {
+			continue
+		}
+		renditions[name] = kbps
+	}
+	return renditions
 }
 
 // GetAudioFile returns the audio file object from MinIO
 func (s *TrackService) GetAudioFile(ctx context.Context, audioKey string) (io.ReadCloser, error) {
-	return s.minioSvc.GetObject(ctx, audioKey)
+	return s.minioSvc.GetObject(ctx, s.minioSvc.Buckets().Audio, audioKey)
 }
 
 // GetAudioFileInfo returns the audio file info from MinIO  
 func (s *TrackService) GetAudioFileInfo(ctx context.Context, audioKey string) (*minio.ObjectInfo, error) {
-	return s.minioSvc.GetObjectInfo(ctx, audioKey)
+	return s.minioSvc.GetObjectInfo(ctx, s.minioSvc.Buckets().Audio, audioKey)
+}
+
+// ErrInvalidLyrics is returned for empty or oversized lyrics or a malformed language tag
+var ErrInvalidLyrics = errors.New("invalid lyrics")
+
+// maxLyricsLength bounds stored lyrics in bytes
+const maxLyricsLength = 100 << 10
+
+// lrcTimestamp matches an LRC line timestamp such as [01:23], [01:23.45] or [01:23:45]
+var lrcTimestamp = regexp.MustCompile(`(?m)^\s*\[\d{1,3}:\d{2}(?:[.:]\d{1,3})?\]`)
+
+// languageTag loosely matches a BCP 47 tag such as "en" or "pt-BR"
+var languageTag = regexp.MustCompile(`^[A-Za-z]{2,3}(?:-[A-Za-z0-9]{1,8})*$`)
+
+// GetLyrics returns the lyrics of a track. A missing track yields
+// ErrTrackNotFound, a track without lyrics ErrLyricsNotFound.
+func (s *TrackService) GetLyrics(ctx context.Context, trackID string) (*models.Lyrics, error) {
+	if _, err := s.GetVisibleTrack(ctx, trackID, false); err != nil {
+		return nil, err
+	}
+	return s.trackRepo.GetLyrics(ctx, trackID)
+}
+
+// SetLyrics stores the lyrics of a track. Content with LRC timestamps is marked as synced.
+func (s *TrackService) SetLyrics(ctx context.Context, trackID string, req *models.LyricsRequest) (*models.Lyrics, error) {
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		return nil, fmt.Errorf("%w: content is required", ErrInvalidLyrics)
+	}
+	if len(content) > maxLyricsLength {
+		return nil, fmt.Errorf("%w: content is longer than %d bytes", ErrInvalidLyrics, maxLyricsLength)
+	}
+
+	var language *string
+	if req.Language != nil && strings.TrimSpace(*req.Language) != "" {
+		tag := strings.TrimSpace(*req.Language)
+		if len(tag) > 35 || !languageTag.MatchString(tag) {
+			return nil, fmt.Errorf("%w: language must be a language tag such as en or pt-BR", ErrInvalidLyrics)
+		}
+		language = &tag
+	}
+
+	if _, err := s.GetTrack(ctx, trackID); err != nil {
+		return nil, err
+	}
+
+	lyrics := &models.Lyrics{
+		TrackID:  trackID,
+		Content:  content,
+		IsSynced: lrcTimestamp.MatchString(content),
+		Language: language,
+	}
+	if err := s.trackRepo.UpsertLyrics(ctx, lyrics); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Track lyrics saved", "track_id", trackID, "is_synced", lyrics.IsSynced)
+	return lyrics, nil
 }
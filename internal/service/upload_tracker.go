@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	minioPkg "koteyye_music_be/pkg/minio"
+)
+
+// ErrShuttingDown is returned for uploads started after shutdown began
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// uploadCleanupTimeout bounds the removal of objects left by uploads that
+// didn't finish before shutdown
+const uploadCleanupTimeout = 10 * time.Second
+
+// uploadTracker lets shutdown wait for operations that write new objects to
+// MinIO, and remove what they wrote when they don't finish in time
+type uploadTracker struct {
+	minioSvc *minioPkg.Service
+	logger   *slog.Logger
+	// abortCtx is cancelled to stop the operations still running at the deadline
+	abortCtx context.Context
+	abort    context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu      sync.Mutex
+	closing bool
+	uploads map[*upload]struct{}
+}
+
+// upload is one tracked operation. Until it commits, the objects under its
+// prefixes aren't referenced by any row and are removed if shutdown aborts it.
+type upload struct {
+	tracker  *uploadTracker
+	prefixes []objectPrefix
+	release  func()
+}
+
+// objectPrefix is a key prefix within a bucket
+type objectPrefix struct {
+	bucket, prefix string
+}
+
+func newUploadTracker(minioSvc *minioPkg.Service, log *slog.Logger) *uploadTracker {
+	abortCtx, abort := context.WithCancel(context.Background())
+	return &uploadTracker{
+		minioSvc: minioSvc,
+		logger:   log,
+		abortCtx: abortCtx,
+		abort:    abort,
+		uploads:  make(map[*upload]struct{}),
+	}
+}
+
+// begin starts tracking an operation. The returned context is cancelled as
+// well when shutdown gives up waiting; the caller must call end.
+func (t *uploadTracker) begin(ctx context.Context) (*upload, context.Context, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closing {
+		return nil, nil, ErrShuttingDown
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(t.abortCtx, cancel)
+	u := &upload{
+		tracker: t,
+		release: func() {
+			stop()
+			cancel()
+		},
+	}
+	t.uploads[u] = struct{}{}
+	t.wg.Add(1)
+	return u, ctx, nil
+}
+
+// writes records that the operation stores objects under prefix in bucket.
+// Call it before uploading.
+func (u *upload) writes(bucket, prefix string) {
+	u.tracker.mu.Lock()
+	defer u.tracker.mu.Unlock()
+	u.prefixes = append(u.prefixes, objectPrefix{bucket: bucket, prefix: prefix})
+}
+
+// commit hands the written objects over to the database row that now refers to them
+func (u *upload) commit() {
+	u.tracker.mu.Lock()
+	defer u.tracker.mu.Unlock()
+	u.prefixes = nil
+}
+
+// end stops tracking the operation
+func (u *upload) end() {
+	u.tracker.mu.Lock()
+	delete(u.tracker.uploads, u)
+	u.tracker.mu.Unlock()
+
+	u.release()
+	u.tracker.wg.Done()
+}
+
+// Close rejects new uploads with ErrShuttingDown and waits for running ones
+// until ctx is done. Uploads still running then are cancelled and the objects
+// they wrote but hadn't committed are deleted.
+func (t *uploadTracker) Close(ctx context.Context) error {
+	t.mu.Lock()
+	t.closing = true
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Snapshot before cancelling: a cancelled upload may end before its own
+	// cleanup, which runs with the cancelled context, could remove anything
+	t.mu.Lock()
+	var prefixes []objectPrefix
+	for u := range t.uploads {
+		prefixes = append(prefixes, u.prefixes...)
+	}
+	running := len(t.uploads)
+	t.abort()
+	t.mu.Unlock()
+
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), uploadCleanupTimeout)
+	defer cancel()
+	for _, p := range prefixes {
+		if err := t.minioSvc.DeleteFolder(cleanupCtx, p.bucket, p.prefix); err != nil {
+			t.logger.Error("Failed to delete objects of an unfinished upload", "bucket", p.bucket, "prefix", p.prefix, "error", err)
+		}
+	}
+
+	t.logger.Warn("Unfinished uploads cancelled at shutdown", "uploads", running, "prefixes", len(prefixes))
+	return fmt.Errorf("%d uploads still running: %w", running, ctx.Err())
+}
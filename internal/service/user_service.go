@@ -1,32 +1,81 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"mime/multipart"
 	"path/filepath"
+	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"koteyye_music_be/internal/models"
 	"koteyye_music_be/internal/repository"
+	"koteyye_music_be/pkg/filetype"
+	imagePkg "koteyye_music_be/pkg/image"
 	"koteyye_music_be/pkg/minio"
 
 	"github.com/google/uuid"
+	miniogo "github.com/minio/minio-go/v7"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrInvalidVolume is returned when a player volume is outside the configured bounds
+var ErrInvalidVolume = errors.New("invalid volume")
+
+// ErrInvalidRole is returned for a role that isn't one of models.UserRoles
+var ErrInvalidRole = repository.ErrInvalidRole
+
+// ErrLastAdmin is returned when a role change would leave no admins
+var ErrLastAdmin = repository.ErrLastAdmin
+
+// ErrPasswordConfirmation is returned when account deletion isn't confirmed with the right password
+var ErrPasswordConfirmation = errors.New("password confirmation failed")
+
+// ErrInvalidName is returned for a profile name that is empty or too long after cleanup
+var ErrInvalidName = errors.New("invalid name")
+
+// ErrInvalidPosition is returned when a player position is negative or past the end of the track
+var ErrInvalidPosition = errors.New("invalid position")
+
+// avatarSize is the side in pixels of cropped avatars
+const avatarSize = 256
+
+// positionTolerance is how far past duration_seconds a position may be.
+// Durations are stored truncated to whole seconds, so the real end of a
+// track can lie up to a second after the stored value.
+const positionTolerance = 1.0
+
 type UserService struct {
 	userRepo    *repository.UserRepository
 	minioClient *minio.Client
+	// thumbnails caches resized covers of tracks removed with their uploader
+	thumbnails *ThumbnailService
+	// maxAvatarSize is the largest accepted avatar in bytes
+	maxAvatarSize int64
+	// cropAvatars stores avatars center-cropped to avatarSize squares
+	cropAvatars bool
+	minVolume   int
+	maxVolume   int
 	logger      *slog.Logger
 }
 
-func NewUserService(userRepo *repository.UserRepository, minioClient *minio.Client, log *slog.Logger) *UserService {
+func NewUserService(userRepo *repository.UserRepository, minioClient *minio.Client, thumbnails *ThumbnailService, maxAvatarSize int64, cropAvatars bool, minVolume, maxVolume int, log *slog.Logger) *UserService {
 	return &UserService{
-		userRepo:    userRepo,
-		minioClient: minioClient,
-		logger:      log,
+		userRepo:      userRepo,
+		minioClient:   minioClient,
+		thumbnails:    thumbnails,
+		maxAvatarSize: maxAvatarSize,
+		cropAvatars:   cropAvatars,
+		minVolume:     minVolume,
+		maxVolume:     maxVolume,
+		logger:        log,
 	}
 }
 
@@ -38,31 +87,14 @@ func (s *UserService) GetUserProfile(ctx context.Context, userID int) (*models.U
 		return nil, fmt.Errorf("failed to get user profile: %w", err)
 	}
 
-	// Convert avatar key to URL
-	var avatarURL *string
-	if user.AvatarKey != nil && *user.AvatarKey != "" {
-		key := *user.AvatarKey
-		if strings.HasPrefix(key, "avatars/") {
-			// Our MinIO file - create API endpoint URL
-			url := fmt.Sprintf("/avatars/%s", key)
-			avatarURL = &url
-		} else if strings.HasPrefix(key, "data:image") || strings.HasPrefix(key, "http") {
-			// Base64 data or external URL - return as is
-			avatarURL = &key
-		} else {
-			// Unknown format - try as MinIO key anyway
-			url := fmt.Sprintf("/avatars/%s", key)
-			avatarURL = &url
-		}
-	}
-
 	profile := &models.UserProfileResponse{
 		ID:               user.ID,
 		Email:            user.Email,
 		Name:             user.Name,
-		AvatarURL:        avatarURL,
+		AvatarURL:        avatarURLFromKey(user.AvatarKey),
 		Provider:         user.Provider,
 		Role:             user.Role,
+		EmailVerified:    user.EmailVerified,
 		LastTrackID:      user.LastTrackID,
 		LastPosition:     user.LastPosition,
 		VolumePreference: user.VolumePreference,
@@ -74,8 +106,26 @@ func (s *UserService) GetUserProfile(ctx context.Context, userID int) (*models.U
 	return profile, nil
 }
 
+// GetUserStats returns the aggregate like, upload and play counts of a user
+func (s *UserService) GetUserStats(ctx context.Context, userID int) (*models.UserStats, error) {
+	stats, err := s.userRepo.GetUserStats(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats: %w", err)
+	}
+	return stats, nil
+}
+
 // UpdateUserProfile updates user profile information
 func (s *UserService) UpdateUserProfile(ctx context.Context, userID int, req *models.UpdateProfileRequest) (*models.UserProfileResponse, error) {
+	// A null name clears it; only a provided name is validated
+	if req.Name.Value != nil {
+		name, err := cleanUserName(*req.Name.Value)
+		if err != nil {
+			return nil, err
+		}
+		req.Name.Value = &name
+	}
+
 	// Update profile in database
 	if err := s.userRepo.UpdateUserProfile(ctx, userID, req.Name, req.AvatarKey); err != nil {
 		s.logger.Error("Failed to update user profile", "user_id", userID, "error", err)
@@ -92,6 +142,22 @@ func (s *UserService) UpdateUserProfile(ctx context.Context, userID int, req *mo
 	return profile, nil
 }
 
+// cleanUserName strips control characters and surrounding whitespace from a
+// profile name and checks its length
+func cleanUserName(name string) (string, error) {
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+
+	if n := utf8.RuneCountInString(name); n < 1 || n > models.MaxUserNameLength {
+		return "", fmt.Errorf("%w: must be between 1 and %d characters", ErrInvalidName, models.MaxUserNameLength)
+	}
+	return name, nil
+}
+
 // UploadAvatar uploads user avatar to MinIO and updates profile
 func (s *UserService) UploadAvatar(ctx context.Context, userID int, file multipart.File, header *multipart.FileHeader) (*models.UserProfileResponse, error) {
 	// Validate file type
@@ -108,17 +174,28 @@ func (s *UserService) UploadAvatar(ctx context.Context, userID int, file multipa
 		return nil, fmt.Errorf("unsupported file type: %s. Allowed: jpg, jpeg, png, gif, webp", ext)
 	}
 
-	// Validate file size (max 5MB)
-	if header.Size > 5*1024*1024 {
-		return nil, fmt.Errorf("file too large: %d bytes. Maximum allowed: 5MB", header.Size)
+	// Validate file size
+	if header.Size > s.maxAvatarSize {
+		return nil, fmt.Errorf("file too large: %d bytes. Maximum allowed: %d bytes", header.Size, s.maxAvatarSize)
+	}
+
+	// Validate file content, the extension alone can't be trusted
+	contentType, err := filetype.Verify(file, filetype.CategoryImage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid avatar image: %w", err)
+	}
+
+	avatar, size, ext, contentType, err := s.prepareAvatar(userID, file, header.Size, ext, contentType)
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate unique filename
 	avatarKey := fmt.Sprintf("avatars/%d/%s%s", userID, uuid.New().String(), ext)
 
 	// Upload to MinIO
-	_, err := s.minioClient.PutObject(ctx, avatarKey, file, header.Size, map[string]string{
-		"Content-Type": header.Header.Get("Content-Type"),
+	_, err = s.minioClient.PutObject(ctx, s.minioClient.Buckets().Avatar, avatarKey, avatar, size, map[string]string{
+		"Content-Type": contentType,
 	})
 	if err != nil {
 		s.logger.Error("Failed to upload avatar to MinIO", "user_id", userID, "error", err)
@@ -133,7 +210,7 @@ func (s *UserService) UploadAvatar(ctx context.Context, userID int, file multipa
 	} else if user.AvatarKey != nil && *user.AvatarKey != "" {
 		// Delete old avatar if exists
 		if strings.HasPrefix(*user.AvatarKey, "avatars/") {
-			if err := s.minioClient.DeleteObject(ctx, *user.AvatarKey); err != nil {
+			if err := s.minioClient.DeleteObject(ctx, s.minioClient.Buckets().Avatar, *user.AvatarKey); err != nil {
 				s.logger.Warn("Failed to remove old avatar", "user_id", userID, "old_key", *user.AvatarKey, "error", err)
 				// Continue anyway
 			}
@@ -141,9 +218,9 @@ func (s *UserService) UploadAvatar(ctx context.Context, userID int, file multipa
 	}
 
 	// Update user profile with new avatar key
-	if err := s.userRepo.UpdateUserProfile(ctx, userID, nil, &avatarKey); err != nil {
+	if err := s.userRepo.UpdateUserProfile(ctx, userID, models.OptionalString{}, models.OptionalString{Set: true, Value: &avatarKey}); err != nil {
 		// Try to cleanup uploaded file
-		s.minioClient.DeleteObject(ctx, avatarKey)
+		s.minioClient.DeleteObject(ctx, s.minioClient.Buckets().Avatar, avatarKey)
 		s.logger.Error("Failed to update user avatar key", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("failed to update profile with new avatar: %w", err)
 	}
@@ -154,6 +231,28 @@ func (s *UserService) UploadAvatar(ctx context.Context, userID int, file multipa
 	return s.GetUserProfile(ctx, userID)
 }
 
+// prepareAvatar returns the avatar content, size, key extension and content
+// type to store. When cropping is enabled the avatar is center-cropped to an
+// avatarSize square. GIFs, which may be animated, and images that can't be
+// decoded, such as WebP or ones over imagePkg.MaxPixels, are stored as uploaded.
+func (s *UserService) prepareAvatar(userID int, file multipart.File, size int64, ext, contentType string) (io.Reader, int64, string, string, error) {
+	if !s.cropAvatars || contentType == "image/gif" {
+		return file, size, ext, contentType, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, s.maxAvatarSize+1))
+	if err != nil {
+		return nil, 0, "", "", fmt.Errorf("failed to read avatar: %w", err)
+	}
+
+	cropped, err := imagePkg.SquareThumbnail(bytes.NewReader(data), avatarSize)
+	if err != nil {
+		s.logger.Warn("Failed to crop avatar, storing it as uploaded", "user_id", userID, "content_type", contentType, "error", err)
+		return bytes.NewReader(data), int64(len(data)), ext, contentType, nil
+	}
+	return bytes.NewReader(cropped), int64(len(cropped)), ".jpg", imagePkg.ThumbnailContentType, nil
+}
+
 // RemoveAvatar removes user avatar from MinIO and updates profile
 func (s *UserService) RemoveAvatar(ctx context.Context, userID int) (*models.UserProfileResponse, error) {
 	// Get current user
@@ -168,7 +267,7 @@ func (s *UserService) RemoveAvatar(ctx context.Context, userID int) (*models.Use
 		if strings.Contains(*user.AvatarKey, "avatars/") {
 			avatarKey := extractMinIOKeyFromURL(*user.AvatarKey)
 			if avatarKey != "" {
-				if err := s.minioClient.DeleteObject(ctx, avatarKey); err != nil {
+				if err := s.minioClient.DeleteObject(ctx, s.minioClient.Buckets().Avatar, avatarKey); err != nil {
 					s.logger.Warn("Failed to remove avatar from MinIO", "user_id", userID, "avatar_key", avatarKey, "error", err)
 					// Continue anyway, still update database
 				}
@@ -177,7 +276,7 @@ func (s *UserService) RemoveAvatar(ctx context.Context, userID int) (*models.Use
 	}
 
 	// Update user profile to remove avatar URL
-	if err := s.userRepo.UpdateUserProfile(ctx, userID, nil, nil); err != nil {
+	if err := s.userRepo.UpdateUserProfile(ctx, userID, models.OptionalString{}, models.OptionalString{Set: true}); err != nil {
 		s.logger.Error("Failed to update user profile", "user_id", userID, "error", err)
 		return nil, fmt.Errorf("failed to remove avatar from profile: %w", err)
 	}
@@ -188,24 +287,24 @@ func (s *UserService) RemoveAvatar(ctx context.Context, userID int) (*models.Use
 	return s.GetUserProfile(ctx, userID)
 }
 
-// GetAvatarStream returns avatar file stream from MinIO
-func (s *UserService) GetAvatarStream(ctx context.Context, avatarKey string) (io.ReadCloser, string, int64, error) {
+// GetAvatarStream returns avatar file stream from MinIO with its content type and object info
+func (s *UserService) GetAvatarStream(ctx context.Context, avatarKey string) (io.ReadCloser, string, *miniogo.ObjectInfo, error) {
 	// Validate that the key is for avatars
 	if !strings.HasPrefix(avatarKey, "avatars/") {
-		return nil, "", 0, fmt.Errorf("invalid avatar key")
+		return nil, "", nil, fmt.Errorf("invalid avatar key")
 	}
 
 	// Get object from MinIO
-	object, err := s.minioClient.GetObject(ctx, avatarKey)
+	object, err := s.minioClient.GetObject(ctx, s.minioClient.Buckets().Avatar, avatarKey)
 	if err != nil {
-		return nil, "", 0, fmt.Errorf("failed to get avatar: %w", err)
+		return nil, "", nil, fmt.Errorf("failed to get avatar: %w", err)
 	}
 
 	// Get object info for content type and size
-	info, err := s.minioClient.GetObjectInfo(ctx, avatarKey)
+	info, err := s.minioClient.GetObjectInfo(ctx, s.minioClient.Buckets().Avatar, avatarKey)
 	if err != nil {
 		object.Close()
-		return nil, "", 0, fmt.Errorf("failed to get avatar info: %w", err)
+		return nil, "", nil, fmt.Errorf("failed to get avatar info: %w", err)
 	}
 
 	contentType := info.Metadata.Get("Content-Type")
@@ -226,20 +325,32 @@ func (s *UserService) GetAvatarStream(ctx context.Context, avatarKey string) (io
 		}
 	}
 
-	return object, contentType, info.Size, nil
+	return object, contentType, &info, nil
 }
 
 // UpdatePlayerState updates user's player state (track, position, volume)
 func (s *UserService) UpdatePlayerState(ctx context.Context, userID int, trackID string, position float64, volume int) error {
+	if err := s.ValidateVolume(volume); err != nil {
+		return err
+	}
+	if math.IsNaN(position) || math.IsInf(position, 0) || position < 0 {
+		return fmt.Errorf("%w: must be a non-negative number of seconds", ErrInvalidPosition)
+	}
+
 	// Validate that track exists (this prevents foreign key constraint violations)
-	exists, err := s.userRepo.TrackExists(ctx, trackID)
+	duration, err := s.userRepo.TrackDuration(ctx, trackID)
 	if err != nil {
+		if errors.Is(err, repository.ErrTrackNotFound) {
+			s.logger.Warn("Track not found for player state update", "track_id", trackID)
+			return err
+		}
 		s.logger.Error("Failed to check if track exists", "track_id", trackID, "error", err)
 		return fmt.Errorf("failed to validate track: %w", err)
 	}
-	if !exists {
-		s.logger.Warn("Track not found for player state update", "track_id", trackID)
-		return fmt.Errorf("track not found")
+
+	// A zero duration is unknown rather than empty, so any position is accepted
+	if duration > 0 && position > float64(duration)+positionTolerance {
+		return fmt.Errorf("%w: %.1fs is beyond the track length of %ds", ErrInvalidPosition, position, duration)
 	}
 
 	err = s.userRepo.UpdatePlayerState(ctx, userID, trackID, position, volume)
@@ -252,6 +363,93 @@ func (s *UserService) UpdatePlayerState(ctx context.Context, userID int, trackID
 	return nil
 }
 
+// ValidateVolume checks that volume lies within the configured bounds (inclusive)
+func (s *UserService) ValidateVolume(volume int) error {
+	if volume < s.minVolume || volume > s.maxVolume {
+		return fmt.Errorf("%w: must be between %d and %d", ErrInvalidVolume, s.minVolume, s.maxVolume)
+	}
+	return nil
+}
+
+// ListUsers returns a page of users for the admin panel. roleFilter is
+// empty for all users or one of models.UserRoles.
+func (s *UserService) ListUsers(ctx context.Context, page, limit int, roleFilter string) ([]models.AdminUser, int, error) {
+	if roleFilter != "" && !slices.Contains(models.UserRoles, roleFilter) {
+		return nil, 0, fmt.Errorf("%w %q", ErrInvalidRole, roleFilter)
+	}
+
+	offset := (page - 1) * limit
+	users, total, err := s.userRepo.ListUsers(ctx, limit, offset, roleFilter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	for i := range users {
+		users[i].AvatarURL = avatarURLFromKey(users[i].AvatarKey)
+	}
+
+	return users, total, nil
+}
+
+// UpdateUserRole changes a user's role and returns the updated user. Demoting
+// the only admin fails with ErrLastAdmin.
+func (s *UserService) UpdateUserRole(ctx context.Context, userID int, role string) (*models.AdminUser, error) {
+	user, err := s.userRepo.UpdateUserRole(ctx, userID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
+	user.AvatarURL = avatarURLFromKey(user.AvatarKey)
+
+	s.logger.Info("User role updated", "user_id", userID, "role", role)
+	return user, nil
+}
+
+// DeleteAccount deletes a user with their likes, uploaded tracks and avatar.
+// Users registered with email and password must confirm with their password,
+// otherwise ErrPasswordConfirmation is returned. The only admin can't be deleted.
+func (s *UserService) DeleteAccount(ctx context.Context, userID int, password string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.Provider != nil && *user.Provider == "local" {
+		if password == "" || user.PasswordHash == nil {
+			return ErrPasswordConfirmation
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(*user.PasswordHash), []byte(password)); err != nil {
+			s.logger.Warn("Account deletion with wrong password", "user_id", userID)
+			return ErrPasswordConfirmation
+		}
+	}
+
+	files, err := s.userRepo.DeleteUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	// The database is the source of truth; objects that fail to delete are only orphaned
+	for _, key := range files.AudioKeys {
+		if err := s.minioClient.DeleteObject(ctx, s.minioClient.Buckets().Audio, key); err != nil {
+			s.logger.Warn("Failed to delete track audio of deleted user", "user_id", userID, "key", key, "error", err)
+		}
+	}
+	for _, key := range files.CoverKeys {
+		if err := s.minioClient.DeleteObject(ctx, s.minioClient.Buckets().Image, key); err != nil {
+			s.logger.Warn("Failed to delete track cover of deleted user", "user_id", userID, "key", key, "error", err)
+		}
+		s.thumbnails.DeleteCoverThumbnails(ctx, key)
+	}
+	if files.AvatarKey != nil && strings.HasPrefix(*files.AvatarKey, "avatars/") {
+		if err := s.minioClient.DeleteObject(ctx, s.minioClient.Buckets().Avatar, *files.AvatarKey); err != nil {
+			s.logger.Warn("Failed to delete avatar of deleted user", "user_id", userID, "key", *files.AvatarKey, "error", err)
+		}
+	}
+
+	s.logger.Info("User account deleted", "user_id", userID, "tracks", len(files.AudioKeys))
+	return nil
+}
+
 // GetUserWithLastTrack retrieves user with full last track details
 func (s *UserService) GetUserWithLastTrack(ctx context.Context, userID int) (*models.User, error) {
 	user, err := s.userRepo.GetUserWithLastTrack(ctx, userID)
@@ -271,3 +469,20 @@ func extractMinIOKeyFromURL(url string) string {
 	}
 	return ""
 }
+
+// avatarURLFromKey converts a stored avatar key to a URL served by the API
+func avatarURLFromKey(avatarKey *string) *string {
+	if avatarKey == nil || *avatarKey == "" {
+		return nil
+	}
+
+	key := *avatarKey
+	if strings.HasPrefix(key, "data:image") || strings.HasPrefix(key, "http") {
+		// Base64 data or external URL - return as is
+		return &key
+	}
+
+	// Our MinIO file (or unknown format treated as one) - create API endpoint URL
+	url := fmt.Sprintf("/avatars/%s", key)
+	return &url
+}
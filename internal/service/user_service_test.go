@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"koteyye_music_be/internal/models"
+)
+
+func newTestUserService(minVolume, maxVolume int) *UserService {
+	return NewUserService(nil, nil, nil, 0, false, minVolume, maxVolume, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestValidateVolumeBoundaries(t *testing.T) {
+	s := newTestUserService(10, 90)
+	for volume, valid := range map[int]bool{
+		9:  false,
+		10: true,
+		50: true,
+		90: true,
+		91: false,
+	} {
+		err := s.ValidateVolume(volume)
+		if valid && err != nil {
+			t.Errorf("ValidateVolume(%d) = %v, want nil", volume, err)
+		}
+		if !valid && !errors.Is(err, ErrInvalidVolume) {
+			t.Errorf("ValidateVolume(%d) = %v, want ErrInvalidVolume", volume, err)
+		}
+	}
+}
+
+func TestCleanUserName(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+		valid             bool
+	}{
+		{"valid", "Alice", "Alice", true},
+		{"trimmed", "  Alice  ", "Alice", true},
+		{"control characters stripped", "Al\x00i\tce\n", "Alice", true},
+		{"longest", strings.Repeat("я", models.MaxUserNameLength), strings.Repeat("я", models.MaxUserNameLength), true},
+		{"empty", "", "", false},
+		{"whitespace only", " \t\n ", "", false},
+		{"control characters only", "\x01\x02", "", false},
+		{"overlong", strings.Repeat("a", models.MaxUserNameLength+1), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cleanUserName(tt.input)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidName) {
+					t.Errorf("cleanUserName(%q) error = %v, want ErrInvalidName", tt.input, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("cleanUserName(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateUserProfileRejectsInvalidNameBeforeSaving(t *testing.T) {
+	// The service has no repository, so reaching it would panic
+	s := newTestUserService(0, 100)
+	blank := "   "
+	req := &models.UpdateProfileRequest{Name: models.OptionalString{Set: true, Value: &blank}}
+
+	if _, err := s.UpdateUserProfile(context.Background(), 1, req); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("UpdateUserProfile error = %v, want ErrInvalidName", err)
+	}
+}
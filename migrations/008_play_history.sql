@@ -0,0 +1,13 @@
+-- Play history: one row per play reported by a client.
+-- Powers "recently played" features; plays_count on tracks stays the aggregate.
+CREATE TABLE IF NOT EXISTS play_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    track_id UUID NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+    played_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_play_history_user_played_at ON play_history(user_id, played_at DESC);
+CREATE INDEX IF NOT EXISTS idx_play_history_track_id ON play_history(track_id);
+
+COMMENT ON TABLE play_history IS 'Per-user track plays, newest first by played_at';
@@ -0,0 +1,5 @@
+-- Optional per-track cover art for singles and compilations.
+-- NULL means the track uses its album's cover.
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS cover_image_key VARCHAR(500);
+
+COMMENT ON COLUMN tracks.cover_image_key IS 'Path to the track''s own cover in MinIO; NULL falls back to the album cover';
@@ -0,0 +1,15 @@
+-- Explicit track order within an album; listings sort by track_number, then created_at.
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS track_number INTEGER;
+
+-- Number existing tracks in upload order so current listings keep their order
+UPDATE tracks t
+SET track_number = numbered.rn
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY album_id ORDER BY created_at ASC) AS rn
+    FROM tracks
+) numbered
+WHERE t.id = numbered.id AND t.track_number IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tracks_album_track_number ON tracks(album_id, track_number);
+
+COMMENT ON COLUMN tracks.track_number IS 'Position of the track within its album, starting at 1';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tracks_album_audio_sha256;
+
+ALTER TABLE tracks DROP COLUMN IF EXISTS audio_sha256;
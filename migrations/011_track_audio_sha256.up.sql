@@ -0,0 +1,9 @@
+-- SHA-256 of the uploaded audio, used to reject re-uploads of the same file into an album.
+-- Tracks uploaded before this migration keep NULL and are never treated as duplicates.
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS audio_sha256 CHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tracks_album_audio_sha256
+    ON tracks(album_id, audio_sha256)
+    WHERE audio_sha256 IS NOT NULL;
+
+COMMENT ON COLUMN tracks.audio_sha256 IS 'Hex SHA-256 of the uploaded audio file';
@@ -0,0 +1,7 @@
+-- Audio quality info reported by ffprobe at upload time. NULL means unknown;
+-- existing tracks are filled in by the backfill maintenance job.
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS bitrate INTEGER CHECK (bitrate >= 0);
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS format VARCHAR(64);
+
+COMMENT ON COLUMN tracks.bitrate IS 'Audio bitrate in bits per second';
+COMMENT ON COLUMN tracks.format IS 'Container format name as reported by ffprobe, e.g. mp3 or flac';
@@ -0,0 +1,12 @@
+-- Lyrics of a track, either plain text or LRC with [mm:ss.xx] timestamps
+CREATE TABLE IF NOT EXISTS track_lyrics (
+    track_id UUID PRIMARY KEY REFERENCES tracks(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    is_synced BOOLEAN NOT NULL DEFAULT false,
+    language VARCHAR(35),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON COLUMN track_lyrics.is_synced IS 'True when content is LRC with line timestamps';
+COMMENT ON COLUMN track_lyrics.language IS 'BCP 47 language tag, e.g. en or pt-BR';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_albums_featured_order;
+ALTER TABLE albums DROP COLUMN IF EXISTS featured_order;
+ALTER TABLE albums DROP COLUMN IF EXISTS is_featured;
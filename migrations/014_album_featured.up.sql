@@ -0,0 +1,9 @@
+-- Editorial "featured" flag for the home page. featured_order sets the
+-- position within the featured section and is NULL for regular albums.
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS is_featured BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS featured_order INTEGER CHECK (featured_order >= 1);
+
+CREATE INDEX IF NOT EXISTS idx_albums_featured_order ON albums(featured_order) WHERE is_featured;
+
+COMMENT ON COLUMN albums.is_featured IS 'Album is shown in the curated section of the home page';
+COMMENT ON COLUMN albums.featured_order IS 'Position within the featured section, lower comes first';
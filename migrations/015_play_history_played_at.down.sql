@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_play_history_played_at;
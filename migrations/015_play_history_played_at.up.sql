@@ -0,0 +1,2 @@
+-- Top-tracks analytics scan plays in a time window across all users
+CREATE INDEX IF NOT EXISTS idx_play_history_played_at ON play_history(played_at, track_id);
@@ -0,0 +1,13 @@
+-- Idempotency-Key headers of track uploads, so a retried upload returns the first result
+CREATE TABLE IF NOT EXISTS upload_idempotency_keys (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    album_id UUID NOT NULL REFERENCES albums(id) ON DELETE CASCADE,
+    -- NULL while the first request is still uploading
+    track_id UUID REFERENCES tracks(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_idempotency_keys_expires_at ON upload_idempotency_keys(expires_at);
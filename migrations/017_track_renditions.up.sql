@@ -0,0 +1,3 @@
+-- Extra MP3 renditions of a track by quality name, e.g. {"low": "albums/{album}/{track}.low.mp3"}.
+-- audio_file_key stays the "high" rendition.
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS renditions JSONB NOT NULL DEFAULT '{}'::jsonb;
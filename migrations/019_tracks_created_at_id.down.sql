@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_tracks_created_at_id;
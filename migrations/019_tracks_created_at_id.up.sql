@@ -0,0 +1,2 @@
+-- Keyset pagination of the track listing orders by (created_at, id)
+CREATE INDEX IF NOT EXISTS idx_tracks_created_at_id ON tracks(created_at DESC, id DESC);
@@ -0,0 +1,13 @@
+-- Publication state of albums and tracks. Drafts are only visible to admins;
+-- a track is public when both it and its album are published. Rows that
+-- already exist stay public, new ones start as drafts.
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'published'));
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'published'));
+
+ALTER TABLE albums ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE tracks ALTER COLUMN status SET DEFAULT 'draft';
+
+COMMENT ON COLUMN albums.status IS 'draft or published; drafts are hidden from public listings';
+COMMENT ON COLUMN tracks.status IS 'draft or published; a track is public only if its album is published too';
@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+
+-- Only the account of the provider the user was created with fits back into users
+UPDATE users u
+SET external_id = o.external_id
+FROM user_oauth_accounts o
+WHERE o.user_id = u.id AND o.provider = u.provider;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_provider_external_id
+    ON users(provider, external_id)
+    WHERE provider IS NOT NULL AND external_id IS NOT NULL;
+
+DROP TABLE IF EXISTS user_oauth_accounts;
@@ -0,0 +1,21 @@
+-- A user can sign in with several OAuth providers. users.provider keeps the
+-- method the account was created with; linked provider accounts live here.
+CREATE TABLE IF NOT EXISTS user_oauth_accounts (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, provider),
+    UNIQUE (provider, external_id)
+);
+
+INSERT INTO user_oauth_accounts (user_id, provider, external_id)
+SELECT id, provider, external_id
+FROM users
+WHERE provider IS NOT NULL AND provider <> 'local' AND external_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS idx_users_provider_external_id;
+ALTER TABLE users DROP COLUMN IF EXISTS external_id;
+
+COMMENT ON TABLE user_oauth_accounts IS 'OAuth provider accounts a user can sign in with';
@@ -8,7 +8,9 @@ import (
 	"mime/multipart"
 	"os"
 	"os/exec"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 // Metadata represents audio file metadata
@@ -27,10 +29,11 @@ type FFProbeOutput struct {
 	} `json:"format"`
 }
 
-// ExtractMetadata extracts duration and other metadata from audio file using ffprobe
-func ExtractMetadata(audioFile multipart.File) (*Metadata, error) {
+// ExtractMetadata extracts duration and other metadata from audio file using ffprobe.
+// The file is copied to a temp file in tempDir; an empty tempDir means the system default.
+func ExtractMetadata(audioFile multipart.File, tempDir string) (*Metadata, error) {
 	// Create temporary file
-	tempFile, err := os.CreateTemp("", "audio_metadata_*.tmp")
+	tempFile, err := os.CreateTemp(tempDir, "audio_metadata_*.tmp")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp file: %w", err)
 	}
@@ -47,18 +50,23 @@ func ExtractMetadata(audioFile multipart.File) (*Metadata, error) {
 	tempFile.Seek(0, 0)
 	audioFile.Seek(0, 0)
 
+	return ExtractMetadataFromPath(tempFile.Name())
+}
+
+// ExtractMetadataFromPath extracts duration and other metadata from an audio file on disk using ffprobe
+func ExtractMetadataFromPath(path string) (*Metadata, error) {
 	// Run ffprobe to get metadata
 	cmd := exec.Command("ffprobe", 
 		"-v", "quiet",           // Suppress verbose output
 		"-print_format", "json", // Output in JSON format
 		"-show_format",          // Show format information
-		tempFile.Name())
+		path)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err = cmd.Run()
+	err := cmd.Run()
 	if err != nil {
 		return nil, fmt.Errorf("ffprobe failed: %w (stderr: %s)", err, stderr.String())
 	}
@@ -97,18 +105,20 @@ func (m *Metadata) GetDurationSeconds() int {
 	return int(m.Duration)
 }
 
-// IsValidAudioFormat checks if the detected format is a supported audio format
+// IsValidAudioFormat checks if the detected format is a supported audio format.
+// ffprobe names demuxers that handle several formats as a comma-separated
+// list, e.g. "mov,mp4,m4a,3gp,3g2,mj2" for M4A, so each name is checked.
 func (m *Metadata) IsValidAudioFormat() bool {
 	validFormats := []string{
 		"mp3", "wav", "m4a", "aac", "flac", "ogg", "wma",
 	}
-	
-	for _, format := range validFormats {
-		if m.Format == format {
+
+	for _, name := range strings.Split(m.Format, ",") {
+		if slices.Contains(validFormats, name) {
 			return true
 		}
 	}
-	
+
 	return false
 }
 
@@ -0,0 +1,27 @@
+package audio
+
+import "testing"
+
+func TestIsValidAudioFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   bool
+	}{
+		{format: "mp3", want: true},
+		{format: "flac", want: true},
+		{format: "wav", want: true},
+		{format: "ogg", want: true},
+		// What ffprobe reports for an .m4a file
+		{format: "mov,mp4,m4a,3gp,3g2,mj2", want: true},
+		{format: "matroska,webm", want: false},
+		{format: "png_pipe", want: false},
+		{format: "", want: false},
+	}
+
+	for _, tt := range tests {
+		m := &Metadata{Format: tt.format}
+		if got := m.IsValidAudioFormat(); got != tt.want {
+			t.Errorf("IsValidAudioFormat() for %q = %v, want %v", tt.format, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -11,26 +12,37 @@ type DB struct {
 	Pool *pgxpool.Pool
 }
 
-// NewDB creates a new database connection pool
-func NewDB(ctx context.Context, dsn string) (*DB, error) {
+// NewDB creates a new database connection pool with the given size and
+// connection lifetime. Connections are opened lazily, call Ping to verify
+// the database is reachable.
+func NewDB(ctx context.Context, dsn string, maxConns, minConns int32, maxConnLifetime time.Duration) (*DB, error) {
 	poolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database DSN: %w", err)
 	}
+	poolConfig.MaxConns = maxConns
+	poolConfig.MinConns = minConns
+	poolConfig.MaxConnLifetime = maxConnLifetime
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
 
-	// Test connection
-	if err := pool.Ping(ctx); err != nil {
-		return nil, fmt.Errorf("unable to ping database: %w", err)
-	}
-
 	return &DB{Pool: pool}, nil
 }
 
+// Ping checks that the database is reachable within timeout
+func (db *DB) Ping(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := db.Pool.Ping(ctx); err != nil {
+		return fmt.Errorf("unable to ping database within %s: %w", timeout, err)
+	}
+	return nil
+}
+
 // Close closes the database connection pool
 func (db *DB) Close() {
 	if db.Pool != nil {
@@ -0,0 +1,95 @@
+package filetype
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// sniffLen is the number of bytes inspected to detect content type
+const sniffLen = 512
+
+// Category is a coarse class of uploaded content
+type Category string
+
+const (
+	CategoryImage Category = "image"
+	CategoryAudio Category = "audio"
+)
+
+// ErrMismatch is returned when file content doesn't match the expected category
+var ErrMismatch = errors.New("file content does not match expected type")
+
+// Detect returns the MIME type of the content based on its leading bytes.
+// It extends http.DetectContentType with audio signatures it doesn't recognize.
+func Detect(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return "audio/flac"
+	case isM4A(header):
+		return "audio/mp4"
+	case isMP3Frame(header):
+		return "audio/mpeg"
+	}
+
+	contentType := http.DetectContentType(header)
+	// Strip parameters such as "; charset=utf-8"
+	if idx := strings.Index(contentType, ";"); idx != -1 {
+		contentType = strings.TrimSpace(contentType[:idx])
+	}
+	return contentType
+}
+
+// DetectReader sniffs the content type of r and rewinds it to the beginning
+func DetectReader(r io.ReadSeeker) (string, error) {
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read file header: %w", err)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind file: %w", err)
+	}
+
+	return Detect(header[:n]), nil
+}
+
+// Verify checks that the content of r belongs to the expected category.
+// It returns the detected MIME type, or an error wrapping ErrMismatch.
+func Verify(r io.ReadSeeker, category Category) (string, error) {
+	contentType, err := DetectReader(r)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(contentType, string(category)+"/") {
+		return contentType, fmt.Errorf("%w: expected %s, detected %s", ErrMismatch, category, contentType)
+	}
+
+	return contentType, nil
+}
+
+// isM4A checks for an ISO base media "ftyp" box with an audio-capable brand
+func isM4A(header []byte) bool {
+	if len(header) < 12 || !bytes.Equal(header[4:8], []byte("ftyp")) {
+		return false
+	}
+	switch string(header[8:12]) {
+	case "M4A ", "M4B ", "mp42", "isom", "dash":
+		return true
+	}
+	return false
+}
+
+// isMP3Frame checks for an MPEG audio frame sync without an ID3 tag
+func isMP3Frame(header []byte) bool {
+	if len(header) < 2 {
+		return false
+	}
+	// 11 set sync bits followed by a non-reserved MPEG version and layer
+	return header[0] == 0xFF && header[1]&0xE0 == 0xE0 && header[1]&0x18 != 0x08 && header[1]&0x06 != 0x00
+}
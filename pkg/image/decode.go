@@ -0,0 +1,36 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+)
+
+// MaxPixels bounds the width times height of images decoded by this package.
+// A small compressed file can declare a huge canvas, and decoding allocates it.
+const MaxPixels = 25_000_000
+
+// ErrTooManyPixels is returned for images larger than MaxPixels
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// decode decodes a JPEG, PNG or GIF image after checking its declared size
+// against MaxPixels
+func decode(src io.Reader) (image.Image, error) {
+	// Keep what DecodeConfig reads so the full decode can start over
+	var header bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(src, &header))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d, at most %d allowed", ErrTooManyPixels, cfg.Width, cfg.Height, MaxPixels)
+	}
+
+	img, _, err := image.Decode(io.MultiReader(&header, src))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
+}
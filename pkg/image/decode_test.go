@@ -0,0 +1,61 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// pngDeclaring returns a small PNG whose header claims width x height pixels
+func pngDeclaring(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	data := buf.Bytes()
+
+	// IHDR follows the 8-byte signature: length, type, then width and height
+	ihdr := data[8+4 : 8+4+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	binary.BigEndian.PutUint32(data[8+4+4+13:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+func TestDecodeRejectsHugeCanvas(t *testing.T) {
+	src := pngDeclaring(t, 30000, 30000)
+
+	for name, fn := range map[string]func() error{
+		"Thumbnail":       func() error { _, err := Thumbnail(bytes.NewReader(src), 64); return err },
+		"SquareThumbnail": func() error { _, err := SquareThumbnail(bytes.NewReader(src), 64); return err },
+		"ConvertToWebP":   func() error { _, err := ConvertToWebP(bytes.NewReader(src), 90); return err },
+	} {
+		if err := fn(); !errors.Is(err, ErrTooManyPixels) {
+			t.Errorf("%s: err = %v, want ErrTooManyPixels", name, err)
+		}
+	}
+}
+
+func TestSquareThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 800, 400))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+
+	out, err := SquareThumbnail(&buf, 256)
+	if err != nil {
+		t.Fatalf("SquareThumbnail: %v", err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("decode thumbnail: %v", err)
+	}
+	if cfg.Width != 256 || cfg.Height != 256 {
+		t.Errorf("size = %dx%d, want 256x256", cfg.Width, cfg.Height)
+	}
+}
@@ -0,0 +1,180 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+)
+
+// ThumbnailContentType is the MIME type of images produced by Thumbnail
+const ThumbnailContentType = "image/jpeg"
+
+// thumbnailQuality is the JPEG quality used for thumbnails
+const thumbnailQuality = 85
+
+// Thumbnail decodes a JPEG, PNG or GIF image of at most MaxPixels, scales it down to the given width
+// keeping the aspect ratio and encodes the result as JPEG. Images already
+// narrower than width are re-encoded at their original size, never upscaled.
+// Transparent areas are flattened onto white since JPEG has no alpha.
+func Thumbnail(src io.Reader, width int) ([]byte, error) {
+	if width < 1 {
+		return nil, fmt.Errorf("invalid thumbnail width %d", width)
+	}
+
+	img, err := decode(src)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() < 1 || bounds.Dy() < 1 {
+		return nil, fmt.Errorf("empty image")
+	}
+
+	flat := flatten(img, bounds)
+
+	out := flat
+	if bounds.Dx() > width {
+		height := bounds.Dy() * width / bounds.Dx()
+		if height < 1 {
+			height = 1
+		}
+		out = resizeArea(flat, width, height)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, out, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SquareThumbnail decodes a JPEG, PNG or GIF image of at most MaxPixels, crops the largest centered
+// square out of it, scales that down to size x size and encodes the result as
+// JPEG. Like Thumbnail it never upscales and flattens transparency onto white.
+// A GIF is reduced to its first frame.
+func SquareThumbnail(src io.Reader, size int) ([]byte, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("invalid thumbnail size %d", size)
+	}
+
+	img, err := decode(src)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	if side < 1 {
+		return nil, fmt.Errorf("empty image")
+	}
+
+	corner := bounds.Min.Add(image.Pt((bounds.Dx()-side)/2, (bounds.Dy()-side)/2))
+	out := flatten(img, image.Rectangle{Min: corner, Max: corner.Add(image.Pt(side, side))})
+	if side > size {
+		out = resizeArea(out, size, size)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, out, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// flatten copies the r part of img onto white, so every source color model
+// ends up as opaque RGBA with its origin at 0,0
+func flatten(img image.Image, r image.Rectangle) *image.RGBA {
+	flat := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, r.Min, draw.Over)
+	return flat
+}
+
+// contribution is the weight of one source pixel in a destination pixel
+type contribution struct {
+	index  int
+	weight float64
+}
+
+// areaWeights maps every destination pixel on one axis to the source pixels
+// it covers, weighted by overlap. Weights of each destination pixel sum to 1.
+func areaWeights(srcSize, dstSize int) [][]contribution {
+	scale := float64(srcSize) / float64(dstSize)
+	weights := make([][]contribution, dstSize)
+	for d := range weights {
+		start := float64(d) * scale
+		end := start + scale
+		for s := int(start); s < srcSize && float64(s) < end; s++ {
+			lo := max(start, float64(s))
+			hi := min(end, float64(s+1))
+			if hi > lo {
+				weights[d] = append(weights[d], contribution{index: s, weight: (hi - lo) / scale})
+			}
+		}
+	}
+	return weights
+}
+
+// resizeArea downscales an opaque image by averaging the source area under
+// each destination pixel (a box filter), done as two separable passes.
+func resizeArea(src *image.RGBA, width, height int) *image.RGBA {
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	xWeights := areaWeights(srcW, width)
+	yWeights := areaWeights(srcH, height)
+
+	// Horizontal pass: srcH rows of width RGB pixels
+	rows := make([]float64, srcH*width*3)
+	for y := 0; y < srcH; y++ {
+		line := src.Pix[y*src.Stride:]
+		for x, contribs := range xWeights {
+			var r, g, b float64
+			for _, c := range contribs {
+				p := line[c.index*4:]
+				r += float64(p[0]) * c.weight
+				g += float64(p[1]) * c.weight
+				b += float64(p[2]) * c.weight
+			}
+			i := (y*width + x) * 3
+			rows[i], rows[i+1], rows[i+2] = r, g, b
+		}
+	}
+
+	// Vertical pass into the destination
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, contribs := range yWeights {
+		for x := 0; x < width; x++ {
+			var r, g, b float64
+			for _, c := range contribs {
+				i := (c.index*width + x) * 3
+				r += rows[i] * c.weight
+				g += rows[i+1] * c.weight
+				b += rows[i+2] * c.weight
+			}
+			o := y*dst.Stride + x*4
+			dst.Pix[o] = clampUint8(r)
+			dst.Pix[o+1] = clampUint8(g)
+			dst.Pix[o+2] = clampUint8(b)
+			dst.Pix[o+3] = 0xff
+		}
+	}
+
+	return dst
+}
+
+// clampUint8 rounds v to the nearest byte value
+func clampUint8(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
@@ -0,0 +1,405 @@
+package image
+
+import "container/heap"
+
+// VP8L bitstream constants, see the WebP lossless bitstream specification
+const (
+	vp8lSignature         = 0x2f
+	vp8lMaxDimension      = 1 << 14
+	vp8lSubtractGreen     = 2
+	vp8lPredictor         = 0
+	vp8lPredictorBits     = 9  // Predictor block size is 512x512
+	vp8lPredictorGradient = 12 // ClampAddSubtractFull(L, T, TL)
+	vp8lMaxCodeLength     = 15
+	vp8lMaxCLCodeLength   = 7
+	vp8lNumCodeLengths    = 19
+	vp8lGreenAlphabet     = 256 + 24
+	vp8lColorAlphabet     = 256
+	vp8lDistanceAlphabet  = 40
+)
+
+// vp8lCodeLengthOrder is the order in which code length code lengths are written
+var vp8lCodeLengthOrder = [vp8lNumCodeLengths]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// bitWriter writes values LSB-first as required by VP8L
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (w *bitWriter) writeBits(value uint32, n uint) {
+	w.acc |= uint64(value) << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc = 0
+		w.nbits = 0
+	}
+	return w.buf
+}
+
+// prefixCode is a canonical Huffman code over an alphabet
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint32 // Bit-reversed so they can be written LSB-first
+	symbols []int    // Used symbols, only set for codes with one or two symbols
+}
+
+// writeSymbol writes the code for symbol; simple codes with one symbol take no bits
+func (c *prefixCode) writeSymbol(w *bitWriter, symbol int) {
+	if n := c.lengths[symbol]; n > 0 {
+		w.writeBits(c.codes[symbol], uint(n))
+	}
+}
+
+// newPrefixCode builds a length-limited Huffman code from symbol frequencies
+func newPrefixCode(freqs []int, maxLength int) *prefixCode {
+	code := &prefixCode{
+		lengths: make([]uint8, len(freqs)),
+		codes:   make([]uint32, len(freqs)),
+	}
+
+	var used []int
+	for symbol, f := range freqs {
+		if f > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	switch {
+	case len(used) == 0:
+		code.symbols = []int{0}
+		return code
+	case len(used) == 1:
+		code.symbols = used
+		return code
+	case len(used) == 2 && used[1] < 256:
+		code.symbols = used
+		code.lengths[used[0]] = 1
+		code.lengths[used[1]] = 1
+		code.codes[used[1]] = 1
+		return code
+	}
+
+	code.lengths = huffmanLengths(freqs, maxLength)
+	code.assignCodes()
+	return code
+}
+
+// assignCodes assigns canonical codes from the code lengths
+func (c *prefixCode) assignCodes() {
+	var count [vp8lMaxCodeLength + 1]uint32
+	for _, l := range c.lengths {
+		count[l]++
+	}
+	count[0] = 0
+
+	var next [vp8lMaxCodeLength + 2]uint32
+	codeValue := uint32(0)
+	for bits := 1; bits <= vp8lMaxCodeLength; bits++ {
+		codeValue = (codeValue + count[bits-1]) << 1
+		next[bits] = codeValue
+	}
+
+	for symbol, l := range c.lengths {
+		if l == 0 {
+			continue
+		}
+		c.codes[symbol] = reverseBits(next[l], uint(l))
+		next[l]++
+	}
+}
+
+// write emits the code description into the bitstream
+func (c *prefixCode) write(w *bitWriter) {
+	if c.symbols != nil {
+		// Simple code with one or two symbols
+		w.writeBits(1, 1)
+		w.writeBits(uint32(len(c.symbols)-1), 1)
+		if c.symbols[0] <= 1 {
+			w.writeBits(0, 1)
+			w.writeBits(uint32(c.symbols[0]), 1)
+		} else {
+			w.writeBits(1, 1)
+			w.writeBits(uint32(c.symbols[0]), 8)
+		}
+		if len(c.symbols) == 2 {
+			w.writeBits(uint32(c.symbols[1]), 8)
+		}
+		return
+	}
+
+	// Normal code: code lengths are written with a code length code
+	w.writeBits(0, 1)
+
+	clFreqs := make([]int, vp8lNumCodeLengths)
+	for _, l := range c.lengths {
+		clFreqs[l]++
+	}
+	// The code length code needs at least two symbols to be a normal code
+	distinct := 0
+	for _, f := range clFreqs {
+		if f > 0 {
+			distinct++
+		}
+	}
+	if distinct < 2 {
+		if clFreqs[0] == 0 {
+			clFreqs[0] = 1
+		} else {
+			clFreqs[1] = 1
+		}
+	}
+
+	clCode := &prefixCode{
+		lengths: huffmanLengths(clFreqs, vp8lMaxCLCodeLength),
+		codes:   make([]uint32, vp8lNumCodeLengths),
+	}
+	clCode.assignCodes()
+
+	numCodes := vp8lNumCodeLengths
+	for numCodes > 4 && clCode.lengths[vp8lCodeLengthOrder[numCodes-1]] == 0 {
+		numCodes--
+	}
+	w.writeBits(uint32(numCodes-4), 4)
+	for i := 0; i < numCodes; i++ {
+		w.writeBits(uint32(clCode.lengths[vp8lCodeLengthOrder[i]]), 3)
+	}
+
+	// Lengths are written for the whole alphabet, so max_symbol is not used
+	w.writeBits(0, 1)
+	for _, l := range c.lengths {
+		clCode.writeSymbol(w, int(l))
+	}
+}
+
+// huffmanLengths computes code lengths no longer than maxLength.
+// Frequencies are flattened until the tree fits, as libwebp does.
+func huffmanLengths(freqs []int, maxLength int) []uint8 {
+	lengths := make([]uint8, len(freqs))
+	scaled := make([]int, len(freqs))
+	copy(scaled, freqs)
+
+	for {
+		if buildHuffmanLengths(scaled, lengths) <= maxLength {
+			return lengths
+		}
+		for i, f := range scaled {
+			if f > 0 {
+				scaled[i] = f/2 + 1
+			}
+		}
+	}
+}
+
+type huffmanNode struct {
+	freq        int
+	symbol      int
+	left, right *huffmanNode
+}
+
+type huffmanHeap []*huffmanNode
+
+func (h huffmanHeap) Len() int { return len(h) }
+func (h huffmanHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].symbol < h[j].symbol
+}
+func (h huffmanHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *huffmanHeap) Push(x interface{}) { *h = append(*h, x.(*huffmanNode)) }
+func (h *huffmanHeap) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// buildHuffmanLengths fills lengths with unrestricted Huffman code lengths and returns the maximum
+func buildHuffmanLengths(freqs []int, lengths []uint8) int {
+	h := &huffmanHeap{}
+	for symbol, f := range freqs {
+		lengths[symbol] = 0
+		if f > 0 {
+			*h = append(*h, &huffmanNode{freq: f, symbol: symbol})
+		}
+	}
+	heap.Init(h)
+
+	nextID := len(freqs)
+	for h.Len() > 1 {
+		a := heap.Pop(h).(*huffmanNode)
+		b := heap.Pop(h).(*huffmanNode)
+		heap.Push(h, &huffmanNode{freq: a.freq + b.freq, symbol: nextID, left: a, right: b})
+		nextID++
+	}
+
+	maxLength := 0
+	var walk func(n *huffmanNode, depth int)
+	walk = func(n *huffmanNode, depth int) {
+		if n.left == nil {
+			lengths[n.symbol] = uint8(depth)
+			if depth > maxLength {
+				maxLength = depth
+			}
+			return
+		}
+		walk(n.left, depth+1)
+		walk(n.right, depth+1)
+	}
+	if h.Len() == 1 {
+		root := (*h)[0]
+		if root.left == nil {
+			// A single symbol still needs a one bit code in a normal code
+			lengths[root.symbol] = 1
+			return 1
+		}
+		walk(root, 0)
+	}
+
+	return maxLength
+}
+
+func reverseBits(value uint32, n uint) uint32 {
+	var out uint32
+	for i := uint(0); i < n; i++ {
+		out = out<<1 | value&1
+		value >>= 1
+	}
+	return out
+}
+
+// encodeVP8L encodes ARGB pixels as a VP8L bitstream using the subtract green
+// and gradient predictor transforms followed by literal prefix coding
+func encodeVP8L(argb []uint32, width, height int, hasAlpha bool) []byte {
+	w := &bitWriter{}
+
+	w.writeBits(vp8lSignature, 8)
+	w.writeBits(uint32(width-1), 14)
+	w.writeBits(uint32(height-1), 14)
+	if hasAlpha {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+	w.writeBits(0, 3) // Version
+
+	pixels := make([]uint32, len(argb))
+	copy(pixels, argb)
+
+	// Subtract green transform
+	w.writeBits(1, 1)
+	w.writeBits(vp8lSubtractGreen, 2)
+	for i, p := range pixels {
+		g := (p >> 8) & 0xff
+		r := ((p >> 16) - g) & 0xff
+		b := (p - g) & 0xff
+		pixels[i] = p&0xff00ff00 | r<<16 | b
+	}
+
+	// Predictor transform with one gradient mode for every block
+	w.writeBits(1, 1)
+	w.writeBits(vp8lPredictor, 2)
+	w.writeBits(vp8lPredictorBits-2, 3)
+	blocksX := (width + 1<<vp8lPredictorBits - 1) >> vp8lPredictorBits
+	blocksY := (height + 1<<vp8lPredictorBits - 1) >> vp8lPredictorBits
+	modes := make([]uint32, blocksX*blocksY)
+	for i := range modes {
+		modes[i] = 0xff000000 | vp8lPredictorGradient<<8
+	}
+	writeEntropyImage(w, modes, false)
+
+	residuals := make([]uint32, len(pixels))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			var prediction uint32
+			switch {
+			case x == 0 && y == 0:
+				prediction = 0xff000000
+			case y == 0:
+				prediction = pixels[i-1]
+			case x == 0:
+				prediction = pixels[i-width]
+			default:
+				prediction = clampAddSubtractFull(pixels[i-1], pixels[i-width], pixels[i-width-1])
+			}
+			residuals[i] = subPixels(pixels[i], prediction)
+		}
+	}
+
+	w.writeBits(0, 1) // No more transforms
+
+	writeEntropyImage(w, residuals, true)
+
+	return w.bytes()
+}
+
+// writeEntropyImage writes pixels as literals with a single group of prefix codes
+func writeEntropyImage(w *bitWriter, pixels []uint32, isMainImage bool) {
+	w.writeBits(0, 1) // No color cache
+	if isMainImage {
+		w.writeBits(0, 1) // No meta prefix codes
+	}
+
+	green := make([]int, vp8lGreenAlphabet)
+	red := make([]int, vp8lColorAlphabet)
+	blue := make([]int, vp8lColorAlphabet)
+	alpha := make([]int, vp8lColorAlphabet)
+	for _, p := range pixels {
+		green[(p>>8)&0xff]++
+		red[(p>>16)&0xff]++
+		blue[p&0xff]++
+		alpha[p>>24]++
+	}
+
+	codes := []*prefixCode{
+		newPrefixCode(green, vp8lMaxCodeLength),
+		newPrefixCode(red, vp8lMaxCodeLength),
+		newPrefixCode(blue, vp8lMaxCodeLength),
+		newPrefixCode(alpha, vp8lMaxCodeLength),
+		newPrefixCode(make([]int, vp8lDistanceAlphabet), vp8lMaxCodeLength),
+	}
+	for _, c := range codes {
+		c.write(w)
+	}
+
+	for _, p := range pixels {
+		codes[0].writeSymbol(w, int((p>>8)&0xff))
+		codes[1].writeSymbol(w, int((p>>16)&0xff))
+		codes[2].writeSymbol(w, int(p&0xff))
+		codes[3].writeSymbol(w, int(p>>24))
+	}
+}
+
+// clampAddSubtractFull predicts each channel as clamp(L + T - TL)
+func clampAddSubtractFull(left, top, topLeft uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		v := int(left>>shift&0xff) + int(top>>shift&0xff) - int(topLeft>>shift&0xff)
+		if v < 0 {
+			v = 0
+		} else if v > 255 {
+			v = 255
+		}
+		out |= uint32(v) << shift
+	}
+	return out
+}
+
+// subPixels subtracts b from a per channel modulo 256
+func subPixels(a, b uint32) uint32 {
+	alphaGreen := 0x00ff00ff + (a & 0xff00ff00) - (b & 0xff00ff00)
+	redBlue := 0xff00ff00 + (a & 0x00ff00ff) - (b & 0x00ff00ff)
+	return alphaGreen&0xff00ff00 | redBlue&0x00ff00ff
+}
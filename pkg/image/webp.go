@@ -0,0 +1,115 @@
+// Package image converts uploaded images to formats better suited for storage
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	// Register decoders for the cover formats accepted on upload
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// WebPContentType is the MIME type of images produced by ConvertToWebP
+const WebPContentType = "image/webp"
+
+// ConvertToWebP decodes a JPEG, PNG or GIF image of at most MaxPixels and re-encodes it as WebP.
+//
+// The encoder is a pure-Go lossless (VP8L) encoder. Quality in the range
+// 1..100 controls near-lossless preprocessing: at 100 pixels are kept exact,
+// lower values drop low-order bits of each channel so the output compresses better.
+func ConvertToWebP(src io.Reader, quality int) ([]byte, error) {
+	img, err := decode(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, img, quality); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// EncodeWebP writes img to w as a lossless WebP with near-lossless preprocessing
+func EncodeWebP(w io.Writer, img image.Image, quality int) error {
+	if quality < 1 || quality > 100 {
+		return fmt.Errorf("invalid quality %d: must be between 1 and 100", quality)
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return fmt.Errorf("unsupported image size %dx%d: WebP allows up to %d pixels per side", width, height, vp8lMaxDimension)
+	}
+
+	dropBits := nearLosslessBits(quality)
+
+	argb := make([]uint32, width*height)
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			if c.A != 0xff {
+				hasAlpha = true
+			}
+			argb[y*width+x] = uint32(c.A)<<24 |
+				uint32(quantize(c.R, dropBits))<<16 |
+				uint32(quantize(c.G, dropBits))<<8 |
+				uint32(quantize(c.B, dropBits))
+		}
+	}
+
+	bitstream := encodeVP8L(argb, width, height, hasAlpha)
+
+	// RIFF container with a single VP8L chunk, chunks are padded to even size
+	chunkSize := len(bitstream)
+	padding := chunkSize & 1
+	header := make([]byte, 20)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(4+8+chunkSize+padding))
+	copy(header[8:12], "WEBP")
+	copy(header[12:16], "VP8L")
+	binary.LittleEndian.PutUint32(header[16:20], uint32(chunkSize))
+
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("failed to write WebP header: %w", err)
+	}
+	if _, err := w.Write(bitstream); err != nil {
+		return fmt.Errorf("failed to write WebP data: %w", err)
+	}
+	if padding == 1 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return fmt.Errorf("failed to write WebP padding: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// nearLosslessBits maps quality to the number of low-order bits dropped per channel
+func nearLosslessBits(quality int) uint {
+	bits := (100 - quality) / 20
+	if bits > 4 {
+		bits = 4
+	}
+	return uint(bits)
+}
+
+// quantize rounds v to the nearest multiple of 2^bits
+func quantize(v uint8, bits uint) uint8 {
+	if bits == 0 {
+		return v
+	}
+	rounded := (int(v) + 1<<(bits-1)) >> bits << bits
+	if rounded > 255 {
+		rounded = 255 >> bits << bits
+	}
+	return uint8(rounded)
+}
@@ -0,0 +1,49 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestConvertToWebPRoundTrip(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 17, 9))
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 17; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 15), G: uint8(y * 28), B: uint8(x * y), A: 255})
+		}
+	}
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, src); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+
+	data, err := ConvertToWebP(bytes.NewReader(pngData.Bytes()), 100)
+	if err != nil {
+		t.Fatalf("ConvertToWebP: %v", err)
+	}
+
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format != "webp" {
+		t.Fatalf("DecodeConfig: format %q, err %v; want webp", format, err)
+	}
+	got, err := webp.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode webp: %v", err)
+	}
+	if got.Bounds() != src.Bounds() {
+		t.Fatalf("bounds = %v, want %v", got.Bounds(), src.Bounds())
+	}
+	// Quality 100 is lossless
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 17; x++ {
+			if want, have := src.NRGBAAt(x, y), color.NRGBAModel.Convert(got.At(x, y)); have != want {
+				t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, have, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Mailer sends a plain-text email. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// LogMailer writes messages to the log instead of sending them. It is the
+// default for development and for deployments without an email provider;
+// the logged body contains any links or tokens in the message.
+type LogMailer struct {
+	logger *slog.Logger
+}
+
+// NewLogMailer creates a Mailer that logs every message
+func NewLogMailer(log *slog.Logger) *LogMailer {
+	return &LogMailer{logger: log}
+}
+
+// Send logs the message and never fails
+func (m *LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.logger.InfoContext(ctx, "Email not sent, logging instead", "to", to, "subject", subject, "body", body)
+	return nil
+}
@@ -16,6 +16,15 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+// Migration files are either plain NNN_name.sql files, which can only be
+// applied, or NNN_name.up.sql / NNN_name.down.sql pairs, which can also be
+// rolled back. A migration is recorded in schema_migrations under the name
+// of its up file.
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
 type Migrator struct {
 	db            *database.DB
 	logger        *slog.Logger
@@ -126,8 +135,8 @@ func (m *Migrator) getMigrationFiles() ([]string, error) {
 			continue
 		}
 		name := entry.Name()
-		// Only process .sql files
-		if strings.HasSuffix(name, ".sql") {
+		// Only process .sql files; down scripts are looked up by Down
+		if strings.HasSuffix(name, ".sql") && !strings.HasSuffix(name, downSuffix) {
 			files = append(files, name)
 		}
 	}
@@ -160,6 +169,75 @@ func (m *Migrator) getAppliedMigrations(ctx context.Context) (map[string]bool, e
 	return applied, nil
 }
 
+// Down rolls back the steps most recently applied migrations, newest first.
+// Each rollback runs in its own transaction. Nothing is rolled back if any of
+// the selected migrations has no down script.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	if err := m.createMigrationsTable(ctx); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	names, err := m.getLatestMigrations(ctx, steps)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	if len(names) == 0 {
+		m.logger.Info("No applied migrations to roll back")
+		return nil
+	}
+
+	// Check every down script up front so a missing one doesn't leave the rollback half done
+	downFiles := make([]string, 0, len(names))
+	for _, name := range names {
+		if !strings.HasSuffix(name, upSuffix) {
+			return fmt.Errorf("migration %s has no down script", name)
+		}
+		downFile := strings.TrimSuffix(name, upSuffix) + downSuffix
+		if _, err := os.Stat(filepath.Join(m.migrationsDir, downFile)); err != nil {
+			return fmt.Errorf("migration %s has no down script: %w", name, err)
+		}
+		downFiles = append(downFiles, downFile)
+	}
+
+	for i, name := range names {
+		m.logger.Info("Rolling back migration", "file", name)
+		if err := m.revertMigration(ctx, name, downFiles[i]); err != nil {
+			return fmt.Errorf("failed to roll back migration %s: %w", name, err)
+		}
+		m.logger.Info("Migration rolled back successfully", "file", name)
+	}
+
+	m.logger.Info("Rollback completed", "rolled_back", len(names))
+	return nil
+}
+
+// getLatestMigrations returns up to limit applied migration names, most recently applied first
+func (m *Migrator) getLatestMigrations(ctx context.Context, limit int) ([]string, error) {
+	query := `SELECT name FROM schema_migrations ORDER BY applied_at DESC, name DESC LIMIT $1`
+
+	rows, err := m.db.Pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
 // applyMigration applies a single migration file
 func (m *Migrator) applyMigration(ctx context.Context, filename string) error {
 	// Read migration file content
@@ -194,6 +272,34 @@ func (m *Migrator) applyMigration(ctx context.Context, filename string) error {
 	return nil
 }
 
+// revertMigration runs a down script and removes the migration's record
+func (m *Migrator) revertMigration(ctx context.Context, name, downFile string) error {
+	content, err := os.ReadFile(filepath.Join(m.migrationsDir, downFile))
+	if err != nil {
+		return fmt.Errorf("failed to read down script: %w", err)
+	}
+
+	tx, err := m.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, string(content)); err != nil {
+		return fmt.Errorf("failed to execute down script SQL: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE name = $1`, name); err != nil {
+		return fmt.Errorf("failed to remove migration record: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 // recordMigration records a migration as applied
 func (m *Migrator) recordMigration(ctx context.Context, tx pgx.Tx, filename string) error {
 	query := `
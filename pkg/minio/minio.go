@@ -2,10 +2,13 @@ package minio
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"mime/multipart"
+	"net/url"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,14 +16,52 @@ import (
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
+// ErrObjectNotFound is returned when an object doesn't exist in its bucket
+var ErrObjectNotFound = errors.New("object not found")
+
+// statObject returns object metadata, wrapping ErrObjectNotFound for a missing key
+func statObject(ctx context.Context, client *minio.Client, bucket, objectName string) (minio.ObjectInfo, error) {
+	info, err := client.StatObject(ctx, bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return minio.ObjectInfo{}, fmt.Errorf("%w: %s/%s", ErrObjectNotFound, bucket, objectName)
+		}
+		return minio.ObjectInfo{}, fmt.Errorf("failed to get object info: %w", err)
+	}
+	return info, nil
+}
+
+// Buckets names the bucket of each content type. Several types may share a bucket.
+type Buckets struct {
+	Audio  string
+	Image  string
+	Avatar string
+}
+
+// Unique returns the distinct bucket names in Audio, Image, Avatar order
+func (b Buckets) Unique() []string {
+	var names []string
+	for _, name := range []string{b.Audio, b.Image, b.Avatar} {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 type Client struct {
 	*minio.Client
-	bucket string
-	logger *slog.Logger
+	// presigner signs URLs for clients; it points at the public endpoint
+	// and never connects to it
+	presigner *minio.Client
+	buckets   Buckets
+	logger    *slog.Logger
 }
 
-// New creates a new MinIO client
-func New(endpoint, accessKey, secretKey, bucket string, useSSL bool, logger *slog.Logger) (*Client, error) {
+// New creates a new MinIO client and creates any of the buckets that don't exist.
+// Presigned URLs are signed for publicEndpoint, the address clients reach
+// MinIO at, or for endpoint when publicEndpoint is empty.
+func New(endpoint, accessKey, secretKey string, buckets Buckets, useSSL bool, publicEndpoint string, publicUseSSL bool, logger *slog.Logger) (*Client, error) {
 	client, err := minio.New(endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
 		Secure: useSSL,
@@ -29,33 +70,114 @@ func New(endpoint, accessKey, secretKey, bucket string, useSSL bool, logger *slo
 		return nil, fmt.Errorf("failed to create minio client: %w", err)
 	}
 
-	// Create bucket if it doesn't exist
+	// Create buckets if they don't exist
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	exists, err := client.BucketExists(ctx, bucket)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check bucket existence: %w", err)
+	for _, bucket := range buckets.Unique() {
+		exists, err := client.BucketExists(ctx, bucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check bucket %s existence: %w", bucket, err)
+		}
+
+		if !exists {
+			err = client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+			}
+			logger.Info("MinIO bucket created", "bucket", bucket)
+		}
 	}
 
-	if !exists {
-		err = client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{})
+	presigner := client
+	if publicEndpoint != "" {
+		// The host is part of the signature, so URLs must be signed for the
+		// public one. Set the region so signing doesn't try to look it up there.
+		region, err := client.GetBucketLocation(ctx, buckets.Audio)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create bucket: %w", err)
+			return nil, fmt.Errorf("failed to get bucket %s region: %w", buckets.Audio, err)
+		}
+		presigner, err = minio.New(publicEndpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+			Secure: publicUseSSL,
+			Region: region,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create minio client for public endpoint: %w", err)
 		}
-		logger.Info("MinIO bucket created", "bucket", bucket)
 	}
 
 	return &Client{
-		Client: client,
-		bucket: bucket,
-		logger: logger,
+		Client:    client,
+		presigner: presigner,
+		buckets:   buckets,
+		logger:    logger,
 	}, nil
 }
 
+// Buckets returns the bucket of each content type
+func (c *Client) Buckets() Buckets {
+	return c.buckets
+}
+
+// Object patterns made anonymously readable by SetPublicImagesPolicy: album
+// covers with their thumbnails in the image bucket, and avatars in the avatar
+// bucket. Audio shares the albums/ prefix but never matches "cover*".
+const (
+	PublicCoverPrefix  = "albums/*/cover*"
+	PublicAvatarPrefix = "avatars/*"
+)
+
+// SetPublicImagesPolicy replaces the policy of the image and avatar buckets
+// with one that allows anonymous GetObject on the public prefixes only.
+// Listing stays private and a separate audio bucket is left untouched.
+func (c *Client) SetPublicImagesPolicy(ctx context.Context) error {
+	prefixes := map[string][]string{}
+	prefixes[c.buckets.Image] = append(prefixes[c.buckets.Image], PublicCoverPrefix)
+	prefixes[c.buckets.Avatar] = append(prefixes[c.buckets.Avatar], PublicAvatarPrefix)
+
+	for _, bucket := range c.buckets.Unique() {
+		if len(prefixes[bucket]) == 0 {
+			continue
+		}
+		if err := c.setPublicReadPolicy(ctx, bucket, prefixes[bucket]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setPublicReadPolicy replaces the bucket policy with anonymous GetObject on prefixes
+func (c *Client) setPublicReadPolicy(ctx context.Context, bucket string, prefixes []string) error {
+	resources := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		resources = append(resources, fmt.Sprintf("arn:aws:s3:::%s/%s", bucket, prefix))
+	}
+
+	policy, err := json.Marshal(map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []map[string]any{{
+			"Effect":    "Allow",
+			"Principal": map[string]any{"AWS": []string{"*"}},
+			"Action":    []string{"s3:GetObject"},
+			"Resource":  resources,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build bucket policy: %w", err)
+	}
+
+	if err := c.SetBucketPolicy(ctx, bucket, string(policy)); err != nil {
+		return fmt.Errorf("failed to set bucket %s policy: %w", bucket, err)
+	}
+
+	c.logger.Info("Public-read policy applied to images", "bucket", bucket, "prefixes", prefixes)
+	return nil
+}
+
 // UploadFile uploads a file to MinIO
-func (c *Client) UploadFile(ctx context.Context, objectName, filePath, contentType string) error {
-	info, err := c.FPutObject(ctx, c.bucket, objectName, filePath, minio.PutObjectOptions{
+func (c *Client) UploadFile(ctx context.Context, bucket, objectName, filePath, contentType string) error {
+	info, err := c.FPutObject(ctx, bucket, objectName, filePath, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 	if err != nil {
@@ -71,25 +193,21 @@ func (c *Client) UploadFile(ctx context.Context, objectName, filePath, contentTy
 }
 
 // GetObject returns a reader for the object
-func (c *Client) GetObject(ctx context.Context, objectName string) (*minio.Object, error) {
-	object, err := c.Client.GetObject(ctx, c.bucket, objectName, minio.GetObjectOptions{})
+func (c *Client) GetObject(ctx context.Context, bucket, objectName string) (*minio.Object, error) {
+	object, err := c.Client.GetObject(ctx, bucket, objectName, minio.GetObjectOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get object: %w", err)
 	}
 	return object, nil
 }
 
-// GetObjectInfo returns object metadata
-func (c *Client) GetObjectInfo(ctx context.Context, objectName string) (minio.ObjectInfo, error) {
-	info, err := c.Client.StatObject(ctx, c.bucket, objectName, minio.StatObjectOptions{})
-	if err != nil {
-		return minio.ObjectInfo{}, fmt.Errorf("failed to get object info: %w", err)
-	}
-	return info, nil
+// GetObjectInfo returns object metadata; the error wraps ErrObjectNotFound for a missing object
+func (c *Client) GetObjectInfo(ctx context.Context, bucket, objectName string) (minio.ObjectInfo, error) {
+	return statObject(ctx, c.Client, bucket, objectName)
 }
 
 // PutObject uploads an object to MinIO
-func (c *Client) PutObject(ctx context.Context, objectName string, reader io.Reader, size int64, metadata map[string]string) (*minio.UploadInfo, error) {
+func (c *Client) PutObject(ctx context.Context, bucket, objectName string, reader io.Reader, size int64, metadata map[string]string) (*minio.UploadInfo, error) {
 	opts := minio.PutObjectOptions{}
 	if contentType, ok := metadata["Content-Type"]; ok {
 		opts.ContentType = contentType
@@ -98,7 +216,7 @@ func (c *Client) PutObject(ctx context.Context, objectName string, reader io.Rea
 		opts.UserMetadata = metadata
 	}
 
-	info, err := c.Client.PutObject(ctx, c.bucket, objectName, reader, size, opts)
+	info, err := c.Client.PutObject(ctx, bucket, objectName, reader, size, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to put object: %w", err)
 	}
@@ -112,8 +230,8 @@ func (c *Client) PutObject(ctx context.Context, objectName string, reader io.Rea
 }
 
 // DeleteObject deletes an object from MinIO
-func (c *Client) DeleteObject(ctx context.Context, objectName string) error {
-	err := c.Client.RemoveObject(ctx, c.bucket, objectName, minio.RemoveObjectOptions{})
+func (c *Client) DeleteObject(ctx context.Context, bucket, objectName string) error {
+	err := c.Client.RemoveObject(ctx, bucket, objectName, minio.RemoveObjectOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to delete object: %w", err)
 	}
@@ -138,14 +256,21 @@ func NewService(client *Client, endpoint string, useSSL bool, logger *slog.Logge
 	}
 }
 
-// UploadFile uploads a multipart file to MinIO
-func (s *Service) UploadFile(ctx context.Context, bucket, objectName string, file multipart.File, size int64) (*minio.UploadInfo, error) {
+// Buckets returns the bucket of each content type
+func (s *Service) Buckets() Buckets {
+	return s.client.buckets
+}
+
+// UploadFile uploads a file to MinIO
+func (s *Service) UploadFile(ctx context.Context, bucket, objectName string, file io.Reader, size int64) (*minio.UploadInfo, error) {
 	// Detect content type from object name
 	contentType := "application/octet-stream"
 	if strings.HasSuffix(strings.ToLower(objectName), ".jpg") || strings.HasSuffix(strings.ToLower(objectName), ".jpeg") {
 		contentType = "image/jpeg"
 	} else if strings.HasSuffix(strings.ToLower(objectName), ".png") {
 		contentType = "image/png"
+	} else if strings.HasSuffix(strings.ToLower(objectName), ".webp") {
+		contentType = "image/webp"
 	} else if strings.HasSuffix(strings.ToLower(objectName), ".mp3") {
 		contentType = "audio/mpeg"
 	}
@@ -189,51 +314,108 @@ func (s *Service) DeleteFile(ctx context.Context, bucket, objectName string) err
 	return nil
 }
 
+// CopyFile copies an object to another key within the bucket
+func (s *Service) CopyFile(ctx context.Context, bucket, srcObject, dstObject string) error {
+	_, err := s.client.Client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: bucket, Object: dstObject},
+		minio.CopySrcOptions{Bucket: bucket, Object: srcObject},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	s.logger.Info("File copied in MinIO", "from", srcObject, "to", dstObject, "bucket", bucket)
+	return nil
+}
+
 // GetObject returns a reader for the object
-func (s *Service) GetObject(ctx context.Context, objectName string) (io.ReadCloser, error) {
-	object, err := s.client.Client.GetObject(ctx, "music-files", objectName, minio.GetObjectOptions{})
+func (s *Service) GetObject(ctx context.Context, bucket, objectName string) (io.ReadCloser, error) {
+	object, err := s.client.Client.GetObject(ctx, bucket, objectName, minio.GetObjectOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get object: %w", err)
 	}
 	return object, nil
 }
 
-// GetObjectInfo returns info about the object
-func (s *Service) GetObjectInfo(ctx context.Context, objectName string) (*minio.ObjectInfo, error) {
-	info, err := s.client.Client.StatObject(ctx, "music-files", objectName, minio.StatObjectOptions{})
+// PresignedGetURL returns a URL on the public endpoint that allows
+// downloading the object without credentials until expiry passes
+func (s *Service) PresignedGetURL(ctx context.Context, bucket, objectName string, expiry time.Duration) (*url.URL, error) {
+	presigned, err := s.client.presigner.PresignedGetObject(ctx, bucket, objectName, expiry, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign object URL: %w", err)
+	}
+	return presigned, nil
+}
+
+// GetObjectInfo returns info about the object; the error wraps ErrObjectNotFound for a missing object
+func (s *Service) GetObjectInfo(ctx context.Context, bucket, objectName string) (*minio.ObjectInfo, error) {
+	info, err := statObject(ctx, s.client.Client, bucket, objectName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get object info: %w", err)
+		return nil, err
 	}
 	return &info, nil
 }
 
-// DeleteFolder deletes all objects with a given prefix (simulating folder deletion)
+// ListObjectKeys returns the keys of all objects with a given prefix
+func (s *Service) ListObjectKeys(ctx context.Context, bucket, prefix string) ([]string, error) {
+	// Cancelling stops the lister if listing ends early on an error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var keys []string
+	for object := range s.client.Client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", object.Err)
+		}
+		keys = append(keys, object.Key)
+	}
+	return keys, nil
+}
+
+// DeleteFolder deletes all objects with a given prefix (simulating folder deletion).
+// Listed keys are streamed into a batch RemoveObjects call; objects that fail to
+// delete are logged and don't stop the others.
 func (s *Service) DeleteFolder(ctx context.Context, bucket, prefix string) error {
-	// List all objects with the prefix
+	// Cancelling stops the lister and the feeder if removal ends early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	opts := minio.ListObjectsOptions{
 		Prefix:    prefix,
 		Recursive: true,
 	}
 
-	objectCh := s.client.Client.ListObjects(ctx, bucket, opts)
-
-	// Collect object names to delete
-	var objectNames []string
-	for object := range objectCh {
-		if object.Err != nil {
-			return fmt.Errorf("error listing objects: %w", object.Err)
+	// listErr and listed are written by the feeder before it closes objectsCh,
+	// which happens before RemoveObjects closes its error channel
+	objectsCh := make(chan minio.ObjectInfo)
+	var listErr error
+	listed := 0
+	go func() {
+		defer close(objectsCh)
+		for object := range s.client.Client.ListObjects(ctx, bucket, opts) {
+			if object.Err != nil {
+				listErr = object.Err
+				return
+			}
+			select {
+			case objectsCh <- object:
+				listed++
+			case <-ctx.Done():
+				return
+			}
 		}
-		objectNames = append(objectNames, object.Key)
+	}()
+
+	failed := 0
+	for removeErr := range s.client.Client.RemoveObjects(ctx, bucket, objectsCh, minio.RemoveObjectsOptions{}) {
+		s.logger.Error("Failed to delete object in folder", "object", removeErr.ObjectName, "error", removeErr.Err)
+		failed++
 	}
 
-	// Delete all objects
-	for _, objectName := range objectNames {
-		if err := s.DeleteFile(ctx, bucket, objectName); err != nil {
-			s.logger.Error("Failed to delete object in folder", "object", objectName, "error", err)
-			// Continue deleting other objects even if one fails
-		}
+	if listErr != nil {
+		return fmt.Errorf("error listing objects: %w", listErr)
 	}
 
-	s.logger.Info("Folder deleted from MinIO", "prefix", prefix, "bucket", bucket, "objects_deleted", len(objectNames))
+	s.logger.Info("Folder deleted from MinIO", "prefix", prefix, "bucket", bucket, "objects_deleted", listed-failed, "objects_failed", failed)
 	return nil
 }